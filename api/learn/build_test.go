@@ -1,6 +1,7 @@
 package learn
 
 import (
+	"io/ioutil"
 	"testing"
 
 	"github.com/gSchool/glearn-cli/api"
@@ -46,6 +47,30 @@ func Test_GetBlockByRepoName(t *testing.T) {
 	}
 }
 
+func Test_GetOwnedBlocks(t *testing.T) {
+	viper.Set("api_token", "apiToken")
+	mockClient := api.MockResponse(`{"blocks":[{"id":1,"repo_name":"blocks-test","sync_errors":["somethin is wrong"],"title":"Blocks Test","cohorts_using":[7,9]},{"id":2,"repo_name":"other","title":"Other"}]}`)
+	API, _ := NewAPI("https://example.com", mockClient)
+
+	blocks, err := API.GetOwnedBlocks()
+	if err != nil {
+		t.Errorf("error not nil: %s\n", err)
+	}
+	if len(blocks) != 2 {
+		t.Errorf("Expected 2 blocks but got %d", len(blocks))
+		return
+	}
+	testValidBlockSerialization(blocks[0], t)
+
+	req := mockClient.Requests[1]
+	if req.Method != "GET" {
+		t.Errorf("Request made to Learn should be a GET, was %s", req.Method)
+	}
+	if req.URL.String() != "https://example.com/api/v1/blocks?owned=true" {
+		t.Errorf("Request made to Learn should be to url '%s' but was '%s'\n", "https://example.com/api/v1/blocks?owned=true", req.URL.String())
+	}
+}
+
 func Test_CreateBlockByRepoName(t *testing.T) {
 	viper.Set("api_token", "apiToken")
 	mockClient := api.MockResponse(validBlockResponse)
@@ -124,6 +149,119 @@ func Test_CreateMasterRelease(t *testing.T) {
 	}
 }
 
+func Test_CreateReleaseAtRef(t *testing.T) {
+	viper.Set("api_token", "apiToken")
+	mockClient := api.MockResponse(validMasterReleaseResponse)
+	API, _ := NewAPI("https://example.com", mockClient)
+
+	id, err := API.CreateReleaseAtRef(1, "0123456789abcdef0123456789abcdef01234567")
+	if err != nil {
+		t.Errorf("error not nil: %s\n", err)
+	}
+	if id != 9 {
+		t.Errorf("Response release id was %d but expected 9", id)
+	}
+
+	if len(mockClient.Requests) != 2 {
+		t.Errorf("creating the release should make two requests")
+		return
+	}
+
+	req := mockClient.Requests[1]
+	if req.Method != "POST" {
+		t.Errorf("Request made to Learn should be a POST, was %s", req.Method)
+	}
+	if req.URL.String() != "https://example.com/api/v1/blocks/1/releases" {
+		t.Errorf("Request made to Learn should be to url '%s' but was '%s'\n", "https://example.com/api/v1/blocks/1/releases", req.URL.String())
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != `{"ref":"0123456789abcdef0123456789abcdef01234567"}` {
+		t.Errorf("Request body should contain the ref, was '%s'\n", string(body))
+	}
+}
+
+func Test_CreateCohortBranchRelease(t *testing.T) {
+	viper.Set("api_token", "apiToken")
+	mockClient := api.MockResponse(validMasterReleaseResponse)
+	API, _ := NewAPI("https://example.com", mockClient)
+
+	id, err := API.CreateCohortBranchRelease(1, 7, "fix-typos")
+	if err != nil {
+		t.Errorf("error not nil: %s\n", err)
+	}
+	if id != 9 {
+		t.Errorf("Response release id was %d but expected 9", id)
+	}
+
+	req := mockClient.Requests[1]
+	if req.Method != "POST" {
+		t.Errorf("Request made to Learn should be a POST, was %s", req.Method)
+	}
+	if req.URL.String() != "https://example.com/api/v1/cohorts/7/blocks/1/releases" {
+		t.Errorf("Request made to Learn should be to url '%s' but was '%s'\n", "https://example.com/api/v1/cohorts/7/blocks/1/releases", req.URL.String())
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != `{"branch":"fix-typos"}` {
+		t.Errorf("Request body should contain the branch, was '%s'\n", string(body))
+	}
+}
+
+const validReleasesResponse = `{"releases":[{"id":12,"commit":"0123456789abcdef","created_at":"2020-07-23T10:00:00Z","author":"someone@example.com","status":"success","sync_warnings":["missing image"],"current":true},{"id":11,"commit":"fedcba9876543210","created_at":"2020-07-22T10:00:00Z","author":"someone@example.com","status":"failed","sync_warnings":[]}]}`
+
+func Test_GetReleases(t *testing.T) {
+	viper.Set("api_token", "apiToken")
+	mockClient := api.MockResponse(validReleasesResponse)
+	API, _ := NewAPI("https://example.com", mockClient)
+
+	releases, err := API.GetReleases(1)
+	if err != nil {
+		t.Errorf("error not nil: %s\n", err)
+	}
+	if len(releases) != 2 {
+		t.Errorf("Expected 2 releases but got %d", len(releases))
+		return
+	}
+	if releases[0].ID != 12 || releases[0].Commit != "0123456789abcdef" || !releases[0].Current || releases[0].SyncWarnings[0] != "missing image" {
+		t.Errorf("Failed to properly json parse the first release, got %+v", releases[0])
+	}
+	if releases[1].Status != "failed" || releases[1].Current {
+		t.Errorf("Failed to properly json parse the second release, got %+v", releases[1])
+	}
+
+	req := mockClient.Requests[1]
+	if req.Method != "GET" {
+		t.Errorf("Request made to Learn should be a GET, was %s", req.Method)
+	}
+	if req.URL.String() != "https://example.com/api/v1/blocks/1/releases" {
+		t.Errorf("Request made to Learn should be to url '%s' but was '%s'\n", "https://example.com/api/v1/blocks/1/releases", req.URL.String())
+	}
+}
+
+func Test_RollbackRelease(t *testing.T) {
+	viper.Set("api_token", "apiToken")
+	mockClient := api.MockResponse(`{"release_id":11}`)
+	API, _ := NewAPI("https://example.com", mockClient)
+
+	id, err := API.RollbackRelease(1, 11)
+	if err != nil {
+		t.Errorf("error not nil: %s\n", err)
+	}
+	if id != 11 {
+		t.Errorf("Response release id was %d but expected 11", id)
+	}
+
+	req := mockClient.Requests[1]
+	if req.Method != "POST" {
+		t.Errorf("Request made to Learn should be a POST, was %s", req.Method)
+	}
+	if req.URL.String() != "https://example.com/api/v1/blocks/1/releases/11/rollback" {
+		t.Errorf("Request made to Learn should be to url '%s' but was '%s'\n", "https://example.com/api/v1/blocks/1/releases/11/rollback", req.URL.String())
+	}
+	if req.Header.Get("Authorization") != "Bearer apiToken" {
+		t.Errorf("Authorization header should be 'Bearer apiToken', was '%s'\n", req.Header.Get("Authorization"))
+	}
+}
+
 func testValidBlockSerialization(block Block, t *testing.T) {
 	if block.ID != 1 {
 		t.Errorf("block response should have id of 1, but got %d\n", block.ID)
@@ -80,6 +80,43 @@ func (api *APIClient) GetBlockByRepoName(repoName string) (Block, error) {
 	return Block{}, nil
 }
 
+// GetOwnedBlocks requests every block owned by the user of the api token from Learn
+func (api *APIClient) GetOwnedBlocks() ([]Block, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/api/v1/blocks", api.baseURL))
+	if err != nil {
+		return nil, errors.New("unable to parse Learn remote")
+	}
+	v := url.Values{}
+	v.Set("owned", "true")
+	u.RawQuery = v.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.Credentials.token))
+
+	res, err := api.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error: response status: %d", res.StatusCode)
+	}
+
+	var blockResp blockResponse
+	err = json.NewDecoder(res.Body).Decode(&blockResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockResp.Blocks, nil
+}
+
 // CreateBlockByRepoName takes a string repo name and makes a POST to the Learn API to create the block
 func (api *APIClient) CreateBlockByRepoName(repoName string) (Block, error) {
 	payload := BlockPost{Block: Block{RepoName: repoName}}
@@ -121,6 +158,12 @@ func (api *APIClient) CreateBlockByRepoName(repoName string) (Block, error) {
 	return Block{}, nil
 }
 
+// releasePost represents the shape of the data needed to POST to learn for creating a release
+// at a specific commit
+type releasePost struct {
+	Ref string `json:"ref"`
+}
+
 // CreateMasterRelease takes a block ID and creates a master release from it by POSTing to the Learn API
 func (api *APIClient) CreateMasterRelease(blockID int) (int, error) {
 	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/blocks/%d/releases", api.baseURL, blockID), nil)
@@ -150,3 +193,158 @@ func (api *APIClient) CreateMasterRelease(blockID int) (int, error) {
 
 	return r.ReleaseID, nil
 }
+
+// CreateReleaseAtRef takes a block ID and a commit sha and creates a release of the block at that
+// commit by POSTing to the Learn API
+func (api *APIClient) CreateReleaseAtRef(blockID int, ref string) (int, error) {
+	payloadBytes, err := json.Marshal(releasePost{Ref: ref})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/blocks/%d/releases", api.baseURL, blockID), bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.Credentials.token))
+
+	res, err := api.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Error: response status: %d", res.StatusCode)
+	}
+
+	var r ReleaseResponse
+
+	err = json.NewDecoder(res.Body).Decode(&r)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.ReleaseID, nil
+}
+
+// cohortReleasePost represents the shape of the data needed to POST to learn for creating a
+// release of a branch for a single cohort
+type cohortReleasePost struct {
+	Branch string `json:"branch"`
+}
+
+// CreateCohortBranchRelease takes a block ID, a cohort ID and a branch and creates a release of
+// the block from that branch for the cohort only by POSTing to the Learn API
+func (api *APIClient) CreateCohortBranchRelease(blockID, cohortID int, branch string) (int, error) {
+	payloadBytes, err := json.Marshal(cohortReleasePost{Branch: branch})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/cohorts/%d/blocks/%d/releases", api.baseURL, cohortID, blockID), bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.Credentials.token))
+
+	res, err := api.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Error: response status: %d", res.StatusCode)
+	}
+
+	var r ReleaseResponse
+
+	err = json.NewDecoder(res.Body).Decode(&r)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.ReleaseID, nil
+}
+
+// Release holds information about a block release yielded from the Learn Release API
+type Release struct {
+	ID           int      `json:"id"`
+	Commit       string   `json:"commit"`
+	CreatedAt    string   `json:"created_at"`
+	Author       string   `json:"author"`
+	Status       string   `json:"status"`
+	SyncWarnings []string `json:"sync_warnings"`
+	Current      bool     `json:"current"`
+}
+
+// releasesResponse represents the shape of our Learn API release list responses
+type releasesResponse struct {
+	Releases []Release `json:"releases"`
+}
+
+// GetReleases takes a block ID and requests the block's releases from Learn, newest first
+func (api *APIClient) GetReleases(blockID int) ([]Release, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/blocks/%d/releases", api.baseURL, blockID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.Credentials.token))
+
+	res, err := api.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error: response status: %d", res.StatusCode)
+	}
+
+	var r releasesResponse
+
+	err = json.NewDecoder(res.Body).Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Releases, nil
+}
+
+// RollbackRelease takes a block ID and one of its release IDs and makes that release the
+// block's current release by POSTing to the Learn API
+func (api *APIClient) RollbackRelease(blockID, releaseID int) (int, error) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/blocks/%d/releases/%d/rollback", api.baseURL, blockID, releaseID), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.Credentials.token))
+
+	res, err := api.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Error: response status: %d", res.StatusCode)
+	}
+
+	var r ReleaseResponse
+
+	err = json.NewDecoder(res.Body).Decode(&r)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.ReleaseID, nil
+}
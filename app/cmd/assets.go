@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// previewAsset is a file a single file preview links to, directly or through other lessons
+type previewAsset struct {
+	// path is where the file is relative to the previewed file's directory, with forward slashes
+	path string
+	// from is the file linking to it, relative to the same directory
+	from string
+	// depth is how many links away from the previewed file it is
+	depth       int
+	whitelisted bool
+}
+
+// collectPreviewAssets follows the local links, images and html images of target and of the
+// markdown files and notebooks it links to, up to depth links away or without a limit when
+// depth is 0. Files that don't exist, are directories or are outside of the block aren't
+// included and are returned as warnings. For a file that isn't in a block, its own directory
+// stands in for the block
+func collectPreviewAssets(target string, depth int) ([]previewAsset, []string, error) {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetDir := filepath.Dir(target)
+	blockRoot, inBlock := lookupBlockRoot(targetDir)
+	scope := "the block"
+	if !inBlock {
+		blockRoot, scope = targetDir, "the previewed file's directory"
+	}
+	relToTarget := func(path string) string {
+		rel, _ := filepath.Rel(targetDir, path)
+		return filepath.ToSlash(rel)
+	}
+
+	type queued struct {
+		path  string
+		depth int
+	}
+	queue := []queued{{target, 0}}
+	seen := map[string]struct{}{target: {}}
+	assets := []previewAsset{}
+	warnings := []string{}
+
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+
+		links, linkWarnings, err := localReferences(file.path)
+		if err != nil && file.path == target {
+			return nil, nil, err
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("the links in %s were not followed, %s", relToTarget(file.path), err))
+			continue
+		}
+		warnings = append(warnings, linkWarnings...)
+
+		for _, link := range links {
+			path := filepath.Join(filepath.Dir(file.path), filepath.FromSlash(link))
+			if strings.HasPrefix(link, "/") {
+				path = filepath.Join(blockRoot, filepath.FromSlash(link))
+			}
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			seen[path] = struct{}{}
+
+			if !withinDir(blockRoot, path) {
+				warnings = append(warnings, fmt.Sprintf("%s links to %s, which is outside of %s and is not included", relToTarget(file.path), link, scope))
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s links to %s, which does not exist", relToTarget(file.path), link))
+				continue
+			}
+			if info.IsDir() {
+				warnings = append(warnings, fmt.Sprintf("%s links to the directory %s, which is not included", relToTarget(file.path), link))
+				continue
+			}
+
+			_, whitelisted := fileExtWhitelist[strings.ToLower(filepath.Ext(path))]
+			assets = append(assets, previewAsset{
+				path:        relToTarget(path),
+				from:        relToTarget(file.path),
+				depth:       file.depth + 1,
+				whitelisted: whitelisted,
+			})
+
+			if ext := filepath.Ext(path); (ext == ".md" || ext == ".ipynb") && (depth == 0 || file.depth+1 < depth) {
+				queue = append(queue, queued{path, file.depth + 1})
+			}
+		}
+	}
+
+	return assets, warnings, nil
+}
+
+// localReferences returns the local files a markdown file or notebook references
+func localReferences(path string) ([]string, []string, error) {
+	if filepath.Ext(path) != ".ipynb" {
+		links, err := collectLinkPaths(path)
+		return links, nil, err
+	}
+
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return notebookLinkPaths(path, source)
+}
+
+// assetPaths are the paths of assets, as createNewTarget takes them
+func assetPaths(assets []previewAsset) []string {
+	paths := make([]string, 0, len(assets))
+	for _, a := range assets {
+		paths = append(paths, a.path)
+	}
+	return paths
+}
+
+// printPreviewManifest lists what a single file preview packs with the previewed file, with a
+// warning for every file type Learn doesn't usually take and every link that was left out
+func printPreviewManifest(out io.Writer, target string, assets []previewAsset, warnings []string) {
+	fmt.Fprintf(out, "Packing %s with %d linked files:\n", filepath.Base(target), len(assets))
+	for _, a := range assets {
+		fmt.Fprintf(out, "  %s (from %s)\n", a.path, a.from)
+	}
+	for _, a := range assets {
+		if !a.whitelisted {
+			fmt.Fprintf(out, "WARNING: %s is not a file type Learn previews usually include, it is packed because %s links to it\n", a.path, a.from)
+		}
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(out, "WARNING: %s\n", w)
+	}
+}
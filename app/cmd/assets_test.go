@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_collectPreviewAssets(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"config.yaml":              "Standards: []\n",
+		"data/people.csv":          "name\nAnn\n",
+		"images/logo.png":          "png",
+		"unit-1/lesson.md":         "# One\n\n![logo](../images/logo.png)\n\n[next](../unit-2/lesson.md)\n\n[missing](missing.png) [folder](../images)\n",
+		"unit-2/lesson.md":         "# Two\n\n[data](/data/people.csv)\n\n[starter](starter.py)\n\n[back](../unit-1/lesson.md)\n\n[third](three.md)\n",
+		"unit-2/starter.py":        "print(1)\n",
+		"unit-2/three.md":          "# Three\n\n[notebook](notebook.ipynb)\n",
+		"unit-2/notebook.ipynb":    "{\"nbformat\": 4, \"cells\": []}",
+		"unit-2/unlinked-file.png": "png",
+	})
+
+	assets, warnings, err := collectPreviewAssets(dir+"/unit-1/lesson.md", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"../images/logo.png from lesson.md",
+		"../unit-2/lesson.md from lesson.md",
+		"../data/people.csv from ../unit-2/lesson.md",
+		"../unit-2/starter.py from ../unit-2/lesson.md",
+		"../unit-2/three.md from ../unit-2/lesson.md",
+		"../unit-2/notebook.ipynb from ../unit-2/three.md",
+	}
+	got := []string{}
+	for _, a := range assets {
+		got = append(got, a.path+" from "+a.from)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("collectPreviewAssets should follow every link once, expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "missing.png, which does not exist") || !strings.Contains(warnings[1], "directory ../images") {
+		t.Errorf("missing files and directories should be warnings, got %v", warnings)
+	}
+
+	assets, _, err = collectPreviewAssets(dir+"/unit-1/lesson.md", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 {
+		t.Errorf("a depth of 1 should only include the files the lesson links to, got %+v", assets)
+	}
+
+	assets, _, _ = collectPreviewAssets(dir+"/unit-1/lesson.md", 2)
+	if len(assets) != 5 {
+		t.Errorf("a depth of 2 should include the links of linked lessons, got %+v", assets)
+	}
+}
+
+func Test_printPreviewManifest(t *testing.T) {
+	out := &bytes.Buffer{}
+	printPreviewManifest(out, "unit-1/lesson.md", []previewAsset{
+		{path: "images/logo.png", from: "lesson.md", whitelisted: true},
+		{path: "data.csv", from: "lesson.md"},
+	}, []string{"lesson.md links to missing.png, which does not exist"})
+
+	for _, want := range []string{
+		"Packing lesson.md with 2 linked files:",
+		"  images/logo.png (from lesson.md)",
+		"WARNING: data.csv is not a file type Learn previews usually include, it is packed because lesson.md links to it",
+		"WARNING: lesson.md links to missing.png, which does not exist",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("the manifest should include %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "WARNING: images/logo.png") {
+		t.Errorf("whitelisted files should not be warned about")
+	}
+}
+
+func Test_compressDirectoryAllTypes(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"lesson.md":  "# Lesson\n",
+		"data.csv":   "a,b\n",
+		"starter.py": "print(1)\n",
+	})
+	zipPath := t.TempDir() + "/preview.zip"
+
+	names := func() []string {
+		r, err := zip.OpenReader(zipPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		files := []string{}
+		for _, f := range r.File {
+			if !strings.HasSuffix(f.Name, "/") {
+				files = append(files, path.Base(f.Name))
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	if err := compressDirectory(dir, zipPath, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); strings.Join(got, ",") != "lesson.md" {
+		t.Errorf("only whitelisted files should be compressed, got %v", got)
+	}
+
+	if err := compressDirectory(dir, zipPath, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); strings.Join(got, ",") != "data.csv,lesson.md,starter.py" {
+		t.Errorf("every file should be compressed with allTypes, got %v", got)
+	}
+}
+
+func Test_collectPreviewAssetsOutsideBlock(t *testing.T) {
+	outside := t.TempDir()
+	ioutil.WriteFile(filepath.Join(outside, "secret.png"), []byte("png"), 0644)
+	dir := filepath.Join(outside, "block")
+	os.MkdirAll(dir, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte("Standards: []\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "lesson.md"), []byte("# Lesson\n\n![secret](../secret.png)\n"), 0644)
+
+	assets, warnings, err := collectPreviewAssets(filepath.Join(dir, "lesson.md"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 0 {
+		t.Errorf("a link leaving the block should not be packed, got %v", assets)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "../secret.png, which is outside of the block") {
+		t.Errorf("a link leaving the block should be a warning, got %v", warnings)
+	}
+}
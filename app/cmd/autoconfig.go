@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// learnIgnoreFile lists patterns of files and directories autoconfig generation leaves out
+const learnIgnoreFile = ".learnignore"
+
+// unitMetaFile holds the title, description and success criteria of the unit in its directory
+const unitMetaFile = "_unit.yaml"
+
+// frontMatter is the metadata a content file can declare between --- lines at its top
+type frontMatter struct {
+	Title      string `yaml:"title"`
+	Type       string `yaml:"type"`
+	Visibility string `yaml:"visibility"`
+	Autoscore  bool   `yaml:"autoscore"`
+	TimeLimit  int    `yaml:"time_limit"`
+}
+
+// unitMeta is the shape of a _unit.yaml
+type unitMeta struct {
+	Title           string   `yaml:"Title"`
+	Description     string   `yaml:"Description"`
+	SuccessCriteria []string `yaml:"SuccessCriteria"`
+}
+
+// ignoreList decides which files and directories autoconfig generation skips. Names starting
+// with __ are always skipped, anything else comes from the block's .learnignore
+type ignoreList struct {
+	patterns []string
+}
+
+// loadIgnoreList reads the .learnignore at the root of a block, if there is one. Each line is
+// a glob matched against names and block relative paths, a trailing / only matches
+// directories and lines starting with # are comments
+func loadIgnoreList(blockRoot string) (ignoreList, error) {
+	list := ignoreList{}
+
+	f, err := os.Open(filepath.Join(blockRoot, learnIgnoreFile))
+	if os.IsNotExist(err) {
+		return list, nil
+	} else if err != nil {
+		return list, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.patterns = append(list.patterns, line)
+	}
+
+	return list, scanner.Err()
+}
+
+// ignored reports whether the file or directory at the block relative path should be skipped
+func (l ignoreList) ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(strings.TrimPrefix(relPath, "./"))
+	name := relPath[strings.LastIndex(relPath, "/")+1:]
+	if strings.HasPrefix(name, "__") {
+		return true
+	}
+
+	for _, pattern := range l.patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		pattern = strings.TrimPrefix(pattern, "/")
+
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, relPath); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readFrontMatter returns the front matter at the top of the markdown file at path. Files
+// without front matter, or starting with a horizontal rule rather than YAML, return nil
+func readFrontMatter(path string) (*frontMatter, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, _ := splitFrontMatter(b)
+	if raw == nil {
+		return nil, nil
+	}
+
+	var doc yaml.Node
+	if err = yaml.Unmarshal(raw, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	fm := &frontMatter{}
+	if err = doc.Decode(fm); err != nil {
+		return nil, fmt.Errorf("%s has invalid front matter: %s", path, err)
+	}
+
+	if fm.Type != "" {
+		fm.Type = strings.Title(strings.ToLower(fm.Type))
+		if _, ok := contentFileTypes[fm.Type]; !ok {
+			return nil, fmt.Errorf("%s has an unknown type '%s' in its front matter", path, fm.Type)
+		}
+	}
+	fm.Visibility = strings.ToLower(fm.Visibility)
+	if fm.Visibility != "" && fm.Visibility != "hidden" && fm.Visibility != "visible" {
+		return nil, fmt.Errorf("%s has visibility '%s' in its front matter, it can only be hidden or visible", path, fm.Visibility)
+	}
+
+	return fm, nil
+}
+
+// splitFrontMatter separates the --- delimited front matter at the start of a markdown file
+// from the rest of it. The front matter is nil when there isn't any
+func splitFrontMatter(b []byte) ([]byte, []byte) {
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(b, []byte("---\n")) {
+		return nil, b
+	}
+	end := bytes.Index(b[4:], []byte("\n---"))
+	if end == -1 {
+		return nil, b
+	}
+
+	body := b[4+end+4:]
+	if i := bytes.IndexByte(body, '\n'); i != -1 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	return b[4 : 4+end], body
+}
+
+// readUnitMeta returns the _unit.yaml in dir, or nil when there isn't one
+func readUnitMeta(dir string) (*unitMeta, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, unitMetaFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	meta := &unitMeta{}
+	if err = yaml.Unmarshal(b, meta); err != nil {
+		return nil, fmt.Errorf("Could not parse '%s': %s", filepath.Join(dir, unitMetaFile), err)
+	}
+
+	return meta, nil
+}
+
+// naturalLess orders strings the way people number things, comparing runs of digits by their
+// value so 9-intro sorts before 10-loops
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+
+		if a[i] != b[j] {
+			return a[i] < b[j]
+		}
+		i++
+		j++
+	}
+
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
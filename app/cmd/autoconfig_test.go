@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const autoConfigMetadataFixture = "../../fixtures/test-block-autoconfig-metadata"
+
+func Test_AutoConfigUsesMetadataAndNaturalOrder(t *testing.T) {
+	createdConfig, err := doesConfigExistOrCreate(autoConfigMetadataFixture, "", false)
+	if err != nil || !createdConfig {
+		t.Fatalf("Should of created a config file, err: %v", err)
+	}
+
+	config, err := readBlockConfig(autoConfigMetadataFixture + "/autoconfig.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := []string{}
+	for _, s := range config.Standards {
+		titles = append(titles, s.Title)
+	}
+	if strings.Join(titles, ", ") != "Intro, Basics, Advanced: Going Further" {
+		t.Errorf("units should be in natural order and skip ignored directories, got %v", titles)
+	}
+	if len(config.Standards) != 3 {
+		t.FailNow()
+	}
+
+	intro := config.Standards[0]
+	if intro.ContentFiles[1].Title != "Setting Up" || intro.ContentFiles[1].DefaultVisibility != "hidden" {
+		t.Errorf("front matter title and visibility should be used, got %+v", intro.ContentFiles[1])
+	}
+	if len(intro.SuccessCriteria) != 1 || intro.SuccessCriteria[0] != "success criteria" {
+		t.Errorf("units without a _unit.yaml should get placeholder success criteria, got %v", intro.SuccessCriteria)
+	}
+
+	basics := config.Standards[1]
+	paths := []string{}
+	for _, cf := range basics.ContentFiles {
+		paths = append(paths, cf.Path)
+	}
+	if strings.Join(paths, ", ") != "/units/2-basics/9-variables.md, /units/2-basics/10-loops.md" {
+		t.Errorf("content files should be in natural order without ignored files, got %v", paths)
+	}
+
+	advanced := config.Standards[2]
+	if advanced.Description != "Everything after the basics" || strings.Join(advanced.SuccessCriteria, "|") != "Write nested loops|Explain recursion" {
+		t.Errorf("_unit.yaml should set the description and success criteria, got %+v", advanced)
+	}
+	quiz := advanced.ContentFiles[0]
+	if quiz.Type != "Checkpoint" || !quiz.Autoscore || quiz.TimeLimit != 30 {
+		t.Errorf("front matter type, autoscore and time limit should be used, got %+v", quiz)
+	}
+	rule := advanced.ContentFiles[1]
+	if rule.Type != "Lesson" || rule.Title != "" {
+		t.Errorf("a leading horizontal rule is not front matter, got %+v", rule)
+	}
+}
+
+func Test_naturalLess(t *testing.T) {
+	names := []string{"10-loops", "9-variables", "1-intro", "01-setup", "b", "a10", "a9", "a"}
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+	expected := "1-intro, 01-setup, 9-variables, 10-loops, a, a9, a10, b"
+	if strings.Join(names, ", ") != expected {
+		t.Errorf("expected natural order %s, got %s", expected, strings.Join(names, ", "))
+	}
+}
+
+func Test_ignoreList(t *testing.T) {
+	list := ignoreList{patterns: []string{"drafts/", "*.notes.md", "/units/old.md"}}
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"units/__skip", true, true},
+		{"units/01/__skipthis.md", false, true},
+		{"units/drafts", true, true},
+		{"units/drafts", false, false},
+		{"units/01/loops.notes.md", false, true},
+		{"units/old.md", false, true},
+		{"units/01/old.md", false, false},
+		{"./units/01/lesson.md", false, false},
+	}
+	for _, tt := range tests {
+		if got := list.ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("ignored(%s, %v) should be %v, was %v", tt.path, tt.isDir, tt.ignored, got)
+		}
+	}
+}
+
+func Test_readFrontMatterRejectsUnknownValues(t *testing.T) {
+	fm, err := readFrontMatter(autoConfigMetadataFixture + "/units/1-intro/1-welcome.md")
+	if fm != nil || err != nil {
+		t.Errorf("a file without front matter should not have any, got %v, %v", fm, err)
+	}
+
+	for _, tt := range []struct {
+		frontMatter string
+		err         string
+	}{
+		{"type: quiz", "unknown type 'Quiz'"},
+		{"visibility: secret", "it can only be hidden or visible"},
+		{"time_limit: soon", "invalid front matter"},
+	} {
+		f, err := ioutil.TempFile("", "front-matter-*.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString("---\n" + tt.frontMatter + "\n---\n# Lesson\n")
+		f.Close()
+		defer os.Remove(f.Name())
+
+		_, err = readFrontMatter(f.Name())
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("front matter '%s' should error with '%s', got %v", tt.frontMatter, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// defaultBatchConcurrency is how many blocks --all works on at once unless --concurrency is given
+const defaultBatchConcurrency = 4
+
+// maxDiscoveryDepth is how many directories deep discoverBlocks looks for block repositories
+const maxDiscoveryDepth = 3
+
+// batchResult is the outcome of previewing or publishing one block of a batch
+type batchResult struct {
+	dir      string
+	status   string
+	detail   string
+	warnings []string
+	err      error
+}
+
+// discoverBlocks returns the block repositories under root, in lexical order. A block
+// repository is a directory holding a .git entry. Hidden directories and node_modules are
+// skipped and nothing inside a block repository is searched
+func discoverBlocks(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	root = filepath.Clean(root)
+	blocks := []string{}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		name := info.Name()
+		if path != root && (strings.HasPrefix(name, ".") || name == "node_modules") {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+			blocks = append(blocks, path)
+			return filepath.SkipDir
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && strings.Count(rel, string(filepath.Separator))+1 >= maxDiscoveryDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// runBatch calls work for every index below n, with at most concurrency calls running at once
+func runBatch(n, concurrency int, work func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// discoverBlocksOrExit finds the block repositories under dir, exiting when there are none
+func discoverBlocksOrExit(dir string) []string {
+	blocks, err := discoverBlocks(dir)
+	if err != nil {
+		fmt.Printf("Cannot search %s for block repositories: %s\n", dir, err)
+		os.Exit(1)
+	}
+	if len(blocks) == 0 {
+		fmt.Printf("No block repositories found under %s\n", dir)
+		os.Exit(1)
+	}
+
+	return blocks
+}
+
+// previewAll previews every block repository under dir and prints a summary of the results
+func previewAll(dir string) {
+	blocks := discoverBlocksOrExit(dir)
+	fmt.Printf("Previewing %d blocks under %s, %d at a time...\n", len(blocks), dir, BatchConcurrency)
+
+	results := make([]batchResult, len(blocks))
+	runBatch(len(blocks), BatchConcurrency, func(i int) {
+		results[i] = previewBlock(blocks[i])
+		fmt.Printf("  %s: %s\n", blocks[i], results[i].status)
+	})
+
+	fmt.Println()
+	if failed := printBatchResults(os.Stdout, results); failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// previewBlock runs the preview pipeline for one block of a batch in its own workspace, so
+// blocks can be previewed side by side
+func previewBlock(dir string) batchResult {
+	result := batchResult{dir: dir}
+
+	workspace, err := newPreviewWorkspace()
+	if err != nil {
+		result.status = "failed"
+		result.err = err
+		return result
+	}
+	defer workspace.remove()
+
+	res, err := previewContent(dir, previewOptions{
+		unitsDir:  UnitsDirectory,
+		workspace: workspace,
+		quiet:     true,
+	})
+	if err != nil {
+		result.status = "failed"
+		result.err = err
+		return result
+	}
+
+	result.status = "ok"
+	result.detail = res.url
+	result.warnings = res.warnings
+	if len(result.warnings) > 0 {
+		result.status = "warnings"
+	}
+	return result
+}
+
+// publishAll publishes the default branch of every block repository under dir. Every block
+// is checked first and a single confirmation covers the whole batch
+func publishAll(dir string) {
+	blocks := discoverBlocksOrExit(dir)
+	fmt.Printf("Checking %d blocks under %s, %d at a time...\n", len(blocks), dir, BatchConcurrency)
+
+	plans := make([]*publishPlan, len(blocks))
+	results := make([]batchResult, len(blocks))
+	runBatch(len(blocks), BatchConcurrency, func(i int) {
+		plans[i], results[i] = preparePublish(blocks[i])
+	})
+
+	ready := []int{}
+	for i, plan := range plans {
+		if plan != nil {
+			ready = append(ready, i)
+		}
+	}
+	restoreAll := func() {
+		for _, i := range ready {
+			plans[i].restoreAutoConfig()
+		}
+	}
+
+	fmt.Println()
+	printPublishPlans(os.Stdout, plans, results)
+	fmt.Println()
+
+	if len(ready) == 0 {
+		fmt.Println("No blocks can be published.")
+		os.Exit(1)
+	}
+	if PublishDryRun {
+		restoreAll()
+		fmt.Println("Dry run complete, nothing was pushed or released.")
+		return
+	}
+	if !PublishYes && !confirm(fmt.Sprintf("Publish %d of %d blocks?", len(ready), len(blocks))) {
+		restoreAll()
+		fmt.Println("Publish cancelled.")
+		return
+	}
+
+	fmt.Printf("Publishing %d blocks, %d at a time...\n", len(ready), BatchConcurrency)
+	runBatch(len(ready), BatchConcurrency, func(n int) {
+		i := ready[n]
+		results[i] = publishBlock(plans[i])
+		fmt.Printf("  %s: %s\n", blocks[i], results[i].status)
+	})
+
+	fmt.Println()
+	if failed := printBatchResults(os.Stdout, results); failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// preparePublish looks up and checks one block of a batch. Only the default branch can be
+// published in a batch, cohort and ref releases need the single block flow
+func preparePublish(dir string) (*publishPlan, batchResult) {
+	result := batchResult{dir: dir, status: "ready"}
+
+	plan, err := lookupPublishBlock(dir)
+	if err == nil {
+		err = plan.check(UnitsDirectory, true)
+	}
+	if err == nil && plan.branch != plan.defaultBranch {
+		plan.restoreAutoConfig()
+		err = fmt.Errorf("on branch '%s', publish it on its own to release it for a cohort", plan.branch)
+	}
+	if err != nil {
+		result.status = "failed"
+		result.err = err
+		return nil, result
+	}
+
+	result.warnings = plan.uidChanges
+	return plan, result
+}
+
+// publishBlock pushes and releases one checked block of a batch
+func publishBlock(plan *publishPlan) batchResult {
+	result := batchResult{dir: plan.dir}
+
+	p, err := executePublish(plan, releaseTarget{}, true)
+	if err != nil {
+		result.status = "failed"
+		result.err = err
+		return result
+	}
+
+	result.status = "ok"
+	result.detail = fmt.Sprintf("released block %d", plan.block.ID)
+	result.warnings = p.SyncWarnings
+	if len(result.warnings) > 0 {
+		result.status = "warnings"
+	}
+	return result
+}
+
+// printPublishPlans writes what a batch publish is about to do for each block
+func printPublishPlans(out io.Writer, plans []*publishPlan, results []batchResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BLOCK\tREPO\tBLOCK ID\tBRANCH\tUNPUSHED\tSTATUS")
+	for i, plan := range plans {
+		if plan == nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", results[i].dir, results[i].status)
+			continue
+		}
+
+		blockID := "new"
+		if plan.block.Exists() {
+			blockID = fmt.Sprintf("%d", plan.block.ID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", plan.dir, plan.remote, blockID, plan.branch, plan.unpushed, results[i].status)
+	}
+	w.Flush()
+
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(out, "\n%s failed: %s\n", r.dir, r.err)
+		}
+		if len(r.warnings) > 0 {
+			fmt.Fprintf(out, "\nWARNING: publishing %s changes UIDs, student progress attached to the old UIDs will be lost:\n", r.dir)
+			for _, warning := range r.warnings {
+				fmt.Fprintln(out, "  -", warning)
+			}
+		}
+	}
+}
+
+// printBatchResults writes the outcome of every block of a batch as a table, followed by
+// warnings and errors, and returns how many blocks failed
+func printBatchResults(out io.Writer, results []batchResult) int {
+	failed, warned := 0, 0
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BLOCK\tSTATUS\tWARNINGS\tDETAIL")
+	for _, r := range results {
+		detail := r.detail
+		if r.err != nil {
+			failed++
+			detail = strings.SplitN(r.err.Error(), "\n", 2)[0]
+		}
+		if len(r.warnings) > 0 {
+			warned++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", r.dir, r.status, len(r.warnings), detail)
+	}
+	w.Flush()
+
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(out, "\n%s failed: %s\n", r.dir, r.err)
+		}
+		if len(r.warnings) > 0 {
+			fmt.Fprintf(out, "\nWarnings on %s:\n", r.dir)
+			for _, warning := range r.warnings {
+				fmt.Fprintln(out, "  -", warning)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d succeeded, %d with warnings, %d failed\n", len(results)-failed, warned, failed)
+	return failed
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_discoverBlocks(t *testing.T) {
+	root, err := ioutil.TempDir("", "learn-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, dir := range []string{
+		"block-b/.git",
+		"block-a/.git",
+		"block-a/nested/.git",
+		"group/block-c/.git",
+		"group/deeper/still/block-d/.git",
+		".hidden/block-e/.git",
+		"node_modules/block-f/.git",
+		"not-a-block/units",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blocks, err := discoverBlocks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"block-a", "block-b", filepath.Join("group", "block-c")}
+	if len(blocks) != len(expected) {
+		t.Fatalf("expected blocks %v, got %v", expected, blocks)
+	}
+	for i, block := range blocks {
+		if block != filepath.Join(root, expected[i]) {
+			t.Errorf("block %d should be %s, was %s", i, expected[i], block)
+		}
+	}
+
+	if _, err := discoverBlocks(filepath.Join(root, "missing")); err == nil {
+		t.Errorf("a missing directory should be an error")
+	}
+}
+
+func Test_runBatch(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	done := make([]bool, 20)
+
+	runBatch(len(done), 3, func(i int) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		mu.Lock()
+		done[i] = true
+		running--
+		mu.Unlock()
+	})
+
+	if maxRunning > 3 {
+		t.Errorf("at most 3 jobs should run at once, %d did", maxRunning)
+	}
+	for i, d := range done {
+		if !d {
+			t.Errorf("job %d was not run", i)
+		}
+	}
+}
+
+func Test_printBatchResults(t *testing.T) {
+	var out bytes.Buffer
+	failed := printBatchResults(&out, []batchResult{
+		{dir: "blocks/a", status: "ok", detail: "https://example.com/a"},
+		{dir: "blocks/b", status: "warnings", detail: "https://example.com/b", warnings: []string{"missing image"}},
+		{dir: "blocks/c", status: "failed", err: errors.New("push rejected\nhint: fetch first")},
+	})
+
+	if failed != 1 {
+		t.Errorf("expected 1 failure, got %d", failed)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if lines[3] != "blocks/c  failed    0         push rejected" {
+		t.Errorf("failures should show the first line of the error, got '%s'", lines[3])
+	}
+	if !strings.Contains(out.String(), "Warnings on blocks/b:\n  - missing image") {
+		t.Errorf("warnings should be listed under the table, got:\n%s", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "2 succeeded, 1 with warnings, 1 failed\n") {
+		t.Errorf("summary line was wrong, got:\n%s", out.String())
+	}
+}
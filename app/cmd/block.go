@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+	"github.com/gSchool/glearn-cli/gitutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var blockCmd = &cobra.Command{
+	Use:   "block [command]",
+	Short: "Inspect and manage your blocks on Learn",
+	Long: `
+Inspect and manage the Learn blocks backed by your curriculum repositories.
+
+  learn block show [--repo name]     id, title, repo, sync errors and cohorts
+  learn block create [--repo name]   create the block without publishing
+  learn block list                   every block you own
+
+Add --json to any of them for machine readable output.
+	`,
+}
+
+var blockShowCmd = &cobra.Command{
+	Use:   "show [--repo name]",
+	Short: "Show a block and its sync errors",
+	Long:  "Show a block's id, title, repo, sync errors and the cohorts using it. Defaults to the block of the current repository.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
+			fmt.Print(setAPITokenMessage)
+			os.Exit(1)
+		}
+
+		setupLearnAPI()
+
+		block := lookupBlock(RepoName)
+
+		if BlockJSON {
+			printJSON(os.Stdout, block)
+			return
+		}
+		printBlock(os.Stdout, block)
+	},
+}
+
+var blockCreateCmd = &cobra.Command{
+	Use:   "create [--repo name]",
+	Short: "Create a block on Learn without publishing it",
+	Long:  "Create a block on Learn for a repository without releasing any content. Defaults to the current repository.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
+			fmt.Print(setAPITokenMessage)
+			os.Exit(1)
+		}
+
+		setupLearnAPI()
+
+		repoName := RepoName
+		if repoName == "" {
+			path, _ := os.Getwd()
+			name, err := remoteName(gitutil.New(path))
+			if err != nil {
+				fmt.Printf("Cannot detect the origin remote of this repository, pass --repo instead:\n%s\n", err)
+				os.Exit(1)
+			}
+			repoName = name
+		}
+
+		block, err := learn.API.GetBlockByRepoName(repoName)
+		if err != nil {
+			fmt.Printf("Error fetching block from learn: %s\n", err)
+			os.Exit(1)
+		}
+
+		created := false
+		if !block.Exists() {
+			block, err = learn.API.CreateBlockByRepoName(repoName)
+			if err != nil {
+				fmt.Printf("Error creating block from learn: %s\n", err)
+				os.Exit(1)
+			}
+			created = true
+		}
+
+		if BlockJSON {
+			printJSON(os.Stdout, block)
+			return
+		}
+		if created {
+			fmt.Printf("Created block %d for repo %s\n\n", block.ID, block.RepoName)
+		} else {
+			fmt.Printf("Block %d already exists for repo %s\n\n", block.ID, block.RepoName)
+		}
+		printBlock(os.Stdout, block)
+	},
+}
+
+var blockListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the blocks you own",
+	Long:  "List every block on Learn owned by you, with the number of sync errors and cohorts using each.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
+			fmt.Print(setAPITokenMessage)
+			os.Exit(1)
+		}
+
+		setupLearnAPI()
+
+		blocks, err := learn.API.GetOwnedBlocks()
+		if err != nil {
+			fmt.Printf("Error fetching blocks from learn: %s\n", err)
+			os.Exit(1)
+		}
+
+		if BlockJSON {
+			printJSON(os.Stdout, blocks)
+			return
+		}
+		if len(blocks) == 0 {
+			fmt.Println("You do not own any blocks yet. Run `learn publish` from a curriculum repository to create one.")
+			return
+		}
+		printBlocks(os.Stdout, blocks)
+	},
+}
+
+// printBlock writes the details of a single block
+func printBlock(out io.Writer, block learn.Block) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", block.ID)
+	fmt.Fprintf(w, "Title:\t%s\n", block.Title)
+	fmt.Fprintf(w, "Repo:\t%s\n", block.RepoName)
+	fmt.Fprintf(w, "Cohorts:\t%s\n", joinCohorts(block.CohortsUsing))
+	w.Flush()
+
+	if len(block.SyncErrors) == 0 {
+		fmt.Fprintln(out, "Sync errors: none")
+		return
+	}
+	fmt.Fprintf(out, "Sync errors (%d):\n", len(block.SyncErrors))
+	for _, e := range block.SyncErrors {
+		fmt.Fprintln(out, "  -", e)
+	}
+}
+
+// printBlocks writes blocks as a table
+func printBlocks(out io.Writer, blocks []learn.Block) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tREPO\tTITLE\tSYNC ERRORS\tCOHORTS")
+	for _, b := range blocks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\n", b.ID, b.RepoName, b.Title, len(b.SyncErrors), len(b.CohortsUsing))
+	}
+	w.Flush()
+}
+
+// printJSON writes v as indented JSON
+func printJSON(out io.Writer, v interface{}) {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Printf("Error encoding json: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// joinCohorts formats cohort ids for display
+func joinCohorts(ids []int) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+
+	cohorts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cohorts = append(cohorts, strconv.Itoa(id))
+	}
+	return strings.Join(cohorts, ", ")
+}
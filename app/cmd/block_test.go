@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+)
+
+func Test_printBlock(t *testing.T) {
+	var out bytes.Buffer
+	printBlock(&out, learn.Block{ID: 1, RepoName: "blocks-test", Title: "Blocks Test", SyncErrors: []string{"somethin is wrong"}, CohortsUsing: []int{7, 9}})
+
+	expected := `ID:       1
+Title:    Blocks Test
+Repo:     blocks-test
+Cohorts:  7, 9
+Sync errors (1):
+  - somethin is wrong
+`
+	if out.String() != expected {
+		t.Errorf("printBlock expected:\n%s\nbut got:\n%s", expected, out.String())
+	}
+}
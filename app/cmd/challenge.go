@@ -0,0 +1,431 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// challengeAttributeLine matches a `* name: value` line at the top of a challenge
+var challengeAttributeLine = regexp.MustCompile(`^\*\s*([A-Za-z_]+):\s?(.*?)\s*$`)
+
+// challengeSectionLine matches the start of a `##### !name` section, capturing the name
+var challengeSectionLine = regexp.MustCompile(`^#####\s+!([a-z_-]+)\s*$`)
+
+// challengeOptionalAttributeLine matches the `<!-- * name: ... -->` comments the templates
+// mention optional attributes in
+var challengeOptionalAttributeLine = regexp.MustCompile(`^<!--\s*\*\s*([A-Za-z_]+):`)
+
+// challengeOptionalSectionLine matches the `<!-- !name - !end-name ... -->` comments the
+// templates mention optional sections in
+var challengeOptionalSectionLine = regexp.MustCompile(`^<!--\s*!([a-z_-]+)\s+-\s+!end-`)
+
+// challengeTemplates are the templates in markdown.go that challenges are written like
+var challengeTemplates = []string{
+	multiplechoiceTemplate,
+	checkboxTemplate,
+	shortanswerTemplate,
+	numberTemplate,
+	paragraphTemplate,
+	javascriptTemplate,
+	javaTemplate,
+	pythonTemplate,
+	sqlTemplate,
+	customsnippetTemplate,
+	projectTemplate,
+	testableProjectTemplate,
+}
+
+// challengeLayouts are the layouts of challengeTemplates, in the same order
+var challengeLayouts = parseChallengeLayouts(challengeTemplates)
+
+// challengeLayout is how a template lays a challenge out, so challenges written by the CLI
+// look like the ones written from the templates
+type challengeLayout struct {
+	// begin and end are the comments around the challenge
+	begin, end    string
+	challengeType string
+	language      string
+	// attributes are in the order they are written, the optional ones mentioned in comments
+	// included
+	attributes []string
+	// sections are written out in the template in order, optional ones are only mentioned in
+	// its comments
+	sections []string
+	optional []string
+}
+
+// parseChallengeLayouts reads the layout of each challenge template
+func parseChallengeLayouts(templates []string) []challengeLayout {
+	layouts := make([]challengeLayout, 0, len(templates))
+	for _, template := range templates {
+		lines := strings.Split(strings.TrimSpace(template), "\n")
+		l := challengeLayout{begin: lines[0], end: lines[len(lines)-1]}
+
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if match := challengeSectionLine.FindStringSubmatch(line); match != nil {
+				if !strings.HasPrefix(match[1], "end-") {
+					l.sections = append(l.sections, match[1])
+				}
+				continue
+			}
+			if match := challengeOptionalSectionLine.FindStringSubmatch(line); match != nil {
+				l.optional = append(l.optional, match[1])
+				continue
+			}
+			if len(l.sections) > 0 {
+				continue
+			}
+			if match := challengeAttributeLine.FindStringSubmatch(line); match != nil {
+				l.attributes = append(l.attributes, match[1])
+				switch match[1] {
+				case "type":
+					l.challengeType = match[2]
+				case "language":
+					l.language = match[2]
+				}
+			} else if match := challengeOptionalAttributeLine.FindStringSubmatch(line); match != nil {
+				l.attributes = append(l.attributes, match[1])
+			}
+		}
+
+		layouts = append(layouts, l)
+	}
+	return layouts
+}
+
+// challengeLayoutFor is the layout of the template for challengeType, preferring the one
+// written for language when templates share a type. ok is false when no template has the type
+func challengeLayoutFor(challengeType, language string) (layout challengeLayout, ok bool) {
+	for _, l := range challengeLayouts {
+		if l.challengeType != challengeType {
+			continue
+		}
+		if l.language == language {
+			return l, true
+		}
+		if !ok {
+			layout, ok = l, true
+		}
+	}
+	return layout, ok
+}
+
+// challenge is a `### !challenge` block in a markdown file. The sections that aren't given
+// their own field are kept in Sections in the order they were written
+type challenge struct {
+	File        string               `json:"file,omitempty"`
+	ID          string               `json:"id"`
+	Type        string               `json:"type"`
+	Title       string               `json:"title"`
+	Attributes  []challengeAttribute `json:"attributes,omitempty"`
+	Question    string               `json:"question"`
+	Options     []string             `json:"options,omitempty"`
+	Answers     []string             `json:"answers,omitempty"`
+	Placeholder string               `json:"placeholder,omitempty"`
+	Hint        string               `json:"hint,omitempty"`
+	Rubric      string               `json:"rubric,omitempty"`
+	Explanation string               `json:"explanation,omitempty"`
+	Sections    []challengeSection   `json:"sections,omitempty"`
+
+	// start and end are the lines of `### !challenge` and `### !end-challenge`
+	start, end int
+}
+
+// challengeAttribute is a `* name: value` line other than type, id and title
+type challengeAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// challengeSection is a `##### !name` section without a field of its own in challenge
+type challengeSection struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// isChoice is true for challenges whose options and answers are lists
+func (c challenge) isChoice() bool {
+	return c.Type == "multiple-choice" || c.Type == "checkbox"
+}
+
+// attribute returns the value of the attribute called name
+func (c challenge) attribute(name string) string {
+	for _, a := range c.Attributes {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseChallenges finds every challenge in the markdown source, skipping examples inside code
+// blocks. file is recorded on each challenge
+func parseChallenges(file, source string) ([]challenge, error) {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	challenges := []challenge{}
+
+	inFence := false
+	for i := 0; i < len(lines); i++ {
+		if fenceLine.MatchString(lines[i]) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || strings.TrimSpace(lines[i]) != "### !challenge" {
+			continue
+		}
+
+		c, err := parseChallenge(lines, i)
+		if err != nil {
+			return challenges, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		c.File = file
+		challenges = append(challenges, c)
+		i = c.end
+	}
+
+	return challenges, nil
+}
+
+// parseChallenge reads the challenge starting at the `### !challenge` line start
+func parseChallenge(lines []string, start int) (challenge, error) {
+	c := challenge{start: start}
+	inSections := false
+
+	for i := start + 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		if strings.TrimSpace(line) == "### !end-challenge" {
+			c.end = i
+			if c.Type == "" {
+				return c, fmt.Errorf("challenge has no type")
+			}
+			return c, nil
+		}
+
+		if match := challengeSectionLine.FindStringSubmatch(line); match != nil {
+			inSections = true
+			end := sectionEnd(lines, i, match[1])
+			if end == -1 {
+				return c, fmt.Errorf("section !%s has no !end-%s", match[1], match[1])
+			}
+			c.setSection(match[1], strings.Trim(strings.Join(lines[i+1:end], "\n"), "\n"))
+			i = end
+			continue
+		}
+
+		if match := challengeAttributeLine.FindStringSubmatch(line); match != nil && !inSections {
+			c.setAttribute(match[1], match[2])
+		}
+	}
+
+	return c, fmt.Errorf("challenge has no ### !end-challenge")
+}
+
+// sectionEnd is the line ending the section called name that starts at start, or -1
+func sectionEnd(lines []string, start int, name string) int {
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "##### !end-"+name {
+			return i
+		}
+		if trimmed == "### !end-challenge" {
+			return -1
+		}
+	}
+	return -1
+}
+
+// setAttribute records a `* name: value` line
+func (c *challenge) setAttribute(name, value string) {
+	switch name {
+	case "type":
+		c.Type = value
+	case "id":
+		c.ID = value
+	case "title":
+		c.Title = value
+	default:
+		c.Attributes = append(c.Attributes, challengeAttribute{Name: name, Value: value})
+	}
+}
+
+// setSection records the content of a `##### !name` section
+func (c *challenge) setSection(name, content string) {
+	switch name {
+	case "question":
+		c.Question = content
+		return
+	case "placeholder":
+		c.Placeholder = content
+		return
+	case "hint":
+		c.Hint = content
+		return
+	case "rubric":
+		c.Rubric = content
+		return
+	case "explanation":
+		c.Explanation = content
+		return
+	case "options", "answer":
+		items, ok := listItems(content)
+		if name == "options" && c.isChoice() && ok {
+			c.Options = items
+			return
+		}
+		if name == "answer" && c.isChoice() && ok {
+			c.Answers = items
+			return
+		}
+		if name == "answer" && !c.isChoice() {
+			c.Answers = strings.Split(content, "\n")
+			return
+		}
+	}
+	c.Sections = append(c.Sections, challengeSection{Name: name, Content: content})
+}
+
+// listItems splits a markdown list with one line per item into its items, ok is false when
+// content is anything else
+func listItems(content string) ([]string, bool) {
+	items := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, "* ") && !strings.HasPrefix(line, "- ") {
+			return nil, false
+		}
+		items = append(items, strings.TrimSpace(line[2:]))
+	}
+	return items, len(items) > 0
+}
+
+// markdown renders the challenge laid out like its template in markdown.go, or like the first
+// template when its type has none
+func (c challenge) markdown() string {
+	layout, ok := challengeLayoutFor(c.Type, c.attribute("language"))
+	if !ok {
+		layout = challengeLayouts[0]
+	}
+
+	var b strings.Builder
+	b.WriteString(layout.begin + "\n\n")
+	b.WriteString("### !challenge\n\n")
+
+	written := map[string]struct{}{}
+	for _, name := range layout.attributes {
+		written[name] = struct{}{}
+		switch name {
+		case "type":
+			fmt.Fprintf(&b, "* type: %s\n", c.Type)
+		case "id":
+			fmt.Fprintf(&b, "* id: %s\n", c.ID)
+		case "title":
+			fmt.Fprintf(&b, "* title: %s\n", c.Title)
+		default:
+			for _, a := range c.Attributes {
+				if a.Name == name {
+					fmt.Fprintf(&b, "* %s: %s\n", a.Name, a.Value)
+				}
+			}
+		}
+	}
+	for _, a := range c.Attributes {
+		if _, ok := written[a.Name]; !ok {
+			fmt.Fprintf(&b, "* %s: %s\n", a.Name, a.Value)
+		}
+	}
+	b.WriteString("\n")
+
+	for _, s := range c.orderedSections(layout) {
+		fmt.Fprintf(&b, "##### !%s\n\n%s\n\n##### !end-%s\n\n", s.Name, s.Content, s.Name)
+	}
+
+	b.WriteString("### !end-challenge\n\n")
+	b.WriteString(layout.end)
+	return b.String()
+}
+
+// orderedSections lists every non-empty section in the order of layout: the sections it
+// writes out, then the sections it doesn't mention, then its optional sections
+func (c challenge) orderedSections(layout challengeLayout) []challengeSection {
+	sections := []challengeSection{{Name: "question", Content: c.Question}}
+	add := func(name, content string) {
+		if content != "" {
+			sections = append(sections, challengeSection{Name: name, Content: content})
+		}
+	}
+
+	add("placeholder", c.Placeholder)
+	if c.isChoice() {
+		if len(c.Options) > 0 {
+			add("options", "* "+strings.Join(c.Options, "\n* "))
+		}
+		if len(c.Answers) > 0 {
+			add("answer", "* "+strings.Join(c.Answers, "\n* "))
+		}
+	} else {
+		add("answer", strings.Join(c.Answers, "\n"))
+	}
+	add("hint", c.Hint)
+	add("rubric", c.Rubric)
+	add("explanation", c.Explanation)
+	sections = append(sections, c.Sections...)
+
+	rank := func(name string) int {
+		for i, n := range layout.sections {
+			if n == name {
+				return i
+			}
+		}
+		for i, n := range layout.optional {
+			if n == name {
+				return len(layout.sections) + 1 + i
+			}
+		}
+		return len(layout.sections)
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		return rank(sections[i].Name) < rank(sections[j].Name)
+	})
+
+	return sections
+}
+
+// validate reports what would stop the challenge working in Learn
+func (c challenge) validate() error {
+	if _, ok := challengeLayoutFor(c.Type, ""); !ok {
+		return fmt.Errorf("unknown challenge type '%s'", c.Type)
+	}
+	if c.ID == "" {
+		return fmt.Errorf("challenge has no id")
+	}
+	if strings.TrimSpace(c.Question) == "" {
+		return fmt.Errorf("challenge %s has no question", c.ID)
+	}
+	if !c.isChoice() {
+		return nil
+	}
+
+	if len(c.Options) < 2 {
+		return fmt.Errorf("challenge %s needs at least two options", c.ID)
+	}
+	if c.Type == "multiple-choice" && len(c.Answers) != 1 {
+		return fmt.Errorf("challenge %s needs exactly one answer", c.ID)
+	}
+	if len(c.Answers) == 0 {
+		return fmt.Errorf("challenge %s needs at least one answer", c.ID)
+	}
+	options := map[string]struct{}{}
+	for _, o := range c.Options {
+		options[o] = struct{}{}
+	}
+	for _, a := range c.Answers {
+		if _, ok := options[a]; !ok {
+			return fmt.Errorf("challenge %s has the answer '%s' which is not one of its options", c.ID, a)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// canonicalChallenges are challenges laid out exactly as challenge.markdown renders them
+var canonicalChallenges = []string{`<!-- >>>>>>>>>>>>>>>>>>>>>> BEGIN CHALLENGE >>>>>>>>>>>>>>>>>>>>>> -->
+
+### !challenge
+
+* type: multiple-choice
+* id: loops-mc
+* title: Loop count
+* points: 2
+* topics: [loops, python]
+
+##### !question
+
+How many times does ` + "`for i in range(3)`" + ` run?
+
+##### !end-question
+
+##### !options
+
+* 2
+* 3
+* 4
+
+##### !end-options
+
+##### !answer
+
+* 3
+
+##### !end-answer
+
+##### !hint
+
+Start counting at 0.
+
+##### !end-hint
+
+### !end-challenge
+
+<!-- ======================= END CHALLENGE ======================= -->`, `<!-- >>>>>>>>>>>>>>>>>>>>>> BEGIN CHALLENGE >>>>>>>>>>>>>>>>>>>>>> -->
+
+### !challenge
+
+* type: short-answer
+* id: loops-sa
+* title: Keyword
+
+##### !question
+
+Which keyword stops a loop?
+
+##### !end-question
+
+##### !placeholder
+
+a keyword
+
+##### !end-placeholder
+
+##### !answer
+
+/^break$/
+
+##### !end-answer
+
+### !end-challenge
+
+<!-- ======================= END CHALLENGE ======================= -->`, `<!-- >>>>>>>>>>>>>>>>>>>>>> BEGIN CHALLENGE >>>>>>>>>>>>>>>>>>>>>> -->
+
+### !challenge
+
+* type: code-snippet
+* language: python3.6
+* id: loops-code
+* title: Sum
+* points: 5
+
+##### !question
+
+Sum a list.
+
+##### !end-question
+
+##### !placeholder
+
+` + "```py" + `
+def total(xs):
+    pass
+` + "```" + `
+
+##### !end-placeholder
+
+##### !tests
+
+` + "```py" + `
+class TestTotal(unittest.TestCase):
+    def test_total(self):
+        self.assertEqual(total([1, 2]), 3)
+` + "```" + `
+
+##### !end-tests
+
+### !end-challenge
+
+<!-- ======================= END CHALLENGE ======================= -->`}
+
+// canonicalLesson is a lesson holding canonicalChallenges and an example of a challenge in a
+// code block that isn't one
+var canonicalLesson = "# Loops\n\n~~~md\n### !challenge\n* type: number\n### !end-challenge\n~~~\n\n" +
+	strings.Join(canonicalChallenges, "\n\nSome more content.\n\n") + "\n"
+
+func Test_parseChallenges(t *testing.T) {
+	challenges, err := parseChallenges("units/loops.md", canonicalLesson)
+	if err != nil {
+		t.Fatalf("parseChallenges errored: %s", err)
+	}
+	if len(challenges) != 3 {
+		t.Fatalf("expected 3 challenges, got %d", len(challenges))
+	}
+
+	mc := challenges[0]
+	if mc.File != "units/loops.md" || mc.ID != "loops-mc" || strings.Join(mc.Options, ",") != "2,3,4" || strings.Join(mc.Answers, ",") != "3" || mc.attribute("topics") != "[loops, python]" {
+		t.Errorf("unexpected multiple choice challenge %+v", mc)
+	}
+	code := challenges[2]
+	if len(code.Sections) != 1 || code.Sections[0].Name != "tests" || code.attribute("language") != "python3.6" {
+		t.Errorf("unexpected code challenge %+v", code)
+	}
+
+	for i, c := range challenges {
+		if c.markdown() != canonicalChallenges[i] {
+			t.Errorf("challenge %d should render as it was written, expected:\n%s\nbut got:\n%s", i, canonicalChallenges[i], c.markdown())
+		}
+	}
+
+	if _, err = parseChallenges("bad.md", "### !challenge\n* type: number\n##### !question\n\nWhat?\n### !end-challenge\n"); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("a section without an end should error with its line, got %v", err)
+	}
+}
+
+func Test_challengeLayoutMatchesTemplates(t *testing.T) {
+	if len(challengeLayouts) != len(challengeTemplates) {
+		t.Fatalf("every challenge template should have a layout, got %d of %d", len(challengeLayouts), len(challengeTemplates))
+	}
+
+	for _, tmp := range templates {
+		if !tmp.RequireId || !strings.Contains(tmp.Template, "### !challenge") {
+			continue
+		}
+
+		source := fmt.Sprintf(tmp.Template, "id")
+		challenges, err := parseChallenges("", source)
+		if err != nil || len(challenges) != 1 {
+			t.Errorf("%s should parse as one challenge, got %v", tmp.Name, err)
+			continue
+		}
+		if _, ok := challengeLayoutFor(challenges[0].Type, challenges[0].attribute("language")); !ok {
+			t.Errorf("%s has a type without a layout: %s", tmp.Name, challenges[0].Type)
+		}
+
+		// Rendering a challenge written from a template writes its lines in the same order
+		written, rendered := []string{}, []string{}
+		for _, line := range strings.Split(source, "\n") {
+			if challengeSectionLine.MatchString(line) || challengeAttributeLine.MatchString(line) || strings.HasPrefix(line, "<!-- >") || strings.HasPrefix(line, "<!-- =") {
+				written = append(written, line)
+			}
+		}
+		for _, line := range strings.Split(challenges[0].markdown(), "\n") {
+			if challengeSectionLine.MatchString(line) || challengeAttributeLine.MatchString(line) || strings.HasPrefix(line, "<!--") {
+				rendered = append(rendered, line)
+			}
+		}
+		if strings.Join(written, "\n") != strings.Join(rendered, "\n") {
+			t.Errorf("%s is written:\n%s\nbut rendered:\n%s", tmp.Name, strings.Join(written, "\n"), strings.Join(rendered, "\n"))
+		}
+	}
+}
+
+func Test_challengeOptionalLayout(t *testing.T) {
+	c := challenge{
+		ID:         "loops-sa",
+		Type:       "short-answer",
+		Title:      "Keyword",
+		Question:   "Which keyword?",
+		Answers:    []string{"for"},
+		Hint:       "It is short",
+		Attributes: []challengeAttribute{{"topics", "[loops]"}, {"difficulty", "easy"}, {"points", "2"}},
+		Sections:   []challengeSection{{"notes", "Extra"}},
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(c.markdown(), "\n") {
+		if challengeSectionLine.MatchString(line) || challengeAttributeLine.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	expected := []string{
+		"* type: short-answer", "* id: loops-sa", "* title: Keyword", "* points: 2", "* topics: [loops]", "* difficulty: easy",
+		"##### !question", "##### !end-question", "##### !answer", "##### !end-answer",
+		"##### !notes", "##### !end-notes", "##### !hint", "##### !end-hint",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("optional attributes and sections should follow the template's comments, got:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,447 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// challengeCSVColumns are the columns of a challenge CSV, in the order they are exported
+var challengeCSVColumns = []string{"file", "id", "type", "title", "attributes", "question", "options", "answers", "placeholder", "hint", "rubric", "explanation", "sections"}
+
+var challengesCmd = &cobra.Command{
+	Use:   "challenges [command]",
+	Short: "Move challenges between markdown and spreadsheets",
+	Long: `
+Export every challenge in a directory to JSON or CSV, or import challenges from
+a JSON or CSV file as challenge markdown laid out like the 'learn md' templates.
+
+  learn challenges export <dir> --format json|csv > challenges.csv
+  learn challenges import challenges.csv --into lesson.md
+
+In CSV, options and answers have one per line and attributes other than type,
+id and title are 'name: value' lines. Importing an export gives back the same
+challenge markdown.
+
+Challenges can also be moved to and from other quiz tools as Moodle GIFT text
+(.gift or .txt) and IMS QTI 2.1 packages (.zip, or a single item .xml):
+
+  learn challenges export <dir> --format gift > quiz.gift
+  learn challenges export <dir> --format qti --out quiz.zip
+  learn challenges import quiz.zip --into lesson.md
+
+Only multiple-choice, checkbox, short-answer, number and paragraph challenges
+have an equivalent. Everything that is skipped or loses a part on the way, like
+a hint, is listed after converting.
+	`,
+}
+
+var challengesExportCmd = &cobra.Command{
+	Use:   "export [dir]",
+	Short: "Print every challenge in a directory as JSON, CSV, GIFT or QTI",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+
+		challenges, err := collectChallenges(dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err = exportChallenges(challenges, ChallengesFormat, ChallengesOut); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var challengesImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Turn a JSON, CSV, GIFT or QTI file of challenges into challenge markdown",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if ChallengesInto != "" {
+			if err := checkAppendTarget(ChallengesInto); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		challenges, notes, err := readChallengesFile(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printConversionNotes(notes)
+		markdown, err := importChallenges(challenges)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if ChallengesInto == "" {
+			fmt.Println(markdown)
+			return
+		}
+		if err = appendMarkdown(ChallengesInto, markdown); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d challenges added to %s\n", len(challenges), ChallengesInto)
+	},
+}
+
+// collectChallenges parses the challenges in every markdown file under dir
+func collectChallenges(dir string) ([]challenge, error) {
+	challenges := []challenge{}
+	err := walkMarkdownFiles(dir, func(path, rel string) error {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		found, err := parseChallenges(rel, string(b))
+		if err != nil {
+			return fmt.Errorf("Could not read the challenges in %s, %s", path, err)
+		}
+		challenges = append(challenges, found...)
+		return nil
+	})
+
+	return challenges, err
+}
+
+// walkMarkdownFiles calls fn with the path of every markdown file under dir, and that path
+// relative to dir with forward slashes. Hidden directories, node_modules and anything in the
+// .learnignore are skipped
+func walkMarkdownFiles(dir string, fn func(path, rel string) error) error {
+	ignore, err := loadIgnoreList(dir)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if rel == "." {
+			return nil
+		}
+
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules" || ignore.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".md" || ignore.ignored(rel, false) {
+			return nil
+		}
+		return fn(path, filepath.ToSlash(rel))
+	})
+}
+
+// writeChallengesJSON writes challenges as an indented JSON array
+func writeChallengesJSON(w io.Writer, challenges []challenge) error {
+	b, err := json.MarshalIndent(challenges, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// writeChallengesCSV writes challenges with a header row of challengeCSVColumns
+func writeChallengesCSV(w io.Writer, challenges []challenge) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(challengeCSVColumns); err != nil {
+		return err
+	}
+
+	for _, c := range challenges {
+		attributes := []string{}
+		for _, a := range c.Attributes {
+			attributes = append(attributes, a.Name+": "+a.Value)
+		}
+		sections := []string{}
+		for _, s := range c.Sections {
+			sections = append(sections, fmt.Sprintf("##### !%s\n\n%s\n\n##### !end-%s", s.Name, s.Content, s.Name))
+		}
+
+		err := out.Write([]string{
+			c.File,
+			c.ID,
+			c.Type,
+			c.Title,
+			strings.Join(attributes, "\n"),
+			c.Question,
+			strings.Join(c.Options, "\n"),
+			strings.Join(c.Answers, "\n"),
+			c.Placeholder,
+			c.Hint,
+			c.Rubric,
+			c.Explanation,
+			strings.Join(sections, "\n\n"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// exportChallenges writes challenges in format to the file out, or stdout when out is empty.
+// QTI packages are zip files so they can only be written to a file
+func exportChallenges(challenges []challenge, format, out string) error {
+	switch format {
+	case "json", "csv", "gift", "qti":
+	default:
+		return fmt.Errorf("Unknown format '%s', use json, csv, gift or qti", format)
+	}
+	if format == "qti" && out == "" {
+		return fmt.Errorf("QTI packages are zip files, use --out to name the file to write")
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var notes []string
+	var err error
+	switch format {
+	case "json":
+		err = writeChallengesJSON(w, challenges)
+	case "csv":
+		err = writeChallengesCSV(w, challenges)
+	case "gift":
+		notes, err = writeChallengesGIFT(w, challenges)
+	case "qti":
+		notes, err = writeChallengesQTI(w, challenges)
+	}
+	printConversionNotes(notes)
+
+	return err
+}
+
+// printConversionNotes lists what didn't convert, on stderr so it stays out of exports
+func printConversionNotes(notes []string) {
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Not everything could be converted:")
+	for _, note := range notes {
+		fmt.Fprintln(os.Stderr, "  "+note)
+	}
+}
+
+// readChallengesFile reads challenges from a .json, .csv, .gift, .txt, .zip or .xml file,
+// returning notes about anything that could not be read as a challenge
+func readChallengesFile(path string) ([]challenge, []string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".zip" || ext == ".xml" {
+		return readChallengesQTI(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	switch ext {
+	case ".json":
+		challenges := []challenge{}
+		if err = json.NewDecoder(f).Decode(&challenges); err != nil {
+			return nil, nil, fmt.Errorf("%s is not a JSON array of challenges: %s", path, err)
+		}
+		return challenges, nil, nil
+	case ".csv":
+		challenges, err := readChallengesCSV(f)
+		return challenges, nil, err
+	case ".gift", ".txt":
+		return readChallengesGIFT(f)
+	}
+
+	return nil, nil, fmt.Errorf("%s must be a .json, .csv, .gift, .txt, .zip or .xml file", path)
+}
+
+// readChallengesCSV reads challenges from CSV with a header row naming its columns. Columns can
+// be in any order and any of them can be left out
+func readChallengesCSV(r io.Reader) ([]challenge, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the CSV is empty, it needs a header row naming the columns")
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["type"]; !ok {
+		return nil, fmt.Errorf("the CSV needs a type column, the columns are: %s", strings.Join(challengeCSVColumns, ", "))
+	}
+
+	challenges := []challenge{}
+	for n, row := range rows[1:] {
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.Trim(strings.ReplaceAll(row[i], "\r\n", "\n"), "\n")
+			}
+			return ""
+		}
+
+		c := challenge{
+			File:        cell("file"),
+			ID:          strings.TrimSpace(cell("id")),
+			Type:        strings.TrimSpace(cell("type")),
+			Title:       cell("title"),
+			Question:    cell("question"),
+			Placeholder: cell("placeholder"),
+			Hint:        cell("hint"),
+			Rubric:      cell("rubric"),
+			Explanation: cell("explanation"),
+		}
+
+		for _, line := range nonEmptyLines(cell("attributes")) {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("row %d: attribute '%s' should be written as 'name: value'", n+2, line)
+			}
+			c.Attributes = append(c.Attributes, challengeAttribute{Name: strings.TrimSpace(parts[0]), Value: strings.TrimSpace(parts[1])})
+		}
+
+		if c.isChoice() {
+			c.Options = listCell(cell("options"))
+			c.Answers = listCell(cell("answers"))
+		} else if answers := cell("answers"); answers != "" {
+			c.Answers = strings.Split(answers, "\n")
+		}
+
+		if err := c.addSectionsText(cell("sections")); err != nil {
+			return nil, fmt.Errorf("row %d: %s", n+2, err)
+		}
+
+		challenges = append(challenges, c)
+	}
+
+	return challenges, nil
+}
+
+// addSectionsText adds the `##### !name` sections written out in text
+func (c *challenge) addSectionsText(text string) error {
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		match := challengeSectionLine.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if match == nil {
+			if strings.TrimSpace(lines[i]) != "" {
+				return fmt.Errorf("'%s' is outside of a ##### !section in the sections column", lines[i])
+			}
+			continue
+		}
+
+		end := sectionEnd(lines, i, match[1])
+		if end == -1 {
+			return fmt.Errorf("section !%s has no !end-%s", match[1], match[1])
+		}
+		c.setSection(match[1], strings.Trim(strings.Join(lines[i+1:end], "\n"), "\n"))
+		i = end
+	}
+
+	return nil
+}
+
+// nonEmptyLines splits text into lines, leaving out blank ones
+func nonEmptyLines(text string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// listCell reads options or answers written one per line, with or without list markers
+func listCell(text string) []string {
+	items := []string{}
+	for _, line := range nonEmptyLines(text) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- ") {
+			line = strings.TrimSpace(line[2:])
+		}
+		items = append(items, line)
+	}
+	return items
+}
+
+// importChallenges checks every challenge, giving any without an id a new one, and renders them
+// as markdown. Nothing is rendered unless every challenge is valid
+func importChallenges(challenges []challenge) (string, error) {
+	problems := []string{}
+	rendered := []string{}
+	for i := range challenges {
+		if challenges[i].ID == "" {
+			challenges[i].ID = uuid.New().String()
+		}
+		if err := challenges[i].validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("challenge %d: %s", i+1, err))
+			continue
+		}
+		rendered = append(rendered, challenges[i].markdown())
+	}
+
+	if len(problems) > 0 {
+		return "", fmt.Errorf("Nothing was imported, fix these challenges first:\n  %s", strings.Join(problems, "\n  "))
+	}
+	if len(rendered) == 0 {
+		return "", fmt.Errorf("There are no challenges to import")
+	}
+
+	return strings.Join(rendered, "\n\n"), nil
+}
+
+// appendMarkdown adds markdown to the end of the file at target, after a blank line
+func appendMarkdown(target, markdown string) error {
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(target)
+	if err != nil {
+		return err
+	}
+
+	lines := 0
+	if len(b) > 0 {
+		lines = len(strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"))
+	}
+	updated, err := insertTemplate(string(b), markdown, insertPosition{line: lines + 1})
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(target, []byte(updated), info.Mode())
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_exportImportRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-challenges")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "units"), 0755)
+	os.MkdirAll(filepath.Join(dir, "node_modules"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "units", "loops.md"), []byte(canonicalLesson), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "node_modules", "skip.md"), []byte(canonicalLesson), 0644)
+
+	challenges, err := collectChallenges(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(challenges) != 3 || challenges[0].File != "units/loops.md" {
+		t.Fatalf("expected the 3 challenges in units/loops.md, got %d", len(challenges))
+	}
+	expected := strings.Join(canonicalChallenges, "\n\n")
+
+	var csvOut bytes.Buffer
+	if err = writeChallengesCSV(&csvOut, challenges); err != nil {
+		t.Fatal(err)
+	}
+	fromCSV, err := readChallengesCSV(&csvOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markdown, err := importChallenges(fromCSV); err != nil || markdown != expected {
+		t.Errorf("importing the CSV export expected:\n%s\nbut got:\n%s\n%v", expected, markdown, err)
+	}
+
+	var jsonOut bytes.Buffer
+	if err = writeChallengesJSON(&jsonOut, challenges); err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "challenges.json")
+	ioutil.WriteFile(jsonPath, jsonOut.Bytes(), 0644)
+	fromJSON, _, err := readChallengesFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markdown, err := importChallenges(fromJSON); err != nil || markdown != expected {
+		t.Errorf("importing the JSON export expected:\n%s\nbut got:\n%s\n%v", expected, markdown, err)
+	}
+}
+
+func Test_importSpreadsheetCSV(t *testing.T) {
+	sheet := "Type,Title,Question,Options,Answers\n" +
+		"checkbox,Primes,Which are prime?,\"2\n- 3\n* 4\",\"2\n3\"\n" +
+		"number,Pi,What is pi?,,3.14\n"
+
+	challenges, err := readChallengesCSV(strings.NewReader(sheet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown, err := importChallenges(challenges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(markdown, "##### !options\n\n* 2\n* 3\n* 4\n\n##### !end-options") || !strings.Contains(markdown, "##### !answer\n\n3.14\n\n##### !end-answer") {
+		t.Errorf("spreadsheet rows should become challenges, got:\n%s", markdown)
+	}
+	if challenges[0].ID == "" || challenges[0].ID == challenges[1].ID {
+		t.Errorf("challenges without an id should get a new one")
+	}
+
+	bad := "type,question,options,answers\nmultiple-choice,Pick,\"a\nb\",c\nessay,Write,,\n"
+	challenges, _ = readChallengesCSV(strings.NewReader(bad))
+	if _, err = importChallenges(challenges); err == nil || !strings.Contains(err.Error(), "'c' which is not one of its options") || !strings.Contains(err.Error(), "unknown challenge type 'essay'") {
+		t.Errorf("every invalid challenge should be reported, got %v", err)
+	}
+}
+
+func Test_appendMarkdown(t *testing.T) {
+	f, err := ioutil.TempFile("", "learn-lesson-*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# Lesson\nContent")
+	f.Close()
+
+	if err = appendMarkdown(f.Name(), "NEW"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(f.Name()); string(b) != "# Lesson\nContent\n\nNEW\n" {
+		t.Errorf("imported challenges should follow a blank line, got %q", b)
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Check whether or nor a config file exists and if it does not we are going to attempt to create one
@@ -50,7 +53,7 @@ func doesConfigExistOrCreate(target, unitsDir string, isSingleFilePreview bool)
 				// Neither exists so we are going to create one
 				fmt.Printf("INFO: No configuration found, generating autoconfig.yaml ")
 			}
-			if target == tmpSingleFileDir {
+			if isStagedSingleFile(target) {
 				err := createAutoConfig(target, ".")
 				if err != nil {
 					return false, err
@@ -90,18 +93,45 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 		os.Remove(autoConfigYamlPath)
 	}
 
-	// Create tmpSingleFileDir if it does not exist
-	if _, err := os.Stat(tmpSingleFileDir); os.IsNotExist(err) {
-		os.Mkdir(tmpSingleFileDir, os.FileMode(0777))
+	lock, err := loadUIDLock(blockRoot)
+	if err != nil {
+		return err
+	}
+
+	var configFile bytes.Buffer
+	configFile.WriteString("# This file is auto-generated and orders your content based on the file structure of your repo.\n")
+	configFile.WriteString("# Do not edit this file; it will be replaced the next time you run the preview command.\n")
+	configFile.WriteString("\n")
+	configFile.WriteString("# To manually order the contents of this curriculum rather than using the auto-generated file,\n")
+	configFile.WriteString("# include a config.yaml in your repo following the same conventions as this auto-generated file.\n")
+	configFile.WriteString("# A user-created config.yaml will have priority over the auto-generated one.\n")
+	configFile.WriteString("\n")
+	configFile.WriteString("---\n")
+
+	if err = writeStandards(&configFile, target, requestedUnitsDir, lock); err != nil {
+		return err
 	}
 
 	// Create the config file
-	configFile, err := os.Create(autoConfigYamlPath)
-	if err != nil {
+	if err = ioutil.WriteFile(autoConfigYamlPath, configFile.Bytes(), 0644); err != nil {
 		return err
 	}
-	defer configFile.Sync()
-	defer configFile.Close()
+
+	return lock.write(blockRoot)
+}
+
+// writeStandards writes the Standards of a config generated from the file structure of the
+// block at target. UIDs come from lock, which records every unit and content file written
+func writeStandards(configFile *bytes.Buffer, target, requestedUnitsDir string, lock *uidLock) error {
+	blockRoot := target
+	if !strings.HasSuffix(blockRoot, "/") {
+		blockRoot += "/"
+	}
+
+	// Lessons are markdown, and a notebook previewed on its own is staged as a lesson too
+	isContentFile := func(name string) bool {
+		return strings.HasSuffix(name, ".md") || (isStagedSingleFile(target) && strings.HasSuffix(name, ".ipynb"))
+	}
 
 	// If no unitsDir was passed in, create a Units directory string
 	unitsDir := ""
@@ -117,7 +147,14 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 		unitsRootDirName = requestedUnitsDir
 	}
 
+	ignore, err := loadIgnoreList(blockRoot)
+	if err != nil {
+		return err
+	}
+
 	unitToContentFileMap := map[string][]string{}
+	// unitDirs is where each unit's _unit.yaml would be found
+	unitDirs := map[string]string{}
 
 	// Check to see if units directory exists
 	_, err = os.Stat(unitsDir)
@@ -126,6 +163,7 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 
 	if err == nil {
 		whereToLookForUnits = unitsDir
+		unitDirs[unitsDirName] = unitsDir
 
 		allItems, err := ioutil.ReadDir(whereToLookForUnits)
 		if err != nil {
@@ -133,8 +171,9 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 		}
 
 		for _, info := range allItems {
-			if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".md") {
-				unitToContentFileMap[unitsDirName] = append(unitToContentFileMap[unitsDirName], unitsRootDirName+"/"+info.Name())
+			localPath := unitsRootDirName + "/" + info.Name()
+			if info.Mode().IsRegular() && isContentFile(info.Name()) && !ignore.ignored(localPath, false) {
+				unitToContentFileMap[unitsDirName] = append(unitToContentFileMap[unitsDirName], localPath)
 			}
 		}
 	}
@@ -163,17 +202,25 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 				} else {
 					nestedFolder = whereToLookForUnits + "/" + dirName
 				}
+				unitDirs[dirName] = nestedFolder
 
 				err = filepath.Walk(nestedFolder, func(path string, info os.FileInfo, err error) error {
 					if err != nil {
 						return err
 					}
 
-					if len(blockRoot) > 0 && len(path) > len(blockRoot) && strings.HasSuffix(path, ".md") {
-						localPath := path
-						if blockRoot != "./" {
-							localPath = path[len(blockRoot):]
+					localPath := path
+					if len(path) > len(blockRoot) && blockRoot != "./" {
+						localPath = path[len(blockRoot):]
+					}
+					if ignore.ignored(localPath, info.IsDir()) {
+						if info.IsDir() {
+							return filepath.SkipDir
 						}
+						return nil
+					}
+
+					if len(blockRoot) > 0 && len(path) > len(blockRoot) && isContentFile(path) {
 						unitToContentFileMap[dirName] = append(unitToContentFileMap[dirName], localPath)
 					}
 
@@ -186,71 +233,110 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 		}
 	}
 
-	configFile.WriteString("# This file is auto-generated and orders your content based on the file structure of your repo.\n")
-	configFile.WriteString("# Do not edit this file; it will be replaced the next time you run the preview command.\n")
-	configFile.WriteString("\n")
-	configFile.WriteString("# To manually order the contents of this curriculum rather than using the auto-generated file,\n")
-	configFile.WriteString("# include a config.yaml in your repo following the same conventions as this auto-generated file.\n")
-	configFile.WriteString("# A user-created config.yaml will have priority over the auto-generated one.\n")
-	configFile.WriteString("\n")
-	configFile.WriteString("---\n")
 	configFile.WriteString("Standards:\n")
 
 	if len(unitToContentFileMap) == 0 {
 		return fmt.Errorf("No content found at '%s'. Preview of an individual unit is not supported, make sure '%s' is the root of a repo or a single lesson.", target, target)
 	}
 
-	// sort unit keys in lexigraphical order
+	// sort unit keys and their content files in natural order, so 10-loops follows 9-intro
 	unitKeys := make([]string, 0, len(unitToContentFileMap))
 	for unit := range unitToContentFileMap {
 		unitKeys = append(unitKeys, unit)
+		paths := unitToContentFileMap[unit]
+		sort.SliceStable(paths, func(i, j int) bool { return naturalLess(paths[i], paths[j]) })
 	}
-	sort.Strings(unitKeys)
+	sort.SliceStable(unitKeys, func(i, j int) bool { return naturalLess(unitKeys[i], unitKeys[j]) })
+
+	// Reuse the UIDs in autoconfig.lock, following renames, so progress on Learn survives moves
+	unitPaths := []string{}
+	contentFilePaths := []string{}
+	for _, unit := range unitKeys {
+		unitPaths = append(unitPaths, unitConfigPath(blockRoot, unitDirs[unit]))
+		for _, path := range unitToContentFileMap[unit] {
+			if path != "README.md" {
+				contentFilePaths = append(contentFilePaths, contentFileConfigPath(path))
+			}
+		}
+	}
+	lock.carryRenames(blockRoot, unitPaths, contentFilePaths)
 
 	formattedTargetName := formattedName(target)
 	for _, unit := range unitKeys {
-		parts := strings.Split(unit, "/")
-		if strings.HasPrefix(parts[0], "__") {
-			continue
+		meta, err := readUnitMeta(unitDirs[unit])
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			meta = &unitMeta{}
 		}
+
 		configFile.WriteString("  -\n")
 
 		formattedUnitName := formattedName(unit)
-		if formattedUnitName != "" {
-			configFile.WriteString("    Title: " + formattedUnitName + "\n")
-		} else {
-			configFile.WriteString("    Title: " + formattedTargetName + "\n")
+		title := formattedUnitName
+		if title == "" {
+			title = formattedTargetName
 		}
-
-		var unitUID = []byte(formattedUnitName)
-		var md5unitUID = md5.Sum(unitUID)
-
-		if formattedUnitName != "" {
-			configFile.WriteString("    Description: " + formattedUnitName + "\n")
-		} else {
-			configFile.WriteString("    Description: " + formattedTargetName + "\n")
+		description := title
+		if meta.Title != "" {
+			title = meta.Title
+		}
+		if meta.Description != "" {
+			description = meta.Description
+		}
+		successCriteria := meta.SuccessCriteria
+		if len(successCriteria) == 0 {
+			successCriteria = []string{"success criteria"}
 		}
 
-		configFile.WriteString("    UID: " + hex.EncodeToString(md5unitUID[:]) + "\n")
+		var md5unitUID = md5.Sum([]byte(formattedUnitName))
+
+		configFile.WriteString("    Title: " + yamlScalar(title) + "\n")
+		configFile.WriteString("    Description: " + yamlScalar(description) + "\n")
+		unitUID := lock.unitUID(unitConfigPath(blockRoot, unitDirs[unit]), hex.EncodeToString(md5unitUID[:]))
+		configFile.WriteString("    UID: " + unitUID + "\n")
 		configFile.WriteString("    SuccessCriteria:\n")
-		configFile.WriteString("      - success criteria\n")
+		for _, criteria := range successCriteria {
+			configFile.WriteString("      - " + yamlScalar(criteria) + "\n")
+		}
 		configFile.WriteString("    ContentFiles:\n")
 
 		for _, path := range unitToContentFileMap[unit] {
-			parts := strings.Split(path, "/")
-			if strings.HasPrefix(parts[len(parts)-1], "__") {
-				continue
-			}
 			if path != "README.md" {
+				fm, err := readFrontMatter(filepath.Join(blockRoot, path))
+				if err != nil {
+					return err
+				}
+				if fm == nil {
+					fm = &frontMatter{}
+				}
+
 				configFile.WriteString("      -\n")
 
-				contentFileType := detectContentType(path)
+				contentFileType := fm.Type
+				if contentFileType == "" {
+					contentFileType = detectContentType(path)
+				}
 				configFile.WriteString("        Type: " + contentFileType + "\n")
 
-				if strings.Contains(strings.ToLower(path), "hidden") {
+				if fm.Title != "" {
+					configFile.WriteString("        Title: " + yamlScalar(fm.Title) + "\n")
+				}
+
+				if fm.Visibility == "hidden" || (fm.Visibility == "" && strings.Contains(strings.ToLower(path), "hidden")) {
 					configFile.WriteString("        DefaultVisibility: hidden\n")
 				}
 
+				if fm.Autoscore {
+					configFile.WriteString("        Autoscore: true\n")
+				}
+
+				if fm.TimeLimit > 0 {
+					configFile.WriteString(fmt.Sprintf("        TimeLimit: %d\n", fm.TimeLimit))
+				}
+
+				configPath := contentFileConfigPath(path)
 				if strings.Contains(strings.ToLower(path), "..") {
 					path = strings.Replace(path, "..", ".", 1)
 				}
@@ -258,22 +344,43 @@ func createAutoConfig(target, requestedUnitsDir string) error {
 				var cfUID = []byte(formattedUnitName + path)
 				var md5cfUID = md5.Sum(cfUID)
 
-				configFile.WriteString("        UID: " + hex.EncodeToString(md5cfUID[:]) + "\n")
-
-				if strings.HasPrefix(path, "./") {
-					configFile.WriteString("        Path: " + path[1:] + "\n")
-				} else {
-					configFile.WriteString("        Path: /" + path + "\n")
-				}
+				uid := lock.contentFileUID(configPath, hex.EncodeToString(md5cfUID[:]))
+				configFile.WriteString("        UID: " + uid + "\n")
+				configFile.WriteString("        Path: " + configPath + "\n")
 			}
 		}
 	}
-	if err != nil {
-		return err
-	}
+
 	return nil
 }
 
+// unitConfigPath is the block relative path of a unit directory, written like a config Path
+func unitConfigPath(blockRoot, dir string) string {
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(dir, blockRoot), "./")
+}
+
+// contentFileConfigPath is the Path a content file is written to the config with
+func contentFileConfigPath(path string) string {
+	if strings.Contains(strings.ToLower(path), "..") {
+		path = strings.Replace(path, "..", ".", 1)
+	}
+	if strings.HasPrefix(path, "./") {
+		return path[1:]
+	}
+	return "/" + path
+}
+
+// yamlScalar quotes s when writing it bare would not read back as the same string
+func yamlScalar(s string) string {
+	parsed := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte("v: "+s), &parsed); err == nil && parsed["v"] == s && !strings.Contains(s, "\n") {
+		return s
+	}
+
+	b, _ := yaml.Marshal(s)
+	return strings.TrimSuffix(string(b), "\n")
+}
+
 func detectContentType(path string) string {
 	path = strings.ToLower(path)
 	if strings.Contains(path, "instructor") {
@@ -309,3 +416,162 @@ func formattedName(name string) string {
 	parts = a.Split(strings.TrimSpace(formattedName), -1)
 	return strings.TrimSpace(strings.Join(parts, ""))
 }
+
+// blockConfig is the shape of a block's config.yaml, config.yml or autoconfig.yaml
+type blockConfig struct {
+	Standards []standard `yaml:"Standards"`
+}
+
+// standard is a unit of a block
+type standard struct {
+	Title           string        `yaml:"Title"`
+	UID             string        `yaml:"UID"`
+	Description     string        `yaml:"Description"`
+	SuccessCriteria []string      `yaml:"SuccessCriteria"`
+	ContentFiles    []contentFile `yaml:"ContentFiles"`
+}
+
+// contentFile is a lesson, checkpoint, instructor or resource file within a unit
+type contentFile struct {
+	Type                     string `yaml:"Type"`
+	Title                    string `yaml:"Title,omitempty"`
+	UID                      string `yaml:"UID"`
+	Path                     string `yaml:"Path"`
+	DefaultVisibility        string `yaml:"DefaultVisibility,omitempty"`
+	Autoscore                bool   `yaml:"Autoscore,omitempty"`
+	MaxCheckpointSubmissions int    `yaml:"MaxCheckpointSubmissions,omitempty"`
+	TimeLimit                int    `yaml:"TimeLimit,omitempty"`
+}
+
+var contentFileTypes = map[string]struct{}{
+	"Lesson":     {},
+	"Checkpoint": {},
+	"Instructor": {},
+	"Resource":   {},
+}
+
+// findConfigPath returns the config Learn will use for the block at root, preferring a user
+// written config.yaml or config.yml over autoconfig.yaml. Returns an empty string if none exist
+func findConfigPath(root string) string {
+	for _, name := range []string{"config.yaml", "config.yml", "autoconfig.yaml"} {
+		path := filepath.Join(root, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// findBlockRoot walks up from dir to the block holding it, a directory with a config file or
+// a git repository, falling back to dir
+func findBlockRoot(dir string) string {
+	root, _ := lookupBlockRoot(dir)
+	return root
+}
+
+// lookupBlockRoot is findBlockRoot that also reports whether a block was found, the absolute
+// dir is returned with false when no directory above it has a config file or a git repository
+func lookupBlockRoot(dir string) (string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir, false
+	}
+
+	for current := abs; ; {
+		if findConfigPath(current) != "" {
+			return current, true
+		}
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, false
+		}
+		current = parent
+	}
+}
+
+// withinDir is true when path is dir or inside it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// readBlockConfig parses the config file at path
+func readBlockConfig(path string) (blockConfig, error) {
+	var config blockConfig
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err = yaml.Unmarshal(b, &config); err != nil {
+		return config, fmt.Errorf("Could not parse '%s': %s", path, err)
+	}
+
+	return config, nil
+}
+
+// validateBlockConfig checks the config of the block at root for the mistakes Learn would reject
+// when syncing: missing fields, duplicate UIDs, unknown content types and paths that do not exist.
+// Each problem found is returned as a message, an empty slice means the config looks valid
+func validateBlockConfig(root string) ([]string, error) {
+	configPath := findConfigPath(root)
+	if configPath == "" {
+		return nil, fmt.Errorf("No config.yaml, config.yml or autoconfig.yaml found in '%s'", root)
+	}
+
+	config, err := readBlockConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	if len(config.Standards) == 0 {
+		problems = append(problems, "config has no Standards")
+	}
+
+	uids := map[string]string{}
+	checkUID := func(uid, owner string) {
+		if uid == "" {
+			problems = append(problems, fmt.Sprintf("%s has no UID", owner))
+			return
+		}
+		if existing, ok := uids[uid]; ok {
+			problems = append(problems, fmt.Sprintf("%s has the same UID as %s: %s", owner, existing, uid))
+			return
+		}
+		uids[uid] = owner
+	}
+
+	for i, s := range config.Standards {
+		unit := fmt.Sprintf("Standard %d (%s)", i+1, s.Title)
+		if s.Title == "" {
+			problems = append(problems, fmt.Sprintf("Standard %d has no Title", i+1))
+		}
+		checkUID(s.UID, unit)
+		if len(s.ContentFiles) == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no ContentFiles", unit))
+		}
+
+		for _, cf := range s.ContentFiles {
+			if cf.Path == "" {
+				problems = append(problems, fmt.Sprintf("%s has a content file with no Path", unit))
+				continue
+			}
+			checkUID(cf.UID, cf.Path)
+			if _, ok := contentFileTypes[cf.Type]; !ok {
+				problems = append(problems, fmt.Sprintf("%s has an unknown Type '%s'", cf.Path, cf.Type))
+			}
+			if !strings.HasPrefix(cf.Path, "/") {
+				problems = append(problems, fmt.Sprintf("%s must be an absolute path starting with /", cf.Path))
+			}
+			if _, err := os.Stat(filepath.Join(root, cf.Path)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s does not exist", cf.Path))
+			}
+		}
+	}
+
+	return problems, nil
+}
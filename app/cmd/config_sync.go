@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config [command]",
+	Short: "Generate and update a block's config.yaml",
+	Long: `
+Work with the config.yaml that orders the units and lessons of a block.
+
+  learn config generate [dir]   write a config.yaml from the files in the block
+  learn config sync [dir]       add new markdown files to an existing config.yaml
+	`,
+}
+
+var configGenerateCmd = &cobra.Command{
+	Use:   "generate [dir]",
+	Short: "Write an editable config.yaml from the files in a block",
+	Long: `
+Writes a config.yaml for the block in dir (defaults to the current directory)
+the same way preview and publish generate autoconfig.yaml: units and lessons in
+natural order, with front matter, _unit.yaml and .learnignore applied and the
+UIDs from autoconfig.lock kept. Unlike autoconfig.yaml it is yours to edit, and
+takes priority over autoconfig.yaml from then on.
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := configDirArg(args)
+		configPath := filepath.Join(dir, "config.yaml")
+
+		if existing := findConfigPath(dir); existing != "" && filepath.Base(existing) != "autoconfig.yaml" && !ConfigForce {
+			fmt.Printf("%s already exists, use `learn config sync` to add new files to it or --force to replace it\n", existing)
+			os.Exit(1)
+		}
+
+		config, err := generateBlockConfig(dir, UnitsDirectory)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err = writeBlockConfig(configPath, config); err != nil {
+			fmt.Printf("Could not write %s: %s\n", configPath, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Wrote %s with %d units\n", configPath, len(config.Standards))
+		if _, err := os.Stat(filepath.Join(dir, "autoconfig.yaml")); err == nil {
+			fmt.Println("config.yaml now takes priority, autoconfig.yaml and autoconfig.lock can be deleted.")
+		}
+	},
+}
+
+var configSyncCmd = &cobra.Command{
+	Use:   "sync [dir]",
+	Short: "Add new markdown files to an existing config.yaml",
+	Long: `
+Compares the config.yaml of the block in dir (defaults to the current directory)
+with the markdown files in it. New files are added to the unit holding other
+files from the same directory, or to a new unit at the end when there is none.
+Files listed in the config that no longer exist are reported but left in place.
+Everything else in config.yaml, including ordering and comments, is untouched.
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := configDirArg(args)
+
+		configPath := findConfigPath(dir)
+		if configPath == "" || filepath.Base(configPath) == "autoconfig.yaml" {
+			fmt.Printf("No config.yaml found in %s, create one with `learn config generate`\n", dir)
+			os.Exit(1)
+		}
+
+		source, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			fmt.Printf("Could not read %s: %s\n", configPath, err)
+			os.Exit(1)
+		}
+
+		generated, err := generateBlockConfig(dir, UnitsDirectory)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		result, err := syncBlockConfig(source, generated, func(p string) bool {
+			_, err := os.Stat(filepath.Join(dir, p))
+			return err == nil
+		})
+		if err != nil {
+			fmt.Printf("Could not sync %s: %s\n", configPath, err)
+			os.Exit(1)
+		}
+
+		if len(result.added) > 0 {
+			if err = ioutil.WriteFile(configPath, result.config, 0644); err != nil {
+				fmt.Printf("Could not write %s: %s\n", configPath, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Added %d content files to %s:\n", len(result.added), configPath)
+			for _, added := range result.added {
+				fmt.Println("  +", added)
+			}
+		} else {
+			fmt.Printf("%s lists every markdown file in %s\n", configPath, dir)
+		}
+
+		if len(result.missing) > 0 {
+			fmt.Printf("\n%s lists %d files that do not exist, update or remove them:\n", configPath, len(result.missing))
+			for _, missing := range result.missing {
+				fmt.Println("  -", missing)
+			}
+			os.Exit(1)
+		}
+	},
+}
+
+// configDirArg returns the block directory named in args, or the current directory
+func configDirArg(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+	return "."
+}
+
+// generateBlockConfig builds the config autoconfig would generate for the block at dir,
+// without writing autoconfig.yaml or autoconfig.lock
+func generateBlockConfig(dir, unitsDir string) (blockConfig, error) {
+	var config blockConfig
+
+	lock, err := loadUIDLock(dir)
+	if err != nil {
+		return config, err
+	}
+
+	var b bytes.Buffer
+	if err = writeStandards(&b, dir, unitsDir, lock); err != nil {
+		return config, err
+	}
+	if err = yaml.Unmarshal(b.Bytes(), &config); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// writeBlockConfig writes config to path as a config.yaml an author can edit
+func writeBlockConfig(path string, config blockConfig) error {
+	var b bytes.Buffer
+	b.WriteString("# Generated by `learn config generate` from the files in this block, edit it freely.\n")
+	b.WriteString("# Run `learn md cfy` for the supported fields and `learn config sync` to add new files.\n")
+	b.WriteString("\n---\n")
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(config); err != nil {
+		return err
+	}
+	enc.Close()
+
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
+
+// configSyncResult is the outcome of syncing a config.yaml with the files of its block
+type configSyncResult struct {
+	config  []byte
+	added   []string
+	missing []string
+}
+
+// configInsert is text to add to a config.yaml after a line
+type configInsert struct {
+	afterLine int
+	text      string
+}
+
+// syncBlockConfig adds the content files of generated that are not in the config.yaml source
+// and reports the ones in source that exists says are gone. New text is inserted after
+// existing lines so the rest of the file, including comments, stays byte for byte the same
+func syncBlockConfig(source []byte, generated blockConfig, exists func(path string) bool) (configSyncResult, error) {
+	result := configSyncResult{config: source}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return result, err
+	}
+	standards := mappingValue(documentRoot(&doc), "Standards")
+	if standards == nil || standards.Kind != yaml.SequenceNode || standards.Style&yaml.FlowStyle != 0 || len(standards.Content) == 0 {
+		return result, errors.New("the config has no Standards to add to")
+	}
+
+	// Index the existing config by path and UID, remembering the unit each directory belongs to
+	listed := map[string]struct{}{}
+	uids := map[string]struct{}{}
+	unitsByDir := map[string]*yaml.Node{}
+	for _, unit := range standards.Content {
+		if uid := mappingValue(unit, "UID"); uid != nil {
+			uids[uid.Value] = struct{}{}
+		}
+		contentFiles := mappingValue(unit, "ContentFiles")
+		if contentFiles == nil || contentFiles.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, cf := range contentFiles.Content {
+			if uid := mappingValue(cf, "UID"); uid != nil {
+				uids[uid.Value] = struct{}{}
+			}
+			p := mappingValue(cf, "Path")
+			if p == nil {
+				continue
+			}
+			listed[p.Value] = struct{}{}
+			if !exists(p.Value) {
+				result.missing = append(result.missing, p.Value)
+			}
+			if contentFiles.Style&yaml.FlowStyle == 0 {
+				if _, ok := unitsByDir[path.Dir(p.Value)]; !ok {
+					unitsByDir[path.Dir(p.Value)] = contentFiles
+				}
+			}
+		}
+	}
+
+	lines := strings.SplitAfter(string(source), "\n")
+	inserts := map[int]*configInsert{}
+	insertAfter := func(line int, text string) {
+		if inserts[line] == nil {
+			inserts[line] = &configInsert{afterLine: line}
+		}
+		inserts[line].text += text
+	}
+
+	unitIndent := strings.Repeat(" ", dashColumn(lines, standards.Content[0]))
+	for _, unit := range generated.Standards {
+		newUnit := unit
+		newUnit.ContentFiles = nil
+
+		for _, cf := range unit.ContentFiles {
+			if _, ok := listed[cf.Path]; ok {
+				continue
+			}
+			cf.UID = uniqueUID(cf.UID, cf.Path, uids)
+			result.added = append(result.added, cf.Path)
+
+			contentFiles, ok := unitsByDir[path.Dir(cf.Path)]
+			if !ok {
+				newUnit.ContentFiles = append(newUnit.ContentFiles, cf)
+				continue
+			}
+
+			text, err := indentedYAML([]contentFile{cf}, strings.Repeat(" ", dashColumn(lines, contentFiles.Content[0])))
+			if err != nil {
+				return result, err
+			}
+			insertAfter(nodeEndLine(contentFiles), text)
+		}
+
+		if len(newUnit.ContentFiles) > 0 {
+			newUnit.UID = uniqueUID(newUnit.UID, newUnit.Title, uids)
+			text, err := indentedYAML([]standard{newUnit}, unitIndent)
+			if err != nil {
+				return result, err
+			}
+			insertAfter(nodeEndLine(standards), text)
+		}
+	}
+
+	if len(inserts) == 0 {
+		return result, nil
+	}
+
+	// Insert from the bottom up so earlier line numbers stay valid
+	ordered := make([]*configInsert, 0, len(inserts))
+	for _, insert := range inserts {
+		ordered = append(ordered, insert)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].afterLine > ordered[j].afterLine })
+	for _, insert := range ordered {
+		at := insert.afterLine
+		if at > len(lines) {
+			at = len(lines)
+		}
+		if at > 0 && !strings.HasSuffix(lines[at-1], "\n") {
+			lines[at-1] += "\n"
+		}
+		lines = append(lines[:at], append([]string{insert.text}, lines[at:]...)...)
+	}
+	result.config = []byte(strings.Join(lines, ""))
+
+	return result, nil
+}
+
+// documentRoot returns the top level node of a parsed YAML document
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value of key in a mapping node, nil when it isn't there
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// nodeEndLine returns the last line a node's content is on
+func nodeEndLine(n *yaml.Node) int {
+	end := n.Line
+	if n.Kind == yaml.ScalarNode && (n.Style&yaml.LiteralStyle != 0 || n.Style&yaml.FoldedStyle != 0) {
+		end += strings.Count(strings.TrimRight(n.Value, "\n"), "\n") + 1
+	}
+	for _, child := range n.Content {
+		if childEnd := nodeEndLine(child); childEnd > end {
+			end = childEnd
+		}
+	}
+	return end
+}
+
+// dashColumn returns the indentation of the "-" starting a sequence item
+func dashColumn(lines []string, item *yaml.Node) int {
+	line := lines[item.Line-1]
+	if dash := strings.LastIndex(line[:item.Column-1], "-"); dash != -1 {
+		return dash
+	}
+
+	// the item starts on the line after its "-"
+	if item.Line >= 2 {
+		previous := lines[item.Line-2]
+		return len(previous) - len(strings.TrimLeft(previous, " "))
+	}
+	return 0
+}
+
+// indentedYAML marshals a sequence and indents every line of it
+func indentedYAML(v interface{}, indent string) (string, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	enc.Close()
+
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(b.String(), "\n") {
+		if line != "" {
+			out.WriteString(indent + line)
+		}
+	}
+	return out.String(), nil
+}
+
+// uniqueUID returns uid, or one derived from it and seed when uid is already taken, and
+// records it as taken
+func uniqueUID(uid, seed string, taken map[string]struct{}) string {
+	for {
+		if _, ok := taken[uid]; !ok && uid != "" {
+			taken[uid] = struct{}{}
+			return uid
+		}
+		sum := md5.Sum([]byte(uid + seed))
+		uid = hex.EncodeToString(sum[:])
+	}
+}
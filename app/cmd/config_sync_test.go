@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const handWrittenConfig = `# Our block, ordered by hand
+---
+Standards:
+  - Title: Loops # comes first on purpose
+    UID: loops
+    Description: >
+      Repeating
+      things
+    SuccessCriteria:
+      - loop
+    ContentFiles:
+      - Type: Lesson
+        UID: while
+        Path: /units/loops/while.md
+      # for loops come after while loops
+      - Type: Lesson
+        UID: gone
+        Path: /units/loops/gone.md
+  - Title: Intro
+    UID: intro
+    Description: Start here
+    SuccessCriteria:
+      - read
+    ContentFiles:
+      -
+        Type: Lesson
+        UID: welcome
+        Path: /units/intro/welcome.md
+`
+
+const syncedConfig = `# Our block, ordered by hand
+---
+Standards:
+  - Title: Loops # comes first on purpose
+    UID: loops
+    Description: >
+      Repeating
+      things
+    SuccessCriteria:
+      - loop
+    ContentFiles:
+      - Type: Lesson
+        UID: while
+        Path: /units/loops/while.md
+      # for loops come after while loops
+      - Type: Lesson
+        UID: gone
+        Path: /units/loops/gone.md
+      - Type: Lesson
+        UID: for-uid
+        Path: /units/loops/for.md
+  - Title: Intro
+    UID: intro
+    Description: Start here
+    SuccessCriteria:
+      - read
+    ContentFiles:
+      -
+        Type: Lesson
+        UID: welcome
+        Path: /units/intro/welcome.md
+      - Type: Checkpoint
+        UID: 59a3b3ba9e3c0b0dc90e0e1d7c1e0d3e
+        Path: /units/intro/quiz.md
+  - Title: Functions
+    UID: functions-uid
+    Description: Functions
+    SuccessCriteria:
+      - success criteria
+    ContentFiles:
+      - Type: Lesson
+        UID: define-uid
+        Path: /units/functions/define.md
+`
+
+func Test_syncBlockConfig(t *testing.T) {
+	generated := blockConfig{Standards: []standard{
+		{Title: "Intro", UID: "intro-uid", Description: "Intro", SuccessCriteria: []string{"success criteria"}, ContentFiles: []contentFile{
+			{Type: "Lesson", UID: "welcome-uid", Path: "/units/intro/welcome.md"},
+			// the generated UID is already taken by the hand written config
+			{Type: "Checkpoint", UID: "welcome", Path: "/units/intro/quiz.md"},
+		}},
+		{Title: "Loops", UID: "loops-uid", Description: "Loops", SuccessCriteria: []string{"success criteria"}, ContentFiles: []contentFile{
+			{Type: "Lesson", UID: "for-uid", Path: "/units/loops/for.md"},
+			{Type: "Lesson", UID: "while-uid", Path: "/units/loops/while.md"},
+		}},
+		{Title: "Functions", UID: "functions-uid", Description: "Functions", SuccessCriteria: []string{"success criteria"}, ContentFiles: []contentFile{
+			{Type: "Lesson", UID: "define-uid", Path: "/units/functions/define.md"},
+		}},
+	}}
+
+	result, err := syncBlockConfig([]byte(handWrittenConfig), generated, func(p string) bool {
+		return p != "/units/loops/gone.md"
+	})
+	if err != nil {
+		t.Fatalf("syncBlockConfig errored: %s", err)
+	}
+
+	expectedQuizUID := uniqueUID("welcome", "/units/intro/quiz.md", map[string]struct{}{"welcome": {}})
+	expected := strings.Replace(syncedConfig, "59a3b3ba9e3c0b0dc90e0e1d7c1e0d3e", expectedQuizUID, 1)
+	if string(result.config) != expected {
+		t.Errorf("synced config expected:\n%s\nbut got:\n%s", expected, result.config)
+	}
+
+	if strings.Join(result.added, ",") != "/units/intro/quiz.md,/units/loops/for.md,/units/functions/define.md" {
+		t.Errorf("unexpected added files %v", result.added)
+	}
+	if strings.Join(result.missing, ",") != "/units/loops/gone.md" {
+		t.Errorf("unexpected missing files %v", result.missing)
+	}
+
+	again, err := syncBlockConfig(result.config, generated, func(string) bool { return true })
+	if err != nil || len(again.added) != 0 || string(again.config) != string(result.config) {
+		t.Errorf("syncing a synced config should change nothing, added %v, err %v", again.added, err)
+	}
+}
+
+func Test_generateBlockConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "units", "1-intro"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "units", "1-intro", "welcome.md"), []byte("# Welcome\n"), 0644)
+
+	config, err := generateBlockConfig(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	if err = writeBlockConfig(configPath, config); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := ioutil.ReadFile(configPath)
+	if !strings.Contains(string(b), "Standards:\n  - Title: Intro\n") || !strings.Contains(string(b), "    ContentFiles:\n      - Type: Lesson\n") {
+		t.Errorf("config.yaml should be formatted like the config.yaml template, got:\n%s", b)
+	}
+	if _, err = os.Stat(filepath.Join(dir, uidLockFile)); !os.IsNotExist(err) {
+		t.Errorf("generating a config.yaml should not write %s", uidLockFile)
+	}
+
+	problems, err := validateBlockConfig(dir)
+	if err != nil || len(problems) > 0 {
+		t.Errorf("the generated config.yaml should be valid, got %v %v", problems, err)
+	}
+}
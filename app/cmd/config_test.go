@@ -127,3 +127,27 @@ func Test_IgnoresFilesAndUnitsThatStartWithTwoUnderscores(t *testing.T) {
 		t.Errorf("Autoconfig have contentfiles that start with __")
 	}
 }
+
+const invalidConfigFixture = "../../fixtures/test-block-invalid-config"
+
+func Test_ValidateBlockConfig(t *testing.T) {
+	problems, err := validateBlockConfig(invalidConfigFixture)
+	if err != nil {
+		t.Errorf("validateBlockConfig errored: %s", err)
+	}
+
+	expected := []string{
+		"/units/missing.md has the same UID as /units/lesson.md: lesson-one",
+		"/units/missing.md does not exist",
+		"units/lesson.md has an unknown Type 'Quiz'",
+		"units/lesson.md must be an absolute path starting with /",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("validateBlockConfig expected problems:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(problems, "\n"))
+	}
+
+	_, err = validateBlockConfig(invalidConfigFixture + "/units")
+	if err == nil {
+		t.Errorf("validateBlockConfig should error when there is no config")
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+	"github.com/gSchool/glearn-cli/gitutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultCourseFile is read by the course commands when no path is given
+const defaultCourseFile = "course.yaml"
+
+var courseCmd = &cobra.Command{
+	Use:   "course [command]",
+	Short: "Validate and publish the blocks of a course.yaml",
+	Long: `
+Work with course.yaml files, which group and order the block repos of a course.
+Run 'learn md cry' for the course.yaml syntax.
+
+  learn course validate [course.yaml]   check every repo maps to a block on Learn
+  learn course publish [course.yaml]    release every listed repo in order
+	`,
+}
+
+var courseValidateCmd = &cobra.Command{
+	Use:   "validate [course.yaml]",
+	Short: "Check a course.yaml against the blocks on Learn",
+	Long: `
+Parses a course.yaml (defaults to ./course.yaml), checks that every repo URL maps
+to an existing block on Learn and flags repos listed more than once.
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		course, path := loadCourseOrExit(args)
+
+		setupLearnAPI()
+
+		repos, problems := validateCourse(course, learn.API.GetBlockByRepoName)
+		printCourseRepos(os.Stdout, repos)
+
+		if len(problems) > 0 {
+			fmt.Printf("\n%s has %d problem(s):\n", path, len(problems))
+			for _, problem := range problems {
+				fmt.Println("  -", problem)
+			}
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n%s is valid: %d repos in %d sections\n", path, len(repos), len(course.Course))
+	},
+}
+
+var coursePublishCmd = &cobra.Command{
+	Use:   "publish [course.yaml]",
+	Short: "Release every repo listed in a course.yaml",
+	Long: `
+Validates a course.yaml (defaults to ./course.yaml) and then releases the HEAD
+of the default branch of every listed repo on Learn, in order. Nothing is pushed;
+each repo is released as it is on GitHub. Results are reported per repo.
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		course, path := loadCourseOrExit(args)
+
+		setupLearnAPI()
+
+		repos, problems := validateCourse(course, learn.API.GetBlockByRepoName)
+		if len(problems) > 0 {
+			printCourseRepos(os.Stdout, repos)
+			fmt.Printf("\nCannot publish, %s has %d problem(s):\n", path, len(problems))
+			for _, problem := range problems {
+				fmt.Println("  -", problem)
+			}
+			os.Exit(1)
+		}
+
+		if !CourseYes && !confirm(fmt.Sprintf("Release all %d repos in %s?", len(repos), path)) {
+			fmt.Println("Publish cancelled.")
+			return
+		}
+
+		failed := 0
+		for i := range repos {
+			fmt.Printf("[%d/%d] Releasing %s...\n", i+1, len(repos), repos[i].name)
+			releaseCourseRepo(&repos[i])
+			if repos[i].err != nil {
+				failed++
+			}
+		}
+
+		fmt.Println()
+		printCourseRepos(os.Stdout, repos)
+
+		if failed > 0 {
+			fmt.Printf("\n%d of %d repos failed to release\n", failed, len(repos))
+			os.Exit(1)
+		}
+	},
+}
+
+// courseConfig is the shape of a course.yaml
+type courseConfig struct {
+	DefaultUnitVisibility string          `yaml:"DefaultUnitVisibility"`
+	Course                []courseSection `yaml:"Course"`
+}
+
+// courseSection groups repos together on the curriculum homepage
+type courseSection struct {
+	Section string       `yaml:"Section"`
+	Repos   []courseRepo `yaml:"Repos"`
+}
+
+// courseRepo is a block repo listed in a course.yaml
+type courseRepo struct {
+	URL string `yaml:"URL"`
+}
+
+// courseRepoResult tracks a listed repo through validation and publishing
+type courseRepoResult struct {
+	section   string
+	url       string
+	name      string
+	block     learn.Block
+	status    string
+	releaseID int
+	warnings  []string
+	err       error
+}
+
+// loadCourseOrExit reads the course.yaml named in args, or ./course.yaml
+func loadCourseOrExit(args []string) (courseConfig, string) {
+	if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
+		fmt.Print(setAPITokenMessage)
+		os.Exit(1)
+	}
+
+	path := defaultCourseFile
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	course, err := readCourseConfig(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return course, path
+}
+
+// readCourseConfig parses the course.yaml at path
+func readCourseConfig(path string) (courseConfig, error) {
+	var course courseConfig
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return course, fmt.Errorf("Could not read '%s': %s", path, err)
+	}
+	if err = yaml.Unmarshal(b, &course); err != nil {
+		return course, fmt.Errorf("Could not parse '%s': %s", path, err)
+	}
+
+	return course, nil
+}
+
+// validateCourse checks the structure of course, that every repo URL can be parsed and is
+// listed once, and that lookup finds a block for it. Every listed repo is returned in order
+// alongside the problems found
+func validateCourse(course courseConfig, lookup func(string) (learn.Block, error)) ([]courseRepoResult, []string) {
+	repos := []courseRepoResult{}
+	problems := []string{}
+
+	if len(course.Course) == 0 {
+		problems = append(problems, "no sections found under Course")
+	}
+	if course.DefaultUnitVisibility != "" && course.DefaultUnitVisibility != "hidden" {
+		problems = append(problems, fmt.Sprintf("DefaultUnitVisibility can only be 'hidden', was '%s'", course.DefaultUnitVisibility))
+	}
+
+	seen := map[string]string{}
+	for i, section := range course.Course {
+		sectionName := section.Section
+		if sectionName == "" {
+			sectionName = fmt.Sprintf("Section %d", i+1)
+			problems = append(problems, fmt.Sprintf("section %d has no name", i+1))
+		}
+		if len(section.Repos) == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no Repos", sectionName))
+		}
+
+		for _, repo := range section.Repos {
+			result := courseRepoResult{section: sectionName, url: repo.URL, status: "ok"}
+
+			remote, err := gitutil.ParseRemoteURL(repo.URL)
+			if err != nil {
+				result.status = "invalid url"
+				result.err = err
+				problems = append(problems, fmt.Sprintf("%s: '%s' is not a repo URL", sectionName, repo.URL))
+				repos = append(repos, result)
+				continue
+			}
+			result.name = remote.Name
+
+			if firstSection, ok := seen[strings.ToLower(remote.Name)]; ok {
+				result.status = "duplicate"
+				problems = append(problems, fmt.Sprintf("%s: %s is already listed in %s", sectionName, remote.Name, firstSection))
+				repos = append(repos, result)
+				continue
+			}
+			seen[strings.ToLower(remote.Name)] = sectionName
+
+			block, err := lookup(remote.Name)
+			if err != nil {
+				result.status = "lookup failed"
+				result.err = err
+				problems = append(problems, fmt.Sprintf("%s: could not fetch the block for %s: %s", sectionName, remote.Name, err))
+			} else if !block.Exists() {
+				result.status = "no block"
+				problems = append(problems, fmt.Sprintf("%s: %s has not been published to Learn, run `learn publish` in it first", sectionName, remote.Name))
+			}
+			result.block = block
+
+			repos = append(repos, result)
+		}
+	}
+
+	return repos, problems
+}
+
+// releaseCourseRepo releases the HEAD of the default branch of a course repo and waits for
+// Learn to build it, recording the outcome on result
+func releaseCourseRepo(result *courseRepoResult) {
+	releaseID, err := learn.API.CreateMasterRelease(result.block.ID)
+	if err != nil || releaseID == 0 {
+		result.status = "failed"
+		result.err = fmt.Errorf("could not create a release: %v", err)
+		return
+	}
+	result.releaseID = releaseID
+
+	var attempts uint8 = 30
+	p, err := learn.API.PollForBuildResponse(releaseID, &attempts)
+	if err != nil {
+		result.status = "failed"
+		result.err = err
+		if block, blockErr := learn.API.GetBlockByRepoName(result.name); blockErr == nil && len(block.SyncErrors) > 0 {
+			result.err = fmt.Errorf("%s", strings.Join(block.SyncErrors, "; "))
+		}
+		return
+	}
+
+	result.status = "released"
+	result.warnings = p.SyncWarnings
+}
+
+// printCourseRepos writes the repos of a course as a table followed by any warnings or errors
+func printCourseRepos(out io.Writer, repos []courseRepoResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SECTION\tREPO\tBLOCK\tSTATUS\tWARNINGS")
+	for _, r := range repos {
+		name := r.name
+		if name == "" {
+			name = r.url
+		}
+		blockID := "-"
+		if r.block.Exists() {
+			blockID = fmt.Sprintf("%d", r.block.ID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", r.section, name, blockID, r.status, len(r.warnings))
+	}
+	w.Flush()
+
+	for _, r := range repos {
+		if r.status == "failed" && r.err != nil {
+			fmt.Fprintf(out, "\n%s failed: %s\n", r.name, r.err)
+		}
+		if len(r.warnings) > 0 {
+			fmt.Fprintf(out, "\nWarnings on %s:\n", r.name)
+			for _, warning := range r.warnings {
+				fmt.Fprintln(out, "  -", warning)
+			}
+		}
+	}
+}
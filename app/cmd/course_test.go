@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+)
+
+const courseFixture = "../../fixtures/test-course/course.yaml"
+
+func Test_ValidateCourse(t *testing.T) {
+	course, err := readCourseConfig(courseFixture)
+	if err != nil {
+		t.Fatalf("readCourseConfig errored: %s", err)
+	}
+
+	published := map[string]learn.Block{
+		"fundamentals-1": {ID: 1, RepoName: "fundamentals-1"},
+		"fundamentals-2": {ID: 2, RepoName: "fundamentals-2"},
+	}
+	lookups := []string{}
+	lookup := func(name string) (learn.Block, error) {
+		lookups = append(lookups, name)
+		return published[name], nil
+	}
+
+	repos, problems := validateCourse(course, lookup)
+
+	if len(repos) != 5 {
+		t.Fatalf("every listed repo should be returned, got %d", len(repos))
+	}
+	statuses := []string{}
+	for _, r := range repos {
+		statuses = append(statuses, r.status)
+	}
+	if strings.Join(statuses, ",") != "ok,ok,no block,duplicate,invalid url" {
+		t.Errorf("unexpected repo statuses %v", statuses)
+	}
+	if strings.Join(lookups, ",") != "fundamentals-1,fundamentals-2,unpublished-block" {
+		t.Errorf("each unique repo should be looked up once, got %v", lookups)
+	}
+
+	expected := []string{
+		"Projects: unpublished-block has not been published to Learn, run `learn publish` in it first",
+		"Projects: fundamentals-1 is already listed in Fundamentals",
+		"Projects: 'not a url' is not a repo URL",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("validateCourse expected problems:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(problems, "\n"))
+	}
+}
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// submissionMount is where the submission directory is mounted in the container
+const submissionMount = "/submission"
+
+var challengesRunDockerCmd = &cobra.Command{
+	Use:   "run-docker <file.md> --id <challenge-id>",
+	Short: "Build and run the Docker setup of a custom-snippet challenge locally",
+	Long: `
+Builds the image in the docker_directory_path of a custom-snippet challenge with
+the local Docker or Podman CLI and runs it against a submission, printing its
+output and exit status. Nothing is pushed to or pulled from a registry by the
+command, so any base image must already be available to the engine.
+
+The docker directory is checked before building. It needs a Dockerfile with a
+CMD or ENTRYPOINT, and every file its COPY and ADD instructions use.
+
+The submission is the challenge's !solution, or its !placeholder when there is
+no solution, or the file given with --submission. It is mounted read only in
+` + submissionMount + ` and its path is in the SUBMISSION_FILE environment variable.
+The container runs without a network.
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		run, err := prepareDockerRun(args[0], DockerChallengeID, DockerSubmission, DockerEngine)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer run.cleanup()
+
+		status, err := run.execute(os.Stdout, os.Stderr)
+		if err != nil {
+			fmt.Println(err)
+			run.cleanup()
+			os.Exit(1)
+		}
+		fmt.Printf("\n%s exited with status %d\n", run.challengeID, status)
+		if status != 0 {
+			run.cleanup()
+			os.Exit(1)
+		}
+	},
+}
+
+// dockerRun is everything needed to build and run a custom-snippet challenge
+type dockerRun struct {
+	challengeID string
+	engine      string
+	dir         string
+	image       string
+
+	// submissionDir is mounted in the container, submission is the name of the file in it
+	submissionDir string
+	submission    string
+	cleanup       func()
+}
+
+// dockerImageChars are the characters not allowed in an image name
+var dockerImageChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// findDockerEngine returns the CLI to use, engine when it is given, otherwise docker or podman
+func findDockerEngine(engine string) (string, error) {
+	if engine != "" {
+		path, err := exec.LookPath(engine)
+		if err != nil {
+			return "", fmt.Errorf("The container engine %s was not found", engine)
+		}
+		return path, nil
+	}
+	for _, name := range []string{"docker", "podman"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("Neither docker nor podman is installed, install one or pass its path with --engine")
+}
+
+// prepareDockerRun finds the challenge, checks its docker directory and writes the submission
+func prepareDockerRun(file, id, submissionFile, engine string) (*dockerRun, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	challenges, err := parseChallenges(file, string(b))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the challenges in %s, %s", file, err)
+	}
+
+	var c *challenge
+	for i := range challenges {
+		if challenges[i].ID == id {
+			c = &challenges[i]
+		}
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%s has no challenge with the id %s", file, id)
+	}
+	if c.Type != "custom-snippet" {
+		return nil, fmt.Errorf("Challenge %s is a %s challenge, only custom-snippet challenges run with Docker", id, c.Type)
+	}
+
+	blockRoot := findBlockRoot(filepath.Dir(file))
+	dir, err := dockerDirectory(blockRoot, c.attribute("docker_directory_path"))
+	if err != nil {
+		return nil, fmt.Errorf("Challenge %s: %s", id, err)
+	}
+	if err := validateDockerDirectory(dir); err != nil {
+		return nil, fmt.Errorf("Challenge %s: %s", id, err)
+	}
+
+	enginePath, err := findDockerEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &dockerRun{
+		challengeID: id,
+		engine:      enginePath,
+		dir:         dir,
+		image:       "learn-custom-snippet-" + strings.Trim(dockerImageChars.ReplaceAllString(strings.ToLower(id), "-"), "-.") + ":local",
+	}
+	if err := run.writeSubmission(*c, blockRoot, submissionFile); err != nil {
+		if run.cleanup != nil {
+			run.cleanup()
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// dockerDirectory resolves the docker_directory_path of a challenge, which must stay in the block
+func dockerDirectory(blockRoot, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("there is no docker_directory_path")
+	}
+
+	dir := filepath.Join(blockRoot, filepath.FromSlash(strings.TrimPrefix(path, "/")))
+	if !withinDir(blockRoot, dir) {
+		return "", fmt.Errorf("docker_directory_path %s is outside of the block", path)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("docker_directory_path %s does not exist", path)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("docker_directory_path %s is not a directory", path)
+	}
+	return dir, nil
+}
+
+// dockerInstruction is an instruction of a Dockerfile with the line it starts on
+type dockerInstruction struct {
+	line int
+	name string
+	args string
+}
+
+// parseDockerfile reads the instructions of a Dockerfile, joining continued lines
+func parseDockerfile(r io.Reader) ([]dockerInstruction, error) {
+	instructions := []dockerInstruction{}
+	scanner := bufio.NewScanner(r)
+	current, start, n := "", 0, 0
+
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || (line == "" && current == "") {
+			continue
+		}
+		if current == "" {
+			start = n
+		}
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		current += line
+
+		fields := strings.SplitN(current, " ", 2)
+		instruction := dockerInstruction{line: start, name: strings.ToUpper(fields[0])}
+		if len(fields) == 2 {
+			instruction.args = strings.TrimSpace(fields[1])
+		}
+		instructions = append(instructions, instruction)
+		current = ""
+	}
+
+	return instructions, scanner.Err()
+}
+
+// copySources are the local files a COPY or ADD instruction uses. Copies from other build
+// stages and URLs are not local and are left out
+func copySources(args string) []string {
+	var parts []string
+	if strings.HasPrefix(args, "[") {
+		if err := json.Unmarshal([]byte(args), &parts); err != nil {
+			return nil
+		}
+	} else {
+		parts = strings.Fields(args)
+	}
+
+	sources := []string{}
+	for _, p := range parts {
+		if strings.HasPrefix(p, "--from=") {
+			return nil
+		}
+		if !strings.HasPrefix(p, "--") {
+			sources = append(sources, p)
+		}
+	}
+	if len(sources) < 2 {
+		return nil
+	}
+
+	local := []string{}
+	for _, s := range sources[:len(sources)-1] {
+		if !strings.Contains(s, "://") {
+			local = append(local, s)
+		}
+	}
+	return local
+}
+
+// validateDockerDirectory checks dir has a Dockerfile that can be built from it
+func validateDockerDirectory(dir string) error {
+	f, err := os.Open(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		return fmt.Errorf("there is no Dockerfile in %s", dir)
+	}
+	defer f.Close()
+
+	instructions, err := parseDockerfile(f)
+	if err != nil {
+		return fmt.Errorf("the Dockerfile in %s could not be read, %s", dir, err)
+	}
+	if len(instructions) == 0 || (instructions[0].name != "FROM" && instructions[0].name != "ARG") {
+		return fmt.Errorf("the Dockerfile in %s must start with FROM", dir)
+	}
+
+	problems := []string{}
+	runnable := false
+	for _, instruction := range instructions {
+		switch instruction.name {
+		case "CMD", "ENTRYPOINT":
+			runnable = true
+		case "COPY", "ADD":
+			for _, source := range copySources(instruction.args) {
+				path := filepath.Join(dir, filepath.FromSlash(source))
+				if !withinDir(dir, path) {
+					problems = append(problems, fmt.Sprintf("line %d: %s %s is outside of the docker directory", instruction.line, instruction.name, source))
+					continue
+				}
+				if matches, _ := filepath.Glob(path); len(matches) == 0 {
+					problems = append(problems, fmt.Sprintf("line %d: %s %s does not exist", instruction.line, instruction.name, source))
+				}
+			}
+		}
+	}
+	if !runnable {
+		problems = append(problems, "there is no CMD or ENTRYPOINT to run the tests")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the Dockerfile in %s has problems:\n  %s", dir, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// submissionExtensions name the submission file after the challenge language
+var submissionExtensions = map[string]string{
+	"csharp":     ".cs",
+	"html":       ".html",
+	"java":       ".java",
+	"javascript": ".js",
+	"json":       ".json",
+	"markdown":   ".md",
+	"python":     ".py",
+	"sql":        ".sql",
+}
+
+// writeSubmission copies the submission to a temporary directory to mount in the container
+func (r *dockerRun) writeSubmission(c challenge, blockRoot, submissionFile string) error {
+	var content []byte
+	if submissionFile != "" {
+		b, err := ioutil.ReadFile(submissionFile)
+		if err != nil {
+			return err
+		}
+		content, r.submission = b, filepath.Base(submissionFile)
+	} else {
+		s := snippetFromChallenge(c, blockRoot)
+		if s.fromPlaceholder {
+			fmt.Printf("Challenge %s has no !solution, running its !placeholder\n", c.ID)
+		}
+		ext, ok := submissionExtensions[strings.TrimRight(s.language, "0123456789.")]
+		if !ok {
+			ext = ".txt"
+		}
+		content, r.submission = []byte(s.code+"\n"), "submission"+ext
+	}
+
+	dir, err := ioutil.TempDir("", "learn-submission")
+	if err != nil {
+		return err
+	}
+	r.submissionDir = dir
+	r.cleanup = func() { os.RemoveAll(dir) }
+
+	return ioutil.WriteFile(filepath.Join(dir, r.submission), content, 0644)
+}
+
+// buildArgs are the arguments to the engine that build the image
+func (r *dockerRun) buildArgs() []string {
+	return []string{"build", "--tag", r.image, r.dir}
+}
+
+// runArgs are the arguments to the engine that run the image against the submission
+func (r *dockerRun) runArgs() []string {
+	return []string{
+		"run", "--rm", "--network", "none",
+		"--volume", r.submissionDir + ":" + submissionMount + ":ro",
+		"--env", "SUBMISSION_FILE=" + submissionMount + "/" + r.submission,
+		r.image,
+	}
+}
+
+// execute builds and runs the image, returning the exit status of the container
+func (r *dockerRun) execute(stdout, stderr io.Writer) (int, error) {
+	fmt.Fprintf(stdout, "Building %s from %s\n", r.image, r.dir)
+	build := exec.Command(r.engine, r.buildArgs()...)
+	build.Stdout, build.Stderr = stdout, stderr
+	if err := build.Run(); err != nil {
+		return 0, fmt.Errorf("The image for %s could not be built, %s", r.challengeID, err)
+	}
+
+	fmt.Fprintf(stdout, "Running %s with %s\n", r.image, r.submission)
+	run := exec.Command(r.engine, r.runArgs()...)
+	run.Stdout, run.Stderr = stdout, stderr
+	if err := run.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, fmt.Errorf("The image for %s could not be run, %s", r.challengeID, err)
+	}
+	return 0, nil
+}
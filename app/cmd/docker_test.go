@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const dockerLesson = `# Docker
+
+### !challenge
+
+* type: custom-snippet
+* language: python3.6
+* id: Custom One
+* title: Custom
+* docker_directory_path: /docker/custom
+
+##### !question
+
+Return one
+
+##### !end-question
+
+##### !placeholder
+
+~~~py
+def one():
+  pass
+~~~
+
+##### !end-placeholder
+
+##### !solution
+
+~~~py
+def one():
+  return 1
+~~~
+
+##### !end-solution
+
+### !end-challenge
+
+### !challenge
+
+* type: custom-snippet
+* language: python3.6
+* id: escapes
+* title: Escapes
+* docker_directory_path: /../outside
+
+##### !question
+
+Anything
+
+##### !end-question
+
+### !end-challenge
+
+### !challenge
+
+* type: short-answer
+* id: short
+* title: Short
+
+##### !question
+
+What?
+
+##### !end-question
+
+### !end-challenge
+`
+
+// fakeEngine is a container CLI that records its arguments and prints the submission it is given
+const fakeEngine = `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/calls"
+if [ "$1" = "run" ]; then
+  mount=$(echo "$6" | cut -d: -f1)
+  cat "$mount"/*
+  exit 3
+fi
+`
+
+func writeDockerBlock(t *testing.T, dockerfile string) string {
+	dir, err := ioutil.TempDir("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"config.yaml":              "Standards: []\n",
+		"docker/custom/Dockerfile": dockerfile,
+		"docker/custom/test.sh":    "python3 -m unittest\n",
+		"01-unit/docker.md":        dockerLesson,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func Test_validateDockerDirectory(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		problem    string
+	}{
+		{"valid", "# syntax=docker/dockerfile:1\nFROM python:3.6\nCOPY test.sh \\\n  /app/\nCMD [\"sh\", \"/app/test.sh\"]\n", ""},
+		{"no from", "CMD sh\n", "must start with FROM"},
+		{"missing copy", "FROM python:3.6\nCOPY test.sh requirements.txt /app/\nCMD sh /app/test.sh\n", "line 2: COPY requirements.txt does not exist"},
+		{"escaping copy", "FROM python:3.6\nADD ../secrets /app/\nCMD sh\n", "ADD ../secrets is outside of the docker directory"},
+		{"no command", "FROM python:3.6\nCOPY [\"test.sh\", \"/app/\"]\n", "no CMD or ENTRYPOINT"},
+		{"other stages and urls", "FROM python:3.6 AS build\nFROM python:3.6\nCOPY --from=build /missing /app/\nADD https://example.com/a.txt /app/\nENTRYPOINT sh\n", ""},
+	}
+
+	for _, tt := range tests {
+		dir := writeDockerBlock(t, tt.dockerfile)
+		err := validateDockerDirectory(filepath.Join(dir, "docker", "custom"))
+		if tt.problem == "" && err != nil {
+			t.Errorf("%s: the docker directory should be valid, got %s", tt.name, err)
+		}
+		if tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)) {
+			t.Errorf("%s: validateDockerDirectory should report '%s', got %v", tt.name, tt.problem, err)
+		}
+	}
+
+	if err := validateDockerDirectory(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no Dockerfile") {
+		t.Errorf("a directory without a Dockerfile should be an error, got %v", err)
+	}
+}
+
+func Test_dockerDirectory(t *testing.T) {
+	dir := writeDockerBlock(t, "FROM python:3.6\nCMD sh\n")
+
+	if got, err := dockerDirectory(dir, "/docker/custom"); err != nil || got != filepath.Join(dir, "docker", "custom") {
+		t.Errorf("docker_directory_path should be relative to the block, got %s %v", got, err)
+	}
+	if _, err := dockerDirectory(dir, "/../outside"); err == nil || !strings.Contains(err.Error(), "outside of the block") {
+		t.Errorf("a docker_directory_path outside of the block should be an error, got %v", err)
+	}
+	if _, err := dockerDirectory(dir, "/docker/missing"); err == nil {
+		t.Errorf("a missing docker_directory_path should be an error")
+	}
+	if _, err := dockerDirectory(dir, ""); err == nil {
+		t.Errorf("an empty docker_directory_path should be an error")
+	}
+}
+
+func Test_prepareDockerRunErrors(t *testing.T) {
+	dir := writeDockerBlock(t, "FROM python:3.6\nCMD sh\n")
+	file := filepath.Join(dir, "01-unit", "docker.md")
+
+	tests := map[string]string{
+		"missing": "has no challenge with the id missing",
+		"short":   "only custom-snippet challenges",
+		"escapes": "outside of the block",
+	}
+	for id, problem := range tests {
+		if _, err := prepareDockerRun(file, id, "", "sh"); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("%s: prepareDockerRun should report '%s', got %v", id, problem, err)
+		}
+	}
+
+	if _, err := prepareDockerRun(file, "Custom One", "", "not-a-container-engine"); err == nil || !strings.Contains(err.Error(), "was not found") {
+		t.Errorf("a missing engine should be an error, got %v", err)
+	}
+}
+
+func Test_dockerRunExecute(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake engine is a shell script")
+	}
+	dir := writeDockerBlock(t, "FROM python:3.6\nCOPY test.sh /app/\nCMD sh /app/test.sh\n")
+	engine := filepath.Join(t.TempDir(), "engine")
+	if err := ioutil.WriteFile(engine, []byte(fakeEngine), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := prepareDockerRun(filepath.Join(dir, "01-unit", "docker.md"), "Custom One", "", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer run.cleanup()
+
+	if run.image != "learn-custom-snippet-custom-one:local" {
+		t.Errorf("the image should be named after the challenge id, got %s", run.image)
+	}
+	if run.submission != "submission.py" {
+		t.Errorf("the submission should be named after the language, got %s", run.submission)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	status, err := run.execute(stdout, stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 3 {
+		t.Errorf("execute should return the exit status of the container, got %d", status)
+	}
+	if !strings.Contains(stdout.String(), "return 1") {
+		t.Errorf("the solution should be mounted as the submission, got %s", stdout.String())
+	}
+
+	calls, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(engine), "calls"))
+	lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+	if len(lines) != 2 || lines[0] != "build --tag "+run.image+" "+filepath.Join(dir, "docker", "custom") {
+		t.Fatalf("execute should build the image from the docker directory, got %q", lines)
+	}
+	if !strings.Contains(lines[1], "--network none") || !strings.Contains(lines[1], "SUBMISSION_FILE=/submission/submission.py") {
+		t.Errorf("execute should run without a network with the submission mounted, got %s", lines[1])
+	}
+}
+
+func Test_dockerRunSubmissionFile(t *testing.T) {
+	dir := writeDockerBlock(t, "FROM python:3.6\nCMD sh\n")
+	submission := filepath.Join(dir, "answer.py")
+	ioutil.WriteFile(submission, []byte("def one():\n  return 2\n"), 0644)
+
+	run, err := prepareDockerRun(filepath.Join(dir, "01-unit", "docker.md"), "Custom One", submission, "sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer run.cleanup()
+
+	b, _ := ioutil.ReadFile(filepath.Join(run.submissionDir, "answer.py"))
+	if string(b) != "def one():\n  return 2\n" {
+		t.Errorf("the submission file should be mounted as it is, got %q", string(b))
+	}
+}
@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// giftSpecialChars are escaped with a backslash in GIFT text
+const giftSpecialChars = `~=#{}:\`
+
+// giftIDComment matches the comment export writes above each question to keep its id
+var giftIDComment = regexp.MustCompile(`^//\s*id:\s*(\S+)\s*$`)
+
+// giftWeight matches the %50% weight at the start of a GIFT answer
+var giftWeight = regexp.MustCompile(`^%(-?[0-9.]+)%`)
+
+// giftFormat matches the [markdown] style format at the start of GIFT question text
+var giftFormat = regexp.MustCompile(`^\[(html|markdown|plain|moodle)\]`)
+
+// unconvertible explains why a challenge can't be written as a quiz question for other
+// systems, or returns an empty string when it can
+func unconvertible(c challenge) string {
+	switch c.Type {
+	case "multiple-choice", "checkbox", "number", "paragraph":
+		return ""
+	case "short-answer":
+		for _, a := range c.Answers {
+			if strings.HasPrefix(a, "/") && strings.HasSuffix(a, "/") && len(a) > 1 {
+				return "short-answer challenges with a regex answer have no equivalent"
+			}
+		}
+		return ""
+	}
+	return fmt.Sprintf("%s challenges have no equivalent", c.Type)
+}
+
+// convertedWithout lists the parts of a challenge other systems have no place for. Only some
+// formats have somewhere to keep the placeholder
+func convertedWithout(c challenge, keepsPlaceholder bool) string {
+	left := []string{}
+	if c.Placeholder != "" && !keepsPlaceholder {
+		left = append(left, "placeholder")
+	}
+	if c.Hint != "" {
+		left = append(left, "hint")
+	}
+	if c.Rubric != "" {
+		left = append(left, "rubric")
+	}
+	for _, a := range c.Attributes {
+		left = append(left, a.Name)
+	}
+	if len(left) == 0 {
+		return ""
+	}
+	return "converted without its " + strings.Join(left, ", ")
+}
+
+// challengeNote names a challenge in the notes printed after converting
+func challengeNote(c challenge, note string) string {
+	name := c.ID
+	if c.File != "" {
+		name = c.File + " " + c.ID
+	}
+	return fmt.Sprintf("%s: %s", name, note)
+}
+
+// giftEscape escapes GIFT's special characters and writes newlines as \n so text stays on one line
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case strings.ContainsRune(giftSpecialChars, r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// giftUnescape reverses giftEscape
+func giftUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// giftWeightFor is the percentage each correct checkbox answer is worth, written the way Moodle
+// lists its weights
+func giftWeightFor(correct int) string {
+	return strings.TrimRight(strings.TrimRight(strconv.FormatFloat(100/float64(correct), 'f', 5, 64), "0"), ".")
+}
+
+// writeChallengesGIFT writes challenges as Moodle GIFT questions, returning notes about the
+// challenges that were skipped or lost parts on the way
+func writeChallengesGIFT(w io.Writer, challenges []challenge) ([]string, error) {
+	notes := []string{}
+	for _, c := range challenges {
+		if reason := unconvertible(c); reason != "" {
+			notes = append(notes, challengeNote(c, "skipped, "+reason))
+			continue
+		}
+		if without := convertedWithout(c, false); without != "" {
+			notes = append(notes, challengeNote(c, without))
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "// id: %s\n", c.ID)
+		if c.Title != "" {
+			fmt.Fprintf(&b, "::%s::", giftEscape(c.Title))
+		}
+		fmt.Fprintf(&b, "[markdown]%s {", giftEscape(c.Question))
+
+		switch c.Type {
+		case "multiple-choice", "checkbox":
+			correct := map[string]struct{}{}
+			for _, a := range c.Answers {
+				correct[a] = struct{}{}
+			}
+			for _, o := range c.Options {
+				_, isCorrect := correct[o]
+				switch {
+				case c.Type == "multiple-choice" && isCorrect:
+					b.WriteString("\n\t=")
+				case c.Type == "multiple-choice":
+					b.WriteString("\n\t~")
+				case isCorrect:
+					fmt.Fprintf(&b, "\n\t~%%%s%%", giftWeightFor(len(c.Answers)))
+				default:
+					b.WriteString("\n\t~%-100%")
+				}
+				b.WriteString(giftEscape(o))
+			}
+		case "short-answer":
+			fmt.Fprintf(&b, "\n\t=%s", giftEscape(strings.Join(c.Answers, "\n")))
+		case "number":
+			fmt.Fprintf(&b, "\n\t#%s", strings.Join(c.Answers, ""))
+		}
+		if c.Explanation != "" {
+			fmt.Fprintf(&b, "\n\t####%s", giftEscape(c.Explanation))
+		}
+		if c.Type != "paragraph" {
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return notes, err
+		}
+	}
+
+	return notes, nil
+}
+
+// readChallengesGIFT reads Moodle GIFT questions as challenges, returning notes about the
+// questions that have no matching challenge type
+func readChallengesGIFT(r io.Reader) ([]challenge, []string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challenges := []challenge{}
+	notes := []string{}
+	for n, block := range giftBlocks(strings.ReplaceAll(string(b), "\r\n", "\n")) {
+		c, err := parseGIFTQuestion(block.text)
+		c.ID = block.id
+		if err != nil {
+			name := fmt.Sprintf("question %d", n+1)
+			if c.Title != "" {
+				name += " (" + c.Title + ")"
+			}
+			notes = append(notes, fmt.Sprintf("%s: skipped, %s", name, err))
+			continue
+		}
+		challenges = append(challenges, c)
+	}
+
+	return challenges, notes, nil
+}
+
+// giftBlock is the text of one GIFT question and the id from the comment above it
+type giftBlock struct {
+	id   string
+	text string
+}
+
+// giftBlocks splits GIFT source into questions, which are separated by blank lines outside of
+// their answer braces. Comments and categories are left out
+func giftBlocks(source string) []giftBlock {
+	blocks := []giftBlock{}
+	current := giftBlock{}
+	lines := []string{}
+	depth := 0
+
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(lines, "\n")); text != "" {
+			current.text = text
+			blocks = append(blocks, current)
+		}
+		current = giftBlock{}
+		lines = []string{}
+	}
+
+	for _, line := range strings.Split(source, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 {
+			if trimmed == "" {
+				flush()
+				continue
+			}
+			if match := giftIDComment.FindStringSubmatch(trimmed); match != nil {
+				current.id = match[1]
+				continue
+			}
+			if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "$CATEGORY:") {
+				continue
+			}
+		}
+
+		lines = append(lines, line)
+		for i := 0; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// unescapedIndex is the index of the first sep in s that isn't escaped with a backslash, or -1
+func unescapedIndex(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseGIFTQuestion reads one GIFT question as a challenge
+func parseGIFTQuestion(text string) (challenge, error) {
+	c := challenge{}
+
+	if strings.HasPrefix(text, "::") {
+		end := unescapedIndex(text[2:], "::")
+		if end == -1 {
+			return c, fmt.Errorf("its title has no closing ::")
+		}
+		c.Title = giftUnescape(strings.TrimSpace(text[2 : 2+end]))
+		text = text[2+end+2:]
+	}
+
+	open := unescapedIndex(text, "{")
+	if open == -1 {
+		return c, fmt.Errorf("it has no answers, so it is a description rather than a question")
+	}
+	close := unescapedIndex(text[open:], "}")
+	if close == -1 {
+		return c, fmt.Errorf("its answers have no closing }")
+	}
+	close += open
+
+	question := strings.TrimSpace(giftFormat.ReplaceAllString(strings.TrimSpace(text[:open]), ""))
+	if after := strings.TrimSpace(text[close+1:]); after != "" {
+		question += " _____ " + after
+	}
+	c.Question = giftUnescape(question)
+	answers := strings.TrimSpace(text[open+1 : close])
+
+	if feedback := unescapedIndex(answers, "####"); feedback != -1 {
+		c.Explanation = giftUnescape(strings.TrimSpace(answers[feedback+4:]))
+		answers = strings.TrimSpace(answers[:feedback])
+	}
+
+	switch upper := strings.ToUpper(strings.TrimSpace(strings.SplitN(answers, "#", 2)[0])); {
+	case answers == "":
+		c.Type = "paragraph"
+		return c, nil
+	case upper == "T" || upper == "TRUE" || upper == "F" || upper == "FALSE":
+		c.Type = "multiple-choice"
+		c.Options = []string{"True", "False"}
+		c.Answers = []string{"True"}
+		if strings.HasPrefix(upper, "F") {
+			c.Answers = []string{"False"}
+		}
+		return c, nil
+	case strings.HasPrefix(answers, "#"):
+		number := strings.TrimSpace(answers[1:])
+		if strings.Contains(number, ":") || strings.Contains(number, "..") || strings.Contains(number, "=") {
+			return c, fmt.Errorf("numerical ranges and tolerances have no equivalent")
+		}
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			return c, fmt.Errorf("'%s' is not a number", number)
+		}
+		c.Type = "number"
+		c.Answers = []string{number}
+		return c, nil
+	case unescapedIndex(answers, "->") != -1:
+		return c, fmt.Errorf("matching questions have no equivalent")
+	}
+
+	return c, c.setGIFTAnswers(answers)
+}
+
+// setGIFTAnswers reads the =right and ~wrong answers of a GIFT question
+func (c *challenge) setGIFTAnswers(answers string) error {
+	type giftAnswer struct {
+		correct bool
+		weight  *float64
+		text    string
+	}
+
+	parsed := []giftAnswer{}
+	hasWrong := false
+	for i := 0; i < len(answers); {
+		if answers[i] != '=' && answers[i] != '~' {
+			return fmt.Errorf("'%s' is not a GIFT answer", strings.TrimSpace(answers[i:]))
+		}
+		marker := answers[i]
+		rest := answers[i+1:]
+		end := len(rest)
+		for _, sep := range []string{"=", "~"} {
+			if j := unescapedIndex(rest, sep); j != -1 && j < end {
+				end = j
+			}
+		}
+
+		a := giftAnswer{correct: marker == '=', text: strings.TrimSpace(rest[:end])}
+		if match := giftWeight.FindStringSubmatch(a.text); match != nil {
+			weight, _ := strconv.ParseFloat(match[1], 64)
+			a.weight = &weight
+			a.correct = weight > 0
+			a.text = strings.TrimSpace(a.text[len(match[0]):])
+		}
+		if feedback := unescapedIndex(a.text, "#"); feedback != -1 {
+			a.text = strings.TrimSpace(a.text[:feedback])
+		}
+		a.text = giftUnescape(a.text)
+		if marker == '~' {
+			hasWrong = true
+		}
+
+		parsed = append(parsed, a)
+		i += 1 + end
+	}
+
+	if !hasWrong {
+		c.Type = "short-answer"
+		if len(parsed) == 1 {
+			c.Answers = []string{parsed[0].text}
+			return nil
+		}
+		alternatives := []string{}
+		for _, a := range parsed {
+			alternatives = append(alternatives, regexp.QuoteMeta(a.text))
+		}
+		c.Answers = []string{"/^(?:" + strings.Join(alternatives, "|") + ")$/"}
+		return nil
+	}
+
+	weighted := false
+	for _, a := range parsed {
+		c.Options = append(c.Options, a.text)
+		if a.correct {
+			c.Answers = append(c.Answers, a.text)
+		}
+		weighted = weighted || a.weight != nil
+	}
+	c.Type = "multiple-choice"
+	if weighted || len(c.Answers) != 1 {
+		c.Type = "checkbox"
+	}
+	if len(c.Answers) == 0 {
+		return fmt.Errorf("it has no correct answer")
+	}
+
+	return nil
+}
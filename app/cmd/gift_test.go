@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// convertibleChallenges has one challenge of every type GIFT and QTI can hold
+var convertibleChallenges = []challenge{
+	{ID: "mc", Type: "multiple-choice", Title: "Colons: {and} braces", Question: "Which is *right*?\n\nPick = one ~ only", Options: []string{"a#1", "b"}, Answers: []string{"b"}, Explanation: "Because."},
+	{ID: "cb", Type: "checkbox", Title: "Primes", Question: "Which are prime?", Options: []string{"2", "3", "4"}, Answers: []string{"2", "3"}},
+	{ID: "sa", Type: "short-answer", Title: "Keyword", Question: "Stop a loop with?", Answers: []string{"break"}},
+	{ID: "nb", Type: "number", Title: "Pi", Question: "Pi to two places?", Answers: []string{"3.14"}},
+	{ID: "pg", Type: "paragraph", Title: "Essay", Question: "Explain loops."},
+}
+
+func Test_GIFTRoundTrip(t *testing.T) {
+	challenges := append(append([]challenge{}, convertibleChallenges...),
+		challenge{File: "lesson.md", ID: "code", Type: "code-snippet", Question: "Write code"},
+		challenge{ID: "regex", Type: "short-answer", Question: "Any", Answers: []string{"/^a+$/"}},
+		challenge{ID: "hinted", Type: "paragraph", Question: "Why?", Hint: "Think", Placeholder: "Because", Attributes: []challengeAttribute{{Name: "points", Value: "1"}}},
+	)
+
+	var out bytes.Buffer
+	notes, err := writeChallengesGIFT(&out, challenges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNotes := []string{
+		"lesson.md code: skipped, code-snippet challenges have no equivalent",
+		"regex: skipped, short-answer challenges with a regex answer have no equivalent",
+		"hinted: converted without its placeholder, hint, points",
+	}
+	if strings.Join(notes, "\n") != strings.Join(expectedNotes, "\n") {
+		t.Errorf("expected notes:\n%s\nbut got:\n%s", strings.Join(expectedNotes, "\n"), strings.Join(notes, "\n"))
+	}
+	if !strings.Contains(out.String(), `::Colons\: \{and\} braces::[markdown]Which is *right*?\n\nPick \= one \~ only {`) {
+		t.Errorf("GIFT special characters and newlines should be escaped, got:\n%s", out.String())
+	}
+
+	read, notes, err := readChallengesGIFT(&out)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("reading the export errored: %v %v", err, notes)
+	}
+	if len(read) != len(convertibleChallenges)+1 {
+		t.Fatalf("expected %d questions, got %d", len(convertibleChallenges)+1, len(read))
+	}
+	for i, expected := range convertibleChallenges {
+		if !reflect.DeepEqual(read[i], expected) {
+			t.Errorf("question %d expected %+v\nbut got %+v", i, expected, read[i])
+		}
+	}
+}
+
+func Test_readMoodleGIFT(t *testing.T) {
+	gift := `$CATEGORY: loops
+// a comment
+::TF::Loops repeat.{TRUE#Yes}
+
+::Match::Match these {
+  =a -> 1
+  =b -> 2
+}
+
+::Range::Pick a number {#1..5}
+
+Stop a loop with {=break =return} inside it.
+
+::Weighted::Pick {
+  ~%50%one
+  ~%50%two
+  ~%-100%three
+}
+
+Just a description.
+`
+	challenges, notes, err := readChallengesGIFT(strings.NewReader(gift))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedNotes := []string{
+		"question 2 (Match): skipped, matching questions have no equivalent",
+		"question 3 (Range): skipped, numerical ranges and tolerances have no equivalent",
+		"question 6: skipped, it has no answers, so it is a description rather than a question",
+	}
+	if strings.Join(notes, "\n") != strings.Join(expectedNotes, "\n") {
+		t.Errorf("expected notes:\n%s\nbut got:\n%s", strings.Join(expectedNotes, "\n"), strings.Join(notes, "\n"))
+	}
+
+	if len(challenges) != 3 {
+		t.Fatalf("expected 3 challenges, got %+v", challenges)
+	}
+	if tf := challenges[0]; tf.Type != "multiple-choice" || strings.Join(tf.Answers, ",") != "True" {
+		t.Errorf("true/false should be multiple choice, got %+v", tf)
+	}
+	if blank := challenges[1]; blank.Question != "Stop a loop with _____ inside it." || blank.Answers[0] != "/^(?:break|return)$/" {
+		t.Errorf("missing word alternatives should become a regex answer, got %+v", blank)
+	}
+	if weighted := challenges[2]; weighted.Type != "checkbox" || strings.Join(weighted.Answers, ",") != "one,two" {
+		t.Errorf("weighted answers should be a checkbox, got %+v", weighted)
+	}
+}
@@ -1,61 +1,81 @@
 package cmd
 
 import (
+	"embed"
 	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/gSchool/glearn-cli/gitutil"
 	"github.com/spf13/cobra"
 )
 
+// walkthroughFS is the example curriculum used by the walkthrough, built into the binary so
+// it works without network access
+//
+//go:embed walkthrough
+var walkthroughFS embed.FS
+
 var guideCmd = &cobra.Command{
 	Use:     "walkthrough",
 	Aliases: []string{"guide"},
-	Short:   "Download examples for use in the walkthrough",
-	Long:    "Download examples for use in the walkthrough",
-	Args:    cobra.MinimumNArgs(0),
+	Short:   "Add the example curriculum used in the walkthrough",
+	Long: `
+Adds a small example curriculum for use with the walkthrough at
+https://learn-2.galvanize.com/cohorts/667/blocks/13/content_files/walkthrough/01-overview.md
+
+The example is built into the CLI so no network access is needed. Use --from to
+copy a different curriculum from a git URL instead, and --dir to add it
+somewhere other than the current directory. Existing files are never replaced.
+	`,
+	Args: cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
-		// Get the current directory
-		currentDir, err := os.Getwd()
-		if err != nil {
-			fmt.Println("Could not detect a working directory")
+		dir := WalkthroughDir
+		if dir == "" {
+			dir = "."
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Could not create %s: %s\n", dir, err)
 			os.Exit(1)
 		}
 
 		// Does that directory have a config file
-		hasConfig, _ := doesCurrentDirHaveConfig(currentDir)
+		hasConfig, _ := doesCurrentDirHaveConfig(dir)
 
 		if hasConfig {
 			fmt.Println("WARNING: configuration file detected and cannot continue with `learn walkthrough` command.")
 			os.Exit(1)
 		}
 
-		// Clone the template from github
-		fmt.Println("Copying curriculum template from Github")
-		fmt.Println("=======================================")
-		fmt.Println("\nCloning into 'learn-curriculum-init'...")
-		err = cloneTemplate()
-		if err != nil {
-			fmt.Println("We had trouble cloning into learn-curriculum-init, please check that you have the correct github credentials")
-			os.Exit(1)
+		var source fs.FS
+		if WalkthroughFrom != "" {
+			fmt.Printf("Cloning %s...\n", WalkthroughFrom)
+			cloned, cleanup, err := cloneToTemp(WalkthroughFrom)
+			if err != nil {
+				fmt.Printf("We had trouble cloning %s:\n%s\n", WalkthroughFrom, err)
+				os.Exit(1)
+			}
+			defer cleanup()
+			source = os.DirFS(cloned)
+		} else {
+			source, _ = fs.Sub(walkthroughFS, "walkthrough")
 		}
 
-		// Move the files into working dir
 		fmt.Println("Copying curriculum")
-		err = moveClonedMaterials(currentDir)
+		created, err := extractCurriculum(source, dir)
 		if err != nil {
-			fmt.Println("Could not move template into working repository")
+			fmt.Println(err)
 			os.Exit(1)
 		}
-		fmt.Println("Removing cloned repo")
 
-		fmt.Println(`
+		fmt.Printf(`
 Success!
 ========
 
-A small example curriculum for use with the walkthrough at https://learn-2.galvanize.com/cohorts/667/blocks/13/content_files/walkthrough/01-overview.md has been added to this directory.`)
+A small example curriculum of %d files for use with the walkthrough at https://learn-2.galvanize.com/cohorts/667/blocks/13/content_files/walkthrough/01-overview.md has been added to %s.
+`, len(created), dir)
 	},
 }
 
@@ -85,32 +105,75 @@ func doesCurrentDirHaveConfig(currentDir string) (bool, bool) {
 	return configExist, autoConfigExist
 }
 
-func cloneTemplate() error {
-	_, err := exec.Command("bash", "-c", "git clone git@github.com:gSchool/learn-curriculum-init.git").CombinedOutput()
+// cloneToTemp clones url into a new temporary directory, returning it and a func removing it
+func cloneToTemp(url string) (string, func(), error) {
+	tmp, err := os.MkdirTemp("", "learn-walkthrough")
 	if err != nil {
-		_, errr := exec.Command("bash", "-c", "git clone https://github.com/gSchool/learn-curriculum-init.git").CombinedOutput()
-		if errr != nil {
-			return errr
-		}
+		return "", nil, err
 	}
+	cleanup := func() { os.RemoveAll(tmp) }
 
-	return nil
+	dir := filepath.Join(tmp, "curriculum")
+	if err = gitutil.Clone(url, dir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return dir, cleanup, nil
 }
 
-func moveClonedMaterials(currentDir string) error {
-	initDir := "/learn-curriculum-init"
-	os.RemoveAll(currentDir + initDir + "/.git/")
-	err := filepath.Walk(currentDir+initDir, func(path string, info os.FileInfo, err error) error {
-		if !strings.HasSuffix(path, initDir) && !strings.Contains(path, ".git/") {
-			oldLocation := path
-			newLocation := strings.Replace(path, initDir, "", 1)
-			os.Rename(oldLocation, newLocation)
+// extractCurriculum copies every regular file in source into dir, skipping git metadata.
+// Nothing is copied when any of the files already exists in dir, so existing work is never
+// replaced. It returns the paths it created
+func extractCurriculum(source fs.FS, dir string) ([]string, error) {
+	files := []string{}
+	conflicts := []string{}
+
+	err := fs.WalkDir(source, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		// symlinks and other special files could point outside of dir
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		files = append(files, p)
+		if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(p))); err == nil {
+			conflicts = append(conflicts, p)
 		}
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("Nothing was copied, these files already exist in %s:\n  %s", dir, strings.Join(conflicts, "\n  "))
+	}
+
+	created := []string{}
+	for _, p := range files {
+		target := filepath.Join(dir, filepath.FromSlash(p))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return created, err
+		}
+
+		content, err := fs.ReadFile(source, p)
+		if err != nil {
+			return created, err
+		}
+		if err = writeNewFile(target, string(content)); err != nil {
+			return created, err
+		}
+		created = append(created, target)
 	}
-	os.RemoveAll(currentDir + initDir)
-	return nil
+
+	return created, nil
 }
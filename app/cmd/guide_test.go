@@ -1,6 +1,14 @@
 package cmd
 
-import "testing"
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
 
 func Test_DoesDirHaveConfig(t *testing.T) {
 	hasConfig, _ := doesCurrentDirHaveConfig(withConfigFixture)
@@ -13,3 +21,63 @@ func Test_DoesDirHaveConfig(t *testing.T) {
 		t.Errorf("Should of found an auto config file in directory")
 	}
 }
+
+func Test_extractCurriculumEmbedded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-walkthrough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source, _ := fs.Sub(walkthroughFS, "walkthrough")
+	created, err := extractCurriculum(source, dir)
+	if err != nil {
+		t.Fatalf("extractCurriculum errored: %s", err)
+	}
+	if len(created) == 0 {
+		t.Errorf("extractCurriculum should copy the embedded curriculum")
+	}
+
+	hasConfig, _ := doesCurrentDirHaveConfig(dir)
+	if !hasConfig {
+		t.Errorf("the embedded curriculum should include a config.yaml")
+	}
+	problems, err := validateBlockConfig(dir)
+	if err != nil || len(problems) > 0 {
+		t.Errorf("the embedded curriculum should be valid, got %v %v", problems, err)
+	}
+}
+
+func Test_extractCurriculumDoesNotClobber(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-walkthrough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := fstest.MapFS{
+		"README.md":               {Data: []byte("# Example\n")},
+		"units/01-intro/intro.md": {Data: []byte("# Intro\n")},
+		".git/HEAD":               {Data: []byte("ref: refs/heads/main\n")},
+	}
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("mine"), 0644)
+
+	if _, err = extractCurriculum(source, dir); err == nil || !strings.Contains(err.Error(), "README.md") {
+		t.Errorf("extracting over an existing file should error naming it, got %v", err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "README.md")); string(b) != "mine" {
+		t.Errorf("an existing file should not be replaced, got %s", b)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "units")); !os.IsNotExist(err) {
+		t.Errorf("nothing should be copied when a file already exists")
+	}
+
+	os.Remove(filepath.Join(dir, "README.md"))
+	created, err := extractCurriculum(source, dir)
+	if err != nil || len(created) != 2 {
+		t.Errorf("expected README.md and intro.md to be copied, got %v %v", created, err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+		t.Errorf("git metadata should not be copied")
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// insertPosition is where learn md puts a template in a file instead of appending it. Only
+// one of its fields is set
+type insertPosition struct {
+	afterHeading string
+	line         int
+	replace      string
+}
+
+// headingLine matches a markdown heading, capturing its text
+var headingLine = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+
+// fenceLine matches the start or end of a fenced code block
+var fenceLine = regexp.MustCompile("^\\s*(```|~~~)")
+
+// set is true when any way of inserting was asked for
+func (p insertPosition) set() bool {
+	return p.afterHeading != "" || p.line != 0 || p.replace != ""
+}
+
+// insertPositionFromFlags is the position given with --after-heading, --line or --replace
+func insertPositionFromFlags() (insertPosition, error) {
+	p := insertPosition{afterHeading: InsertAfterHeading, line: InsertLine, replace: ReplaceChallenge}
+
+	given := 0
+	for _, set := range []bool{p.afterHeading != "", p.line != 0, p.replace != ""} {
+		if set {
+			given++
+		}
+	}
+	if given > 1 {
+		return p, fmt.Errorf("Only one of --after-heading, --line and --replace can be used at a time\n")
+	}
+	if p.line < 0 {
+		return p, fmt.Errorf("--line must be a line number, starting at 1\n")
+	}
+
+	return p, nil
+}
+
+// render is the template's content with a new id when it needs one
+func (t temp) render() (string, string) {
+	if !t.RequireId {
+		return t.Template, ""
+	}
+	id := uuid.New().String()
+	return fmt.Sprintf(strings.ReplaceAll(t.Template, `~~~`, "```"), id), id
+}
+
+// insertContent writes the template into the markdown file target at pos
+func (t temp) insertContent(target string, pos insertPosition) error {
+	if err := checkAppendTarget(target); err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(target)
+	if err != nil {
+		return fmt.Errorf("Cannot read '%s'!\n%s\n", target, err)
+	}
+
+	content, id := t.render()
+	updated, err := insertTemplate(string(b), content, pos)
+	if err != nil {
+		return fmt.Errorf("Cannot add %s to '%s': %s\n", t.Name, target, err)
+	}
+	if err = ioutil.WriteFile(target, []byte(updated), info.Mode()); err != nil {
+		return fmt.Errorf("Cannot write to '%s'!\n%s\n", target, err)
+	}
+
+	switch {
+	case pos.replace != "":
+		fmt.Printf("%s replaced challenge %s in %s!\n", t.Name, pos.replace, target)
+	case pos.afterHeading != "":
+		fmt.Printf("%s added after '%s' in %s!\n", t.Name, pos.afterHeading, target)
+	default:
+		fmt.Printf("%s added at line %d of %s!\n", t.Name, pos.line, target)
+	}
+	if id != "" {
+		fmt.Printf("id: %s\n", id)
+	}
+
+	return nil
+}
+
+// insertTemplate puts content into the markdown source at pos, separating it from the lines
+// around it with a blank line where there isn't one already
+func insertTemplate(source, content string, pos insertPosition) (string, error) {
+	trailingNewline := strings.HasSuffix(source, "\n")
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	if source == "" {
+		lines = []string{}
+	}
+
+	var at int
+	switch {
+	case pos.replace != "":
+		start, end, err := findChallenge(lines, pos.replace)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines[:start], lines[end+1:]...)
+		at = start
+	case pos.afterHeading != "":
+		heading, err := findHeading(lines, pos.afterHeading)
+		if err != nil {
+			return "", err
+		}
+		at = heading + 1
+	default:
+		if pos.line < 1 || pos.line > len(lines)+1 {
+			return "", fmt.Errorf("line %d is outside of the file, which has %d lines", pos.line, len(lines))
+		}
+		at = pos.line - 1
+	}
+
+	inserted := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+		inserted = append([]string{""}, inserted...)
+	}
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		inserted = append(inserted, "")
+	}
+
+	result := append(append(append([]string{}, lines[:at]...), inserted...), lines[at:]...)
+	out := strings.Join(result, "\n")
+	if trailingNewline || at == len(lines) {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// findHeading returns the index of the first heading outside of code blocks whose text is
+// heading, ignoring case
+func findHeading(lines []string, heading string) (int, error) {
+	inFence := false
+	for i, line := range lines {
+		if fenceLine.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if match := headingLine.FindStringSubmatch(line); match != nil && strings.EqualFold(match[1], strings.TrimSpace(heading)) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no heading '%s' found", heading)
+}
+
+// findChallenge returns the first and last line of the challenge with id, including the
+// BEGIN CHALLENGE and END CHALLENGE comments around it when it has them
+func findChallenge(lines []string, id string) (int, int, error) {
+	challenges, err := parseChallenges("", strings.Join(lines, "\n"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, c := range challenges {
+		if c.ID == id {
+			start, end := expandChallenge(lines, c.start, c.end)
+			return start, end, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("no challenge with id '%s' found", id)
+}
+
+// expandChallenge widens the challenge between start and end to the comment markers around it,
+// skipping only blank lines and other comments to reach them
+func expandChallenge(lines []string, start, end int) (int, int) {
+	isSkippable := func(line string) bool {
+		line = strings.TrimSpace(line)
+		return line == "" || (strings.HasPrefix(line, "<!--") && strings.HasSuffix(line, "-->"))
+	}
+
+	for i := start - 1; i >= 0 && isSkippable(lines[i]); i-- {
+		if strings.Contains(lines[i], "BEGIN CHALLENGE") {
+			start = i
+			break
+		}
+	}
+	for i := end + 1; i < len(lines) && isSkippable(lines[i]); i++ {
+		if strings.Contains(lines[i], "END CHALLENGE") {
+			end = i
+			break
+		}
+	}
+
+	return start, end
+}
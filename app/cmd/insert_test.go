@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const lessonWithChallenges = `# Loops
+
+Some content.
+
+~~~md
+## Challenges
+~~~
+
+## Challenges
+Intro to the challenges.
+
+<!-- >>>>>>>>>>>>>>>>>>>>>> BEGIN CHALLENGE >>>>>>>>>>>>>>>>>>>>>> -->
+<!-- Replace everything in square brackets [] and remove brackets  -->
+
+### !challenge
+
+* type: short-answer
+* id: first
+
+##### !question
+
+What?
+
+##### !end-question
+
+### !end-challenge
+
+<!-- ======================= END CHALLENGE ======================= -->
+
+### !challenge
+
+* type: short-answer
+* id: second
+
+### !end-challenge
+
+## Wrap up
+`
+
+func Test_insertTemplate(t *testing.T) {
+	tableTest := map[string]struct {
+		pos      insertPosition
+		expected string
+	}{
+		"after heading": {
+			insertPosition{afterHeading: "challenges"},
+			"# Loops\n\nSome content.\n\n~~~md\n## Challenges\n~~~\n\n## Challenges\n\nNEW\n\nIntro to the challenges.\n",
+		},
+		"at a line": {
+			insertPosition{line: 2},
+			"# Loops\n\nNEW\n\nSome content.\n",
+		},
+		"at the end": {
+			insertPosition{line: 38},
+			"## Wrap up\n\nNEW\n",
+		},
+		"replace with markers": {
+			insertPosition{replace: "first"},
+			"Intro to the challenges.\n\nNEW\n\n### !challenge\n\n* type: short-answer\n* id: second\n",
+		},
+		"replace without markers": {
+			insertPosition{replace: "second"},
+			"<!-- ======================= END CHALLENGE ======================= -->\n\nNEW\n\n## Wrap up\n",
+		},
+	}
+
+	for name, test := range tableTest {
+		result, err := insertTemplate(lessonWithChallenges, "NEW\n", test.pos)
+		if err != nil {
+			t.Errorf("%s: insertTemplate errored: %s", name, err)
+			continue
+		}
+		if !strings.Contains(result, test.expected) {
+			t.Errorf("%s: expected to find:\n%s\nin:\n%s", name, test.expected, result)
+		}
+	}
+
+	if result, _ := insertTemplate(lessonWithChallenges, "NEW", insertPosition{replace: "first"}); strings.Contains(result, "* id: first") {
+		t.Errorf("the replaced challenge should be removed, got:\n%s", result)
+	}
+
+	for name, pos := range map[string]insertPosition{
+		"missing heading":   {afterHeading: "Nope"},
+		"line past the end": {line: 39},
+		"missing challenge": {replace: "third"},
+	} {
+		if _, err := insertTemplate(lessonWithChallenges, "NEW", pos); err == nil {
+			t.Errorf("%s should error", name)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// interactiveTypes are the challenge types `learn md --interactive` can build, keyed by the
+// same names and abbreviations as the templates
+var interactiveTypes = map[string]string{
+	"mc":             "multiple-choice",
+	"multiplechoice": "multiple-choice",
+	"cb":             "checkbox",
+	"checkbox":       "checkbox",
+	"sa":             "short-answer",
+	"shortanswer":    "short-answer",
+	"nb":             "number",
+	"number":         "number",
+	"pg":             "paragraph",
+	"paragraph":      "paragraph",
+}
+
+// challengeAnswers is everything asked for when building a challenge interactively
+type challengeAnswers struct {
+	Type        string
+	Title       string
+	Question    string
+	Options     []string
+	Answers     []string
+	Placeholder string
+	Points      string
+	Topics      []string
+	Hint        string
+	Explanation string
+}
+
+// interactiveTemp asks for the parts of a challenge of the given type and returns it as a
+// template with a place for the id, so it can be copied, printed or appended like the others
+func interactiveTemp(command string) (temp, error) {
+	challengeType, ok := interactiveTypes[command]
+	if !ok {
+		return temp{}, fmt.Errorf("'%s' cannot be built interactively, use one of: mc, cb, sa, nb, pg\n", command)
+	}
+
+	answers, err := askChallenge(challengeType)
+	if err != nil {
+		return temp{}, fmt.Errorf("Stopped building the challenge: %s\n", err)
+	}
+
+	return temp{
+		Name:      strings.Title(strings.Replace(challengeType, "-", " ", 1)) + " challenge",
+		Template:  renderChallenge(answers),
+		RequireId: true,
+	}, nil
+}
+
+// askChallenge prompts for each part of a challenge, asking again when an answer can't be used
+func askChallenge(challengeType string) (challengeAnswers, error) {
+	c := challengeAnswers{Type: challengeType}
+	var err error
+
+	if c.Title, err = askRequired("Title: "); err != nil {
+		return c, err
+	}
+	if c.Question, err = askLines("Question (markdown, finish with an empty line):"); err != nil {
+		return c, err
+	}
+	for c.Question == "" {
+		fmt.Fprintln(promptOut, "A question is required")
+		if c.Question, err = askLines("Question (markdown, finish with an empty line):"); err != nil {
+			return c, err
+		}
+	}
+
+	switch challengeType {
+	case "multiple-choice", "checkbox":
+		if c.Options, c.Answers, err = askOptions(challengeType == "checkbox"); err != nil {
+			return c, err
+		}
+	case "short-answer":
+		if c.Placeholder, err = prompt("Placeholder (optional): "); err != nil {
+			return c, err
+		}
+		answer, err := askRequired("Answer (text, or a regex wrapped in /): ")
+		if err != nil {
+			return c, err
+		}
+		c.Answers = []string{answer}
+	case "number":
+		if c.Placeholder, err = prompt("Placeholder (optional): "); err != nil {
+			return c, err
+		}
+		for {
+			answer, err := askRequired("Answer (a number): ")
+			if err != nil {
+				return c, err
+			}
+			if _, err = strconv.ParseFloat(answer, 64); err == nil {
+				c.Answers = []string{answer}
+				break
+			}
+			fmt.Fprintf(promptOut, "'%s' is not a number\n", answer)
+		}
+	case "paragraph":
+		if c.Placeholder, err = prompt("Placeholder (optional): "); err != nil {
+			return c, err
+		}
+	}
+
+	for {
+		if c.Points, err = prompt("Points (optional, for checkpoints): "); err != nil {
+			return c, err
+		}
+		if _, err := strconv.Atoi(c.Points); c.Points == "" || err == nil {
+			break
+		}
+		fmt.Fprintf(promptOut, "'%s' is not a whole number\n", c.Points)
+	}
+
+	topics, err := prompt("Topics (optional, comma separated): ")
+	if err != nil {
+		return c, err
+	}
+	for _, topic := range strings.Split(topics, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			c.Topics = append(c.Topics, topic)
+		}
+	}
+
+	if c.Hint, err = askLines("Hint (optional, finish with an empty line):"); err != nil {
+		return c, err
+	}
+	if c.Explanation, err = askLines("Explanation (optional, finish with an empty line):"); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// askRequired prompts until it gets a non-empty answer
+func askRequired(question string) (string, error) {
+	for {
+		answer, err := prompt(question)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		fmt.Fprintln(promptOut, "An answer is required")
+	}
+}
+
+// askLines prompts for markdown spanning several lines, ending at the first empty line
+func askLines(question string) (string, error) {
+	fmt.Fprintln(promptOut, question)
+
+	lines := []string{}
+	for {
+		line, err := prompt("> ")
+		if err != nil {
+			if len(lines) > 0 {
+				break
+			}
+			return "", err
+		}
+		if line == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// askOptions prompts for the options of a multiple choice or checkbox challenge and the
+// numbers of the correct ones
+func askOptions(multiple bool) ([]string, []string, error) {
+	fmt.Fprintln(promptOut, "Options, one per line (finish with an empty line):")
+	options := []string{}
+	for {
+		option, err := prompt(fmt.Sprintf("%d. ", len(options)+1))
+		if err != nil {
+			if len(options) >= 2 {
+				break
+			}
+			return nil, nil, err
+		}
+		if option == "" {
+			if len(options) >= 2 {
+				break
+			}
+			fmt.Fprintln(promptOut, "At least two options are required")
+			continue
+		}
+		options = append(options, option)
+	}
+
+	question := "Number of the correct option: "
+	if multiple {
+		question = "Numbers of the correct options (comma separated): "
+	}
+	for {
+		answer, err := askRequired(question)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		answers, err := pickOptions(options, answer, multiple)
+		if err == nil {
+			return options, answers, nil
+		}
+		fmt.Fprintln(promptOut, err)
+	}
+}
+
+// pickOptions returns the options chosen by a comma separated list of their numbers
+func pickOptions(options []string, answer string, multiple bool) ([]string, error) {
+	picked := []string{}
+	seen := map[int]struct{}{}
+	for _, part := range strings.Split(answer, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > len(options) {
+			return nil, fmt.Errorf("'%s' is not an option number between 1 and %d", strings.TrimSpace(part), len(options))
+		}
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+		picked = append(picked, options[n-1])
+	}
+	if !multiple && len(picked) != 1 {
+		return nil, fmt.Errorf("a multiple choice challenge has exactly one correct option")
+	}
+
+	return picked, nil
+}
+
+// renderChallenge writes the challenge markdown with %s where the id goes. Everything that was
+// typed in has % escaped so the id can be added with fmt
+func renderChallenge(a challengeAnswers) string {
+	const idPlaceholder = "ID-PLACEHOLDER"
+
+	c := challenge{
+		ID:          idPlaceholder,
+		Type:        a.Type,
+		Title:       a.Title,
+		Question:    a.Question,
+		Options:     a.Options,
+		Answers:     a.Answers,
+		Placeholder: a.Placeholder,
+		Hint:        a.Hint,
+		Explanation: a.Explanation,
+	}
+	if a.Points != "" {
+		c.Attributes = append(c.Attributes, challengeAttribute{Name: "points", Value: a.Points})
+	}
+	if len(a.Topics) > 0 {
+		c.Attributes = append(c.Attributes, challengeAttribute{Name: "topics", Value: "[" + strings.Join(a.Topics, ", ") + "]"})
+	}
+
+	escaped := strings.ReplaceAll(c.markdown(), "%", "%%")
+	return strings.Replace(escaped, "* id: "+idPlaceholder, "* id: %s", 1)
+}
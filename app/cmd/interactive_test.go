@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const builtCheckbox = `<!-- >>>>>>>>>>>>>>>>>>>>>> BEGIN CHALLENGE >>>>>>>>>>>>>>>>>>>>>> -->
+
+### !challenge
+
+* type: checkbox
+* id: abc-123
+* title: Even numbers
+* points: 2
+* topics: [math, parity]
+
+##### !question
+
+Which are even?
+Pick every one that is 100% even.
+
+##### !end-question
+
+##### !options
+
+* 1
+* 2
+* 4
+
+##### !end-options
+
+##### !answer
+
+* 2
+* 4
+
+##### !end-answer
+
+##### !hint
+
+Divide by two
+
+##### !end-hint
+
+### !end-challenge
+
+<!-- ======================= END CHALLENGE ======================= -->`
+
+func Test_interactiveTemp(t *testing.T) {
+	input := strings.Join([]string{
+		"", // title is required
+		"Even numbers",
+		"Which are even?",
+		"Pick every one that is 100% even.",
+		"",
+		"1", "2", "4", "",
+		"5",   // not an option
+		"2,3", // correct options
+		"two", // not a number of points
+		"2",
+		"math, parity",
+		"Divide by two", "",
+		"", // no explanation
+	}, "\n") + "\n"
+
+	withPromptInput(input, func() {
+		tmp, err := interactiveTemp("cb")
+		if err != nil {
+			t.Fatalf("interactiveTemp errored: %s", err)
+		}
+		if !tmp.RequireId || fmt.Sprintf(tmp.Template, "abc-123") != builtCheckbox {
+			t.Errorf("interactiveTemp expected:\n%s\nbut got:\n%s", builtCheckbox, fmt.Sprintf(tmp.Template, "abc-123"))
+		}
+	})
+
+	withPromptInput("Title\nQuestion\n\n\n7.5\n\n\n\n\n", func() {
+		tmp, err := interactiveTemp("number")
+		if err != nil || !strings.Contains(tmp.Template, "##### !answer\n\n7.5\n\n##### !end-answer") {
+			t.Errorf("a number challenge should have the number as its answer, got %s %v", tmp.Template, err)
+		}
+	})
+
+	withPromptInput("Title\n", func() {
+		if _, err := interactiveTemp("mc"); err == nil {
+			t.Errorf("running out of input should stop building the challenge")
+		}
+	})
+
+	if _, err := interactiveTemp("js"); err == nil {
+		t.Errorf("types that can't be built interactively should error")
+	}
+}
+
+func Test_pickOptions(t *testing.T) {
+	options := []string{"a", "b", "c"}
+	if picked, err := pickOptions(options, "3, 1, 3", true); err != nil || strings.Join(picked, ",") != "c,a" {
+		t.Errorf("expected c,a got %v %v", picked, err)
+	}
+	if _, err := pickOptions(options, "1,2", false); err == nil {
+		t.Errorf("multiple choice should only allow one correct option")
+	}
+	if _, err := pickOptions(options, "4", true); err == nil {
+		t.Errorf("option numbers should be in range")
+	}
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gSchool/glearn-cli/mdlinkparser"
+	"github.com/spf13/cobra"
+)
+
+// externalLinkTimeout is how long a request checking an external link can take
+const externalLinkTimeout = 10 * time.Second
+
+var linksCmd = &cobra.Command{
+	Use:   "links",
+	Short: "Check the links in your curriculum",
+	Long: `
+Work with the links between the lessons of a block. For example:
+
+  learn links check
+  learn links check units/01-intro --external
+	`,
+}
+
+var linksCheckCmd = &cobra.Command{
+	Use:   "check [dir]",
+	Short: "Report links, images and anchors that don't resolve",
+	Long: `
+Checks every link and image in the markdown files under a directory, which
+defaults to the current one. Relative paths are resolved from the file they are
+in, and paths starting with / from the root of the block. Anchors like
+lesson.md#a-heading must match a heading or an html id in the file linked to.
+Links that leave the block are reported as broken.
+
+With --external, http and https links are requested too and reported when they
+fail or return an error status. Links in code blocks are never checked.
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+
+		var checker linkChecker
+		if LinksExternal {
+			checker = newHTTPLinkChecker(externalLinkTimeout)
+		}
+
+		report, err := checkLinks(dir, checker)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if broken := report.print(os.Stdout); broken > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// linkChecker checks an external URL, returning why it is broken
+type linkChecker interface {
+	check(url string) error
+}
+
+// httpLinkChecker requests external links, trying GET when a server doesn't allow HEAD
+type httpLinkChecker struct {
+	client *http.Client
+}
+
+func newHTTPLinkChecker(timeout time.Duration) *httpLinkChecker {
+	return &httpLinkChecker{client: &http.Client{Timeout: timeout}}
+}
+
+func (c *httpLinkChecker) check(link string) error {
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, link, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", "learn-cli/"+currentReleaseVersion)
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("the request failed, %s", err)
+		}
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+		res.Body.Close()
+
+		status = res.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented && status != http.StatusForbidden {
+			break
+		}
+	}
+
+	if status >= 400 {
+		return fmt.Errorf("it returned %d %s", status, http.StatusText(status))
+	}
+	return nil
+}
+
+// brokenLink is a reference that doesn't resolve, and why
+type brokenLink struct {
+	file        string
+	line        int
+	column      int
+	destination string
+	reason      string
+}
+
+// linkReport is the outcome of checking the links under a directory
+type linkReport struct {
+	files   int
+	checked int
+	broken  []brokenLink
+}
+
+// linkSource is a reference and the file it was found in
+type linkSource struct {
+	file string
+	ref  mdlinkparser.Reference
+}
+
+// checkLinks resolves the references in every markdown file under dir. External links are
+// only checked when a checker is given
+func checkLinks(dir string, checker linkChecker) (*linkReport, error) {
+	root := findBlockRoot(dir)
+	report := &linkReport{}
+	anchors := map[string]map[string]struct{}{}
+	external := map[string][]linkSource{}
+
+	err := walkMarkdownFiles(dir, func(path, rel string) error {
+		source, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		report.files++
+
+		abs, _ := filepath.Abs(path)
+		anchors[abs] = mdlinkparser.Anchors(source)
+
+		for _, ref := range mdlinkparser.Parse(source) {
+			// A reference style link carries the destination of its definition, so checking the
+			// definition as well would report it twice
+			if ref.Kind == mdlinkparser.Definition {
+				continue
+			}
+
+			switch {
+			case ref.IsLocal():
+				report.checked++
+				if reason := resolveLocalLink(root, abs, ref, anchors); reason != "" {
+					report.add(path, ref, reason)
+				}
+			case strings.HasPrefix(ref.Destination, "#"):
+				report.checked++
+				if !hasAnchor(anchors[abs], ref.Fragment()) {
+					report.add(path, ref, "there is no heading or html id #"+ref.Fragment()+" in this file")
+				}
+			case checker != nil && (strings.HasPrefix(ref.Destination, "http://") || strings.HasPrefix(ref.Destination, "https://")):
+				report.checked++
+				external[ref.Destination] = append(external[ref.Destination], linkSource{file: path, ref: ref})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if checker != nil {
+		report.checkExternal(external, checker)
+	}
+
+	sort.Slice(report.broken, func(i, j int) bool {
+		a, b := report.broken[i], report.broken[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		return a.column < b.column
+	})
+	return report, nil
+}
+
+// resolveLocalLink returns why the local reference in file doesn't resolve in the block at
+// root, or an empty string when it does. anchors caches the anchors of markdown files read
+func resolveLocalLink(root, file string, ref mdlinkparser.Reference, anchors map[string]map[string]struct{}) string {
+	path := ref.Path()
+	target := filepath.Join(filepath.Dir(file), filepath.FromSlash(path))
+	if strings.HasPrefix(path, "/") {
+		target = filepath.Join(root, filepath.FromSlash(path))
+	}
+
+	if !withinDir(root, target) {
+		return path + " is outside of the block"
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return path + " does not exist"
+	}
+
+	fragment := ref.Fragment()
+	if fragment == "" || info.IsDir() || filepath.Ext(target) != ".md" {
+		return ""
+	}
+	if _, ok := anchors[target]; !ok {
+		source, err := ioutil.ReadFile(target)
+		if err != nil {
+			return path + " cannot be read"
+		}
+		anchors[target] = mdlinkparser.Anchors(source)
+	}
+	if !hasAnchor(anchors[target], fragment) {
+		return fmt.Sprintf("there is no heading or html id #%s in %s", fragment, path)
+	}
+	return ""
+}
+
+// hasAnchor is true when fragment, which may be percent encoded, is one of anchors
+func hasAnchor(anchors map[string]struct{}, fragment string) bool {
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	_, ok := anchors[fragment]
+	if !ok {
+		_, ok = anchors[strings.ToLower(fragment)]
+	}
+	return ok
+}
+
+// checkExternal requests each external URL once, a few at a time, and reports every place
+// a broken one is linked from
+func (r *linkReport) checkExternal(external map[string][]linkSource, checker linkChecker) {
+	urls := make([]string, 0, len(external))
+	for u := range external {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	var mu sync.Mutex
+	runBatch(len(urls), defaultBatchConcurrency, func(i int) {
+		err := checker.check(urls[i])
+		if err == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, source := range external[urls[i]] {
+			r.add(source.file, source.ref, err.Error())
+		}
+	})
+}
+
+// add records a broken reference found in file
+func (r *linkReport) add(file string, ref mdlinkparser.Reference, reason string) {
+	r.broken = append(r.broken, brokenLink{
+		file:        file,
+		line:        ref.Line,
+		column:      ref.Column,
+		destination: ref.Destination,
+		reason:      reason,
+	})
+}
+
+// print writes a line per broken link and a summary, returning the number of broken links
+func (r *linkReport) print(out io.Writer) int {
+	for _, b := range r.broken {
+		fmt.Fprintf(out, "%s:%d:%d: %s: %s\n", b.file, b.line, b.column, b.destination, b.reason)
+	}
+	if len(r.broken) > 0 {
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "Checked %d links in %d files, %d broken\n", r.checked, r.files, len(r.broken))
+	return len(r.broken)
+}
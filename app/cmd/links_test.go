@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLinkBlock(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func Test_checkLinks(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"config.yaml":         "Standards: []\n",
+		"images/logo.png":     "png",
+		"unit-2/lesson.md":    "# Lesson Two\n\n## Getting Started\n",
+		"node_modules/a/x.md": "[not checked](missing.md)\n",
+		"unit-1/lesson.md": "# Lesson One\n\n" +
+			"![logo](../images/logo.png) ![absolute](/images/logo.png)\n\n" +
+			"[next](../unit-2/lesson.md) [start](../unit-2/lesson.md#getting-started) [top](#lesson-one)\n\n" +
+			"[missing](../unit-2/missing.md)\n\n" +
+			"[bad anchor](../unit-2/lesson.md#nowhere)\n\n" +
+			"[bad local anchor](#nowhere)\n\n" +
+			"<img src=\"missing.png\">\n\n" +
+			"[escapes](../../outside.md)\n\n" +
+			"```md\n[in code](not-checked.md)\n```\n\n" +
+			"[site](https://example.com)\n",
+	})
+
+	report, err := checkLinks(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.files != 2 {
+		t.Errorf("checkLinks should skip node_modules and check 2 files, checked %d", report.files)
+	}
+
+	lesson := filepath.Join(dir, "unit-1", "lesson.md")
+	want := []string{
+		lesson + ":7:1: ../unit-2/missing.md: ../unit-2/missing.md does not exist",
+		lesson + ":9:1: ../unit-2/lesson.md#nowhere: there is no heading or html id #nowhere in ../unit-2/lesson.md",
+		lesson + ":11:1: #nowhere: there is no heading or html id #nowhere in this file",
+		lesson + ":13:1: missing.png: missing.png does not exist",
+		lesson + ":15:1: ../../outside.md: ../../outside.md is outside of the block",
+	}
+	out := &bytes.Buffer{}
+	if broken := report.print(out); broken != len(want) {
+		t.Errorf("checkLinks should find %d broken links, found %d:\n%s", len(want), broken, out.String())
+	}
+	for _, line := range want {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("the report should include %q, got:\n%s", line, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "Checked 10 links in 2 files, 5 broken") {
+		t.Errorf("the report should end with a summary, got:\n%s", out.String())
+	}
+}
+
+func Test_checkLinksExternal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := writeLinkBlock(t, map[string]string{
+		"lesson.md": fmt.Sprintf("# Links\n\n[ok](%[1]s/ok)\n\n[no head](%[1]s/no-head)\n\n[gone](%[1]s/gone) and [again](%[1]s/gone)\n\n[mail](mailto:a@example.com)\n", server.URL),
+	})
+
+	report, err := checkLinks(dir, newHTTPLinkChecker(externalLinkTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.broken) != 2 {
+		t.Fatalf("both links to the missing page should be broken, got %+v", report.broken)
+	}
+	for _, b := range report.broken {
+		if b.destination != server.URL+"/gone" || b.reason != "it returned 404 Not Found" {
+			t.Errorf("only the missing page should be broken, got %+v", b)
+		}
+	}
+	if report.checked != 4 {
+		t.Errorf("the http links should be checked and mailto skipped, checked %d", report.checked)
+	}
+}
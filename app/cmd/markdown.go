@@ -12,14 +12,31 @@ import (
 
 var PrintTemplate bool
 
+// markdownLong is the help of learn md before the custom templates are listed
+const markdownLong = "Copy curriculum markdown to clipboard. Takes 1-2 arguments, the type of content to copy to clipboard and optionally a file to append.\n\nWith --interactive, builds a complete challenge by asking for each part of it. Works with multiplechoice, checkbox, shortanswer, number and paragraph.\n\nInstead of appending, --after-heading \"<heading>\" or --line <n> adds the template at that place in the file, and --replace <challenge id> swaps that challenge for the template.\n\n" + argList
+
 var markdownCmd = &cobra.Command{
 	Use:     "markdown",
 	Aliases: []string{"md"},
 	Short:   "Copy curriculum markdown to clipboard",
-	Long:    "Copy curriculum markdown to clipboard. Takes 1-2 arguments, the type of content to copy to clipboard and optionally a file to append.\n\n" + argList,
+	Long:    markdownLong,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// Templates from the user's and the repo's templates directories, see `learn md --help`
+		loadCustomTemplates()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		pos, err := insertPositionFromFlags()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
 		if len(args) == 1 {
-			t, err := getTemp(args[0])
+			if pos.set() {
+				fmt.Println("--after-heading, --line and --replace need a markdown file to add the template to")
+				os.Exit(1)
+			}
+			t, err := chooseTemp(args[0])
 			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
@@ -31,7 +48,11 @@ var markdownCmd = &cobra.Command{
 			}
 
 		} else if len(args) == 2 {
-			t, err := getTemp(args[0])
+			if err := checkAppendTarget(args[1]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			t, err := chooseTemp(args[0])
 			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
@@ -39,19 +60,39 @@ var markdownCmd = &cobra.Command{
 			if PrintTemplate {
 				fmt.Println("-o flag skipped when appending...")
 			}
-			if err = t.appendContent(args[1]); err != nil {
+			if pos.set() {
+				err = t.insertContent(args[1], pos)
+			} else {
+				err = t.appendContent(args[1])
+			}
+			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
 			}
 
 		} else {
-			fmt.Println(incorrectNumArgs)
+			fmt.Println(incorrectNumArgs + customTemplateList(customTemplates))
 			os.Exit(1)
 		}
 
 	},
 }
 
+// markdownHelp is the help of learn md, which lists the custom templates it can use
+func markdownHelp(cmd *cobra.Command, args []string) {
+	loadCustomTemplates()
+	cmd.Long = markdownLong + customTemplateList(customTemplates)
+	rootCmd.HelpFunc()(cmd, args)
+}
+
+// chooseTemp is the template for command, built by asking questions with --interactive
+func chooseTemp(command string) (temp, error) {
+	if InteractiveTemplate {
+		return interactiveTemp(command)
+	}
+	return getTemp(command)
+}
+
 func getTemp(command string) (temp, error) {
 	t, ok := templates[command]
 	if !ok {
@@ -86,9 +127,10 @@ func (t temp) copyContent() {
 	}
 }
 
-func (t temp) appendContent(target string) error {
+// checkAppendTarget makes sure target is a markdown file that content can be appended to
+func checkAppendTarget(target string) error {
 	if !strings.HasSuffix(target, ".md") {
-		return fmt.Errorf("'%s' must have an `.md` extension to append %s content.\n", target, t.Name)
+		return fmt.Errorf("'%s' must have an `.md` extension to append content.\n", target)
 	}
 
 	targetInfo, err := os.Stat(target)
@@ -99,6 +141,14 @@ func (t temp) appendContent(target string) error {
 		return fmt.Errorf("'%s' is a directory, please specify a markdown file.\n", target)
 	}
 
+	return nil
+}
+
+func (t temp) appendContent(target string) error {
+	if err := checkAppendTarget(target); err != nil {
+		return err
+	}
+
 	f, err := os.OpenFile(target, os.O_APPEND|os.O_WRONLY, 0600)
 	if err != nil {
 		return fmt.Errorf("Cannot open '%s'!\n%s\n", target, err)
@@ -179,7 +229,12 @@ Other Markdown:
   callout (co)
 Configuration:
   configyaml (cfy)
-  courseyaml (cry)`
+  courseyaml (cry)
+
+Add your own templates as markdown files in ~/.config/learn/templates or a
+repo's .learn/templates, with front matter giving a name, aliases, description
+and an id_placeholder (defaults to {{id}}) replaced with a generated id.
+Repo templates replace user templates, which replace the ones above.`
 
 const lessonTemplate = `# Title
 
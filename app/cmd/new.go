@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var newCmd = &cobra.Command{
+	Use:   "new [block|unit|lesson] <name>",
+	Short: "Create a block, unit or lesson from templates",
+	Long: `
+Scaffold curriculum without leaving the terminal or needing network access.
+
+  learn new block <name>            a block directory with a first unit, lesson and config.yaml
+  learn new unit <name>             a numbered unit directory with a first lesson
+  learn new lesson <unit>/<name>    a numbered lesson in an existing unit
+
+Units and lessons are created inside the block in the current directory, and
+added to its config.yaml when it has one. Lessons start from the lesson
+template, see 'learn md ls'.
+	`,
+}
+
+var newBlockCmd = &cobra.Command{
+	Use:   "block <name>",
+	Short: "Create a block directory with a first unit and lesson",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		created, err := newBlock(".", args[0], unitsDirOrDefault())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printCreated(created)
+		fmt.Printf("\nPreview it with `learn preview %s`\n", slugify(args[0]))
+	},
+}
+
+var newUnitCmd = &cobra.Command{
+	Use:   "unit <name>",
+	Short: "Create a numbered unit with a first lesson in the current block",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		created, err := newUnit(".", unitsDirOrDefault(), args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printCreated(created)
+	},
+}
+
+var newLessonCmd = &cobra.Command{
+	Use:   "lesson <unit>/<name>",
+	Short: "Create a numbered lesson in a unit of the current block",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		parts := strings.SplitN(args[0], "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fmt.Println("Usage: `learn new lesson <unit>/<name>` takes the unit directory and the name of the lesson, separated by a slash")
+			os.Exit(1)
+		}
+
+		created, err := newLesson(".", unitsDirOrDefault(), parts[0], parts[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printCreated(created)
+	},
+}
+
+// blockReadmeTemplate starts the README of a new block. It is filled in with the block name,
+// the units directory and the path of the first lesson
+const blockReadmeTemplate = `# %s
+
+Curriculum for Learn. Units live in the %s directory and are ordered by
+config.yaml.
+
+* Preview a lesson: learn preview %s
+* Preview the block: learn preview .
+* Add a unit: learn new unit <name>
+* Add a lesson: learn new lesson <unit>/<name>
+* Publish: learn publish
+`
+
+// nonSlugChars are replaced with dashes in file and directory names
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// numberPrefix matches the order prefix of a unit directory or lesson file
+var numberPrefix = regexp.MustCompile(`^([0-9]+)[-_.]`)
+
+// unitsDirOrDefault is the units directory given with --units, or units
+func unitsDirOrDefault() string {
+	if UnitsDirectory != "" {
+		return UnitsDirectory
+	}
+	return "units"
+}
+
+// printCreated lists the paths a new command created or changed
+func printCreated(paths []string) {
+	for _, p := range paths {
+		fmt.Println("  +", p)
+	}
+}
+
+// slugify turns a name into a lower case, dash separated file name
+func slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// nextNumber returns the zero padded number following the highest numbered entry in dir
+func nextNumber(dir string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	highest := 0
+	for _, entry := range entries {
+		if match := numberPrefix.FindStringSubmatch(entry.Name()); match != nil {
+			if n, _ := strconv.Atoi(match[1]); n > highest {
+				highest = n
+			}
+		}
+	}
+
+	return fmt.Sprintf("%02d", highest+1), nil
+}
+
+// lessonContent is the lesson template titled with name
+func lessonContent(name string) string {
+	return strings.Replace(lessonTemplate, "# Title", "# "+name, 1) + "\n"
+}
+
+// writeNewFile writes content to path, refusing to replace a file that already exists
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	return err
+}
+
+// newBlock creates a block named name in parent, with a first unit and lesson and a
+// config.yaml listing them. It returns the paths it created
+func newBlock(parent, name, unitsDir string) ([]string, error) {
+	blockDir := filepath.Join(parent, slugify(name))
+	if slugify(name) == "" {
+		return nil, fmt.Errorf("'%s' cannot be used as a block name", name)
+	}
+	if entries, err := ioutil.ReadDir(blockDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s already exists and is not empty", blockDir)
+	}
+
+	if err := os.MkdirAll(filepath.Join(blockDir, unitsDir), 0755); err != nil {
+		return nil, err
+	}
+
+	created, err := newUnit(blockDir, unitsDir, "Introduction")
+	if err != nil {
+		return nil, err
+	}
+
+	// The README points at the lesson newUnit just created, whatever it was named
+	firstLesson, _ := filepath.Rel(blockDir, created[len(created)-1])
+	readme := filepath.Join(blockDir, "README.md")
+	content := fmt.Sprintf(blockReadmeTemplate, name, filepath.ToSlash(filepath.Clean(unitsDir)), filepath.ToSlash(firstLesson))
+	if err := writeNewFile(readme, content); err != nil {
+		return nil, err
+	}
+
+	config, err := generateBlockConfig(blockDir, unitsDir)
+	if err != nil {
+		return nil, err
+	}
+	configPath := filepath.Join(blockDir, "config.yaml")
+	if err = writeBlockConfig(configPath, config); err != nil {
+		return nil, err
+	}
+
+	return append([]string{readme}, append(created, configPath)...), nil
+}
+
+// newUnit creates the next numbered unit named name in the block at blockDir, with a first
+// lesson of the same name, and adds it to the block's config.yaml if it has one
+func newUnit(blockDir, unitsDir, name string) ([]string, error) {
+	root := filepath.Join(blockDir, unitsDir)
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("No %s directory found, run this inside a block or create one with `learn new block <name>`", unitsDir)
+	}
+	if slugify(name) == "" {
+		return nil, fmt.Errorf("'%s' cannot be used as a unit name", name)
+	}
+
+	number, err := nextNumber(root)
+	if err != nil {
+		return nil, err
+	}
+	unitDir := filepath.Join(root, number+"-"+slugify(name))
+	if err = os.Mkdir(unitDir, 0755); err != nil {
+		return nil, err
+	}
+
+	lesson := name
+	if name == "Introduction" {
+		lesson = "Welcome"
+	}
+	created, err := newLesson(blockDir, unitsDir, filepath.Base(unitDir), lesson)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{unitDir}, created...), nil
+}
+
+// newLesson creates the next numbered lesson named name in unit, a unit directory with or
+// without its number, and adds it to the block's config.yaml if it has one
+func newLesson(blockDir, unitsDir, unit, name string) ([]string, error) {
+	root := filepath.Join(blockDir, unitsDir)
+	unitDir, err := findUnitDir(root, unit)
+	if err != nil {
+		return nil, err
+	}
+	if slugify(name) == "" {
+		return nil, fmt.Errorf("'%s' cannot be used as a lesson name", name)
+	}
+
+	number, err := nextNumber(unitDir)
+	if err != nil {
+		return nil, err
+	}
+	lessonPath := filepath.Join(unitDir, number+"-"+slugify(name)+".md")
+	if err = writeNewFile(lessonPath, lessonContent(name)); err != nil {
+		return nil, err
+	}
+	created := []string{lessonPath}
+
+	configPath := findConfigPath(blockDir)
+	if configPath == "" || filepath.Base(configPath) == "autoconfig.yaml" {
+		return created, nil
+	}
+
+	rel, _ := filepath.Rel(blockDir, lessonPath)
+	unitTitle := formattedName(filepath.Base(unitDir))
+	if meta, err := readUnitMeta(unitDir); err == nil && meta != nil && meta.Title != "" {
+		unitTitle = meta.Title
+	}
+	addition := blockConfig{Standards: []standard{{
+		Title:           unitTitle,
+		UID:             uuid.New().String(),
+		Description:     unitTitle,
+		SuccessCriteria: []string{"success criteria"},
+		ContentFiles: []contentFile{{
+			Type: "Lesson",
+			UID:  uuid.New().String(),
+			Path: "/" + filepath.ToSlash(rel),
+		}},
+	}}}
+
+	source, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return created, err
+	}
+	result, err := syncBlockConfig(source, addition, func(string) bool { return true })
+	if err != nil {
+		return created, fmt.Errorf("Created %s but could not add it to %s: %s", lessonPath, configPath, err)
+	}
+	if err = ioutil.WriteFile(configPath, result.config, 0644); err != nil {
+		return created, err
+	}
+
+	return append(created, configPath), nil
+}
+
+// findUnitDir finds the directory of unit under root, matching its full name or its name
+// without the number prefix
+func findUnitDir(root, unit string) (string, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("No %s directory found, run this inside a block or create one with `learn new block <name>`", filepath.Base(root))
+	}
+
+	units := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		units = append(units, entry.Name())
+		if entry.Name() == unit || numberPrefix.ReplaceAllString(entry.Name(), "") == slugify(unit) {
+			return filepath.Join(root, entry.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("No unit '%s' in %s, the units are: %s", unit, root, strings.Join(units, ", "))
+}
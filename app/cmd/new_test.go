@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_newBlockUnitAndLesson(t *testing.T) {
+	parent, err := ioutil.TempDir("", "learn-new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+
+	if _, err = newBlock(parent, "Intro to Go!", "units"); err != nil {
+		t.Fatalf("newBlock errored: %s", err)
+	}
+	block := filepath.Join(parent, "intro-to-go")
+
+	welcome, err := ioutil.ReadFile(filepath.Join(block, "units", "01-introduction", "01-welcome.md"))
+	if err != nil || !strings.HasPrefix(string(welcome), "# Welcome\n\n## Learning Objectives") {
+		t.Errorf("the first lesson should come from the lesson template, got %s %v", welcome, err)
+	}
+
+	readme, err := ioutil.ReadFile(filepath.Join(block, "README.md"))
+	if err != nil || !strings.Contains(string(readme), "learn preview units/01-introduction/01-welcome.md") {
+		t.Errorf("the README should point at the first lesson, got %s %v", readme, err)
+	}
+
+	if _, err = newUnit(block, "units", "Loops"); err != nil {
+		t.Fatalf("newUnit errored: %s", err)
+	}
+	if _, err = newLesson(block, "units", "loops", "While Loops"); err != nil {
+		t.Fatalf("newLesson errored: %s", err)
+	}
+	if _, err = newLesson(block, "units", "03-missing", "Nope"); err == nil || !strings.Contains(err.Error(), "01-introduction, 02-loops") {
+		t.Errorf("a missing unit should list the units, got %v", err)
+	}
+
+	config, err := readBlockConfig(filepath.Join(block, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Standards) != 2 || config.Standards[1].Title != "Loops" {
+		t.Fatalf("config.yaml should list both units, got %+v", config.Standards)
+	}
+	paths := []string{}
+	for _, cf := range config.Standards[1].ContentFiles {
+		paths = append(paths, cf.Path)
+	}
+	if strings.Join(paths, ",") != "/units/02-loops/01-loops.md,/units/02-loops/02-while-loops.md" {
+		t.Errorf("the Loops unit should list its lessons in order, got %v", paths)
+	}
+
+	problems, err := validateBlockConfig(block)
+	if err != nil || len(problems) > 0 {
+		t.Errorf("the scaffolded block should be valid, got %v %v", problems, err)
+	}
+
+	if _, err = newBlock(parent, "intro to go", "units"); err == nil {
+		t.Errorf("newBlock should not replace an existing block")
+	}
+}
+
+func Test_nextNumber(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if n, _ := nextNumber(dir); n != "01" {
+		t.Errorf("an empty directory should start at 01, got %s", n)
+	}
+	for _, name := range []string{"01-intro", "9-loops", "notes.md", "10_functions.md"} {
+		ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	if n, _ := nextNumber(dir); n != "11" {
+		t.Errorf("the next number should follow the highest, got %s", n)
+	}
+}
+
+func Test_newBlockReadmeUnitsDir(t *testing.T) {
+	parent := t.TempDir()
+	if _, err := newBlock(parent, "Loops", "curriculum"); err != nil {
+		t.Fatalf("newBlock errored: %s", err)
+	}
+
+	readme, err := ioutil.ReadFile(filepath.Join(parent, "loops", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(readme), "in the curriculum directory") || !strings.Contains(string(readme), "learn preview curriculum/01-introduction/01-welcome.md") {
+		t.Errorf("the README should use the units directory the block was created with, got:\n%s", readme)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "loops", "curriculum", "01-introduction", "01-welcome.md")); err != nil {
+		t.Errorf("the lesson the README points at should exist, %s", err)
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gSchool/glearn-cli/mdlinkparser"
+	"github.com/spf13/cobra"
+)
+
+var nbCmd = &cobra.Command{
+	Use:     "nb",
+	Aliases: []string{"notebook"},
+	Short:   "Work with Jupyter notebooks in your curriculum",
+	Long: `
+Helpers for the Jupyter notebooks in a block. For example:
+
+  learn nb strip lesson.ipynb
+	`,
+}
+
+var nbStripCmd = &cobra.Command{
+	Use:   "strip <file.ipynb>...",
+	Short: "Clear the outputs of code cells so notebooks upload small",
+	Long: `
+Removes the outputs and execution counts of every code cell in the notebooks
+given, rewriting them in place the way Jupyter saves them. Run it before a
+preview or a commit to keep plots and large results out of the upload.
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, path := range args {
+			before, after, err := stripNotebookFile(path)
+			if err != nil {
+				fmt.Printf("Could not strip %s: %s\n", path, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Stripped %s, %d bytes to %d bytes\n", path, before, after)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// notebookCell is the part of a notebook cell needed to find what it references
+type notebookCell struct {
+	CellType    string                       `json:"cell_type"`
+	Source      notebookSource               `json:"source"`
+	Attachments map[string]map[string]string `json:"attachments"`
+}
+
+// notebookSource is the source of a cell, which notebooks store as a string or a list of lines
+type notebookSource string
+
+func (s *notebookSource) UnmarshalJSON(b []byte) error {
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err == nil {
+		*s = notebookSource(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(b, &text); err != nil {
+		return err
+	}
+	*s = notebookSource(text)
+	return nil
+}
+
+// readNotebookCells reads the cells of a version 4 notebook
+func readNotebookCells(source []byte) ([]notebookCell, error) {
+	var nb struct {
+		NBFormat int            `json:"nbformat"`
+		Cells    []notebookCell `json:"cells"`
+	}
+	if err := json.Unmarshal(source, &nb); err != nil {
+		return nil, fmt.Errorf("the notebook is not valid JSON, %s", err)
+	}
+	if nb.NBFormat < 4 {
+		return nil, fmt.Errorf("only version 4 notebooks are supported, this is version %d", nb.NBFormat)
+	}
+	return nb.Cells, nil
+}
+
+// quotedPath matches a quoted string in code that looks like a file name with an extension
+var quotedPath = regexp.MustCompile(`["']([^"'\s]+\.[A-Za-z0-9]+)["']`)
+
+// notebookLinkPaths returns the local files the notebook at path uses: the images and links in
+// its markdown cells, and the files named in its code cells that exist next to the notebook,
+// like a dataset opened with pandas. Attachments are part of the notebook and need no file, a
+// reference to one that is missing is returned as a warning
+func notebookLinkPaths(path string, source []byte) ([]string, []string, error) {
+	cells, err := readNotebookCells(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := []string{}
+	warnings := []string{}
+	seen := map[string]struct{}{}
+	add := func(p string) {
+		if _, ok := seen[p]; !ok && p != "" {
+			seen[p] = struct{}{}
+			paths = append(paths, p)
+		}
+	}
+
+	for i, cell := range cells {
+		switch cell.CellType {
+		case "markdown":
+			for _, ref := range mdlinkparser.Parse([]byte(cell.Source)) {
+				if name := strings.TrimPrefix(ref.Destination, "attachment:"); name != ref.Destination {
+					if _, ok := cell.Attachments[name]; !ok {
+						warnings = append(warnings, fmt.Sprintf("cell %d of %s uses the attachment %s, which it does not have", i+1, filepath.Base(path), name))
+					}
+					continue
+				}
+				if ref.IsLocal() {
+					add(ref.Path())
+				}
+			}
+		case "code":
+			for _, match := range quotedPath.FindAllStringSubmatch(string(cell.Source), -1) {
+				ref := mdlinkparser.Reference{Destination: match[1]}
+				if !ref.IsLocal() {
+					continue
+				}
+				if info, err := os.Stat(filepath.Join(filepath.Dir(path), filepath.FromSlash(match[1]))); err == nil && !info.IsDir() {
+					add(match[1])
+				}
+			}
+		}
+	}
+
+	return paths, warnings, nil
+}
+
+// stripNotebook clears the outputs and execution counts of the code cells in a notebook,
+// keeping everything else. It is written like Jupyter writes notebooks, with sorted keys and
+// an indent of one space, so stripping a stripped notebook changes nothing
+func stripNotebook(source []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(source))
+	decoder.UseNumber()
+	var nb map[string]interface{}
+	if err := decoder.Decode(&nb); err != nil {
+		return nil, fmt.Errorf("the notebook is not valid JSON, %s", err)
+	}
+
+	cells, ok := nb["cells"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("only version 4 notebooks, which have cells, are supported")
+	}
+	for _, c := range cells {
+		cell, ok := c.(map[string]interface{})
+		if !ok || cell["cell_type"] != "code" {
+			continue
+		}
+		cell["outputs"] = []interface{}{}
+		cell["execution_count"] = nil
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", " ")
+	if err := encoder.Encode(nb); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// stripNotebookFile strips the notebook at path in place, returning its size before and after
+func stripNotebookFile(path string) (int, int, error) {
+	if filepath.Ext(path) != ".ipynb" {
+		return 0, 0, fmt.Errorf("it is not a .ipynb notebook")
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	stripped, err := stripNotebook(source)
+	if err != nil {
+		return 0, 0, err
+	}
+	if bytes.Equal(source, stripped) {
+		return len(source), len(stripped), nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(source), len(stripped), ioutil.WriteFile(path, stripped, info.Mode())
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testNotebook is saved the way Jupyter saves notebooks, with sorted keys and one space indents
+const testNotebook = `{
+ "cells": [
+  {
+   "attachments": {
+    "pasted.png": {
+     "image/png": "iVBORw0KGgo="
+    }
+   },
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": [
+    "# Plots & <data>\n",
+    "\n",
+    "![chart](images/chart.png) ![pasted](attachment:pasted.png) ![lost](attachment:lost.png)\n",
+    "\n",
+    "<img src=\"images/diagram.svg\"> [docs](https://example.com) [next](next.md)\n",
+    "\n",
+    "` + "```" + `\n",
+    "![not in code](code.png)\n",
+    "` + "```" + `"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 3,
+   "metadata": {},
+   "outputs": [
+    {
+     "name": "stdout",
+     "output_type": "stream",
+     "text": [
+      "1.5\n"
+     ]
+    }
+   ],
+   "source": "import pandas as pd\ndf = pd.read_csv('data/people.csv')\nother = open(\"missing.txt\")\nprint(1.5)"
+  }
+ ],
+ "metadata": {
+  "kernelspec": {
+   "display_name": "Python 3",
+   "language": "python",
+   "name": "python3"
+  }
+ },
+ "nbformat": 4,
+ "nbformat_minor": 4
+}
+`
+
+func Test_notebookLinkPaths(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"lesson.ipynb":       testNotebook,
+		"data/people.csv":    "name\nAnn\n",
+		"images/chart.png":   "png",
+		"images/diagram.svg": "svg",
+	})
+	path := filepath.Join(dir, "lesson.ipynb")
+
+	paths, warnings, err := notebookLinkPaths(path, []byte(testNotebook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"images/chart.png", "images/diagram.svg", "next.md", "data/people.csv"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("notebookLinkPaths expected %v but got %v", want, paths)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "attachment lost.png") {
+		t.Errorf("a missing attachment should be a warning, got %v", warnings)
+	}
+
+	if _, _, err := notebookLinkPaths(path, []byte(`{"nbformat": 3, "worksheets": []}`)); err == nil {
+		t.Errorf("version 3 notebooks should be an error")
+	}
+}
+
+func Test_collectPreviewAssetsNotebook(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"lesson.md":          "# Lesson\n\n[notebook](lesson.ipynb)\n",
+		"lesson.ipynb":       testNotebook,
+		"data/people.csv":    "name\nAnn\n",
+		"images/chart.png":   "png",
+		"images/diagram.svg": "svg",
+		"next.md":            "# Next\n",
+	})
+
+	assets, warnings, err := collectPreviewAssets(filepath.Join(dir, "lesson.md"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(assetPaths(assets), ",")
+	if got != "lesson.ipynb,images/chart.png,images/diagram.svg,next.md,data/people.csv" {
+		t.Errorf("the files a linked notebook uses should be included, got %s", got)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("the missing attachment should be warned about, got %v", warnings)
+	}
+}
+
+func Test_stripNotebook(t *testing.T) {
+	stripped, err := stripNotebook([]byte(testNotebook))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Replace(testNotebook, `   "execution_count": 3,`, `   "execution_count": null,`, 1)
+	start := strings.Index(want, `   "outputs": [`)
+	end := strings.Index(want, `   "source": "import`)
+	want = want[:start] + "   \"outputs\": [],\n" + want[end:]
+	if string(stripped) != want {
+		t.Errorf("stripNotebook should only clear outputs and execution counts, expected:\n%s\ngot:\n%s", want, string(stripped))
+	}
+
+	again, err := stripNotebook(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(stripped) {
+		t.Errorf("stripping a stripped notebook should change nothing")
+	}
+
+	if _, err := stripNotebook([]byte("not json")); err == nil {
+		t.Errorf("stripNotebook should fail for invalid JSON")
+	}
+}
+
+func Test_stripNotebookFile(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{"lesson.ipynb": testNotebook, "lesson.md": "# Lesson\n"})
+
+	before, after, err := stripNotebookFile(filepath.Join(dir, "lesson.ipynb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != len(testNotebook) || after >= before {
+		t.Errorf("stripNotebookFile should return the sizes before and after, got %d and %d", before, after)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "lesson.ipynb"))
+	if len(b) != after || strings.Contains(string(b), "output_type") {
+		t.Errorf("stripNotebookFile should rewrite the notebook in place, got:\n%s", string(b))
+	}
+
+	if _, _, err := stripNotebookFile(filepath.Join(dir, "lesson.md")); err == nil {
+		t.Errorf("stripNotebookFile should refuse files that aren't notebooks")
+	}
+}
+
+func Test_createAutoConfigNotebookPreview(t *testing.T) {
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
+	os.MkdirAll(staging, 0755)
+	ioutil.WriteFile(filepath.Join(staging, "lesson.ipynb"), []byte(testNotebook), 0644)
+
+	if err := createAutoConfig(staging, "."); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(staging, "autoconfig.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Path: /lesson.ipynb") {
+		t.Errorf("a notebook previewed on its own should be a content file, got:\n%s", string(b))
+	}
+}
+
+func Test_collectPreviewAssetsInvalidNotebook(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"lesson.md":    "# Lesson\n\n[notebook](broken.ipynb)\n",
+		"broken.ipynb": "not json",
+	})
+
+	assets, warnings, err := collectPreviewAssets(filepath.Join(dir, "lesson.md"), 0)
+	if err != nil {
+		t.Fatalf("a linked notebook that can't be read should not stop the preview, got %s", err)
+	}
+	if len(assets) != 1 || len(warnings) != 1 || !strings.Contains(warnings[0], "the links in broken.ipynb were not followed") {
+		t.Errorf("the notebook should be packed with a warning, got %+v %v", assets, warnings)
+	}
+
+	if _, _, err := collectPreviewAssets(filepath.Join(dir, "broken.ipynb"), 0); err == nil {
+		t.Errorf("previewing a notebook that can't be read should be an error")
+	}
+}
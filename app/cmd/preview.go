@@ -30,16 +30,57 @@ import (
 	proxyReader "github.com/gSchool/glearn-cli/proxy_reader"
 )
 
-// tmpZipFile is used throughout as the temporary zip file target location.
+// tmpZipFile is the name of the zip file a preview uploads, in its workspace and on s3.
 const tmpZipFile string = "preview-curriculum.zip"
 
-// tmpSingleFileDir is used throughout as the temporary single file directory location. This
-// is the name of the tmp dir we build when needing to attach relative links.
+// maxDataPathDepth is how many parents of a file outside of a block are searched for its .sql data paths.
+const maxDataPathDepth = 5
+
+// tmpSingleFileDir is the name of the directory in a preview's workspace that a single file is
+// staged in with the files it links to.
 const tmpSingleFileDir string = "single-file-upload"
 
+// previewWorkspace is a temporary directory holding everything a preview writes, its zip file
+// and single file staging directory, so nothing is left next to the content being previewed
+// and previews can run side by side
+type previewWorkspace struct {
+	dir string
+}
+
+// newPreviewWorkspace creates a workspace in the system's temporary directory
+func newPreviewWorkspace() (*previewWorkspace, error) {
+	dir, err := os.MkdirTemp("", "learn-preview-")
+	if err != nil {
+		return nil, fmt.Errorf("Could not create a temporary directory for the preview. Err: %v", err)
+	}
+	return &previewWorkspace{dir: dir}, nil
+}
+
+// zipPath is where the compressed content is written before uploading
+func (w *previewWorkspace) zipPath() string {
+	return filepath.Join(w.dir, tmpZipFile)
+}
+
+// stagingDir is where a single file preview is staged with the files it links to
+func (w *previewWorkspace) stagingDir() string {
+	return filepath.Join(w.dir, tmpSingleFileDir)
+}
+
+// remove deletes the workspace and everything in it
+func (w *previewWorkspace) remove() {
+	if err := os.RemoveAll(w.dir); err != nil {
+		fmt.Println("Sorry, we had trouble cleaning up the temporary files created for curriculum preview")
+	}
+}
+
+// isStagedSingleFile is true when target is the staging directory of a single file preview
+func isStagedSingleFile(target string) bool {
+	return filepath.Base(filepath.Clean(target)) == tmpSingleFileDir
+}
+
 // previewCmd is executed when the `learn preview` command is used. Preview's concerns:
-// 1. Compress directory/file into target location.
-// 2. Defer cleaning up the file after command is finished.
+// 1. Compress directory/file into a temporary workspace.
+// 2. Remove the workspace once the content is uploaded.
 // 3. Create a checksum for the zip file.
 // 4. Upload the zip file to s3.
 // 5. Notify learn that new content is available for building.
@@ -51,8 +92,32 @@ var previewCmd = &cobra.Command{
 The preview command takes a path to either a directory or a single file and
 uploads the content to Learn through the Learn API. Learn will build the
 preview and return/open the preview URL when it is complete.
+
+Without a config.yaml an autoconfig.yaml is generated from the file structure,
+with units and lessons in natural order (9-intro before 10-loops). Lessons can
+set title, type, visibility, autoscore and time_limit in YAML front matter, a
+_unit.yaml in a unit directory sets its Title, Description and SuccessCriteria,
+and a .learnignore lists patterns to leave out besides names starting with __.
+UIDs are kept in autoconfig.lock, commit it so renamed files and units keep
+their UIDs and the student progress attached to them.
+
+A single markdown file or notebook is previewed with every local file it links
+to, and the files those link to in turn, like other lessons, images, notebooks
+and datasets. Notebooks include the images in their markdown cells and the files
+their code cells name that are next to them. Use ` + "`learn nb strip`" + ` first to
+leave the outputs of code cells out of the upload.
+--depth limits how many links away files are included. What is packed is listed
+before uploading, with a warning for file types previews don't usually include.
+Files keep their place in the block, and links leading out of it are left out.
+A file with no config or .git above it is packed with the files it links to in
+its own directory, and the .sql data_path files of its challenges are looked
+for in its parents.
+Everything is staged and zipped in a temporary directory, never the current one.
+
+Use --all <directory> instead of a path to preview every block repository found
+under a directory, several at a time, and print a summary of the results.
 	`,
-	Args: cobra.MinimumNArgs(1),
+	Args: cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Start benchmarking the total time spent in preview cmd
 		startOfCmd := time.Now()
@@ -63,331 +128,346 @@ preview and return/open the preview URL when it is complete.
 			previewCmdError(setAPITokenMessage)
 		}
 
+		if PreviewAll != "" {
+			if len(args) != 0 {
+				previewCmdError("Usage: `learn preview --all <directory>` takes no other arguments")
+				return
+			}
+			previewAll(PreviewAll)
+			return
+		}
+
 		// Takes one argument which is the filepath to the directory you want zipped/previewed
 		if len(args) != 1 {
 			previewCmdError("Usage: `learn preview` takes just one argument")
 			return
 		}
 
-		// Set target file path
-		target := args[0]
-
-		// Get os.FileInfo from call to os.Stat so we can see if it is a single file or directory
-		fileInfo, err := os.Stat(target)
+		workspace, err := newPreviewWorkspace()
 		if err != nil {
-			previewCmdError(fmt.Sprintf("Failed to get stats on file. Err: %v", err))
+			previewCmdError(err.Error())
 			return
 		}
-		isDirectory := fileInfo.IsDir()
-		includeLinks := !isDirectory && !FileOnly // not a dir, false
 
-		if !isDirectory && (!strings.HasSuffix(target, ".md") && !strings.HasSuffix(target, ".ipynb")) {
-			previewCmdError("The preview file that you chose is not able to be rendered as a single file preview in learn")
+		res, err := previewContent(args[0], previewOptions{
+			unitsDir:  UnitsDirectory,
+			fileOnly:  FileOnly,
+			depth:     PreviewDepth,
+			workspace: workspace,
+		})
+		// Nothing in the workspace is needed once the content is uploaded
+		workspace.remove()
+		if err != nil {
+			previewCmdError(err.Error())
 			return
 		}
 
-		// If it is a single file preview we need to parse the target for any md link tags
-		// linking to local files. If there are any, add them to the target
-		var singleFileLinkPaths []string
-		var dataPaths []string
-		if includeLinks {
-			if filepath.Ext(target) == ".md" {
-				dataPaths, err = collectDataPaths(target)
-				singleFileLinkPaths, err = collectLinkPaths(target)
-				if err != nil {
-					previewCmdError(fmt.Sprintf("Failed to attach local images for single file preview for: (%s). Err: %v", target, err))
-					return
-				}
-			} else {
-				previewCmdError("Sorry we only support markdown files for single file previews")
-				return
-			}
+		res.bench.TotalCmdTime = time.Since(startOfCmd).Milliseconds()
+
+		if OpenPreview {
+			exec.Command("bash", "-c", fmt.Sprintf("open %s", res.url)).Output()
 		}
-		fileContainsLinks := len(singleFileLinkPaths) > 0
-		fileContainsSQLPaths := len(dataPaths) > 0
 
-		// variable holding whether or not source is a dir OR when it is a single file preview
-		// AND singleFileLinkPaths is > 0 that means it is now a dir again (tmp one we created)
-		isSingleFilePreviewWithLinks := !isDirectory && (fileContainsLinks || fileContainsSQLPaths)
-		isDirectory = isDirectory || (!isDirectory && fileContainsLinks)
+		err = learn.API.SendMetadataToLearn(&learn.CLIBenchmarkPayload{
+			CLIBenchmark: res.bench,
+		})
+		if err != nil {
+			learn.API.NotifySlack(err)
+			os.Exit(1)
+		}
+	},
+}
 
-		var alternateTarget string
-		if fileContainsLinks {
-			alternateTarget, err = createNewTarget(target, singleFileLinkPaths)
-			if err != nil {
-				previewCmdError(fmt.Sprintf("Failed build tmp files around single file preview for: (%s). Err: %v", target, err))
-				return
-			}
+// previewOptions configure a single run of the preview pipeline
+type previewOptions struct {
+	unitsDir string
+	fileOnly bool
+	// workspace is where the zip file is written and a single file is staged
+	workspace *previewWorkspace
+	// depth is how many links away from a single file preview linked files are included, 0 for all
+	depth int
+	// quiet turns off spinners, progress bars and messages, used when previewing many blocks at once
+	quiet bool
+}
+
+// previewResult is the outcome of a successful preview
+type previewResult struct {
+	url      string
+	warnings []string
+	bench    *learn.CLIBenchmark
+}
+
+// previewContent runs the preview pipeline for a directory or single file target:
+// 1. Stage a single file with its local links and data paths in the workspace.
+// 2. Find or generate a config.
+// 3. Compress the target into the workspace and create a checksum for it.
+// 4. Upload the zip file to s3, with a progress bar unless quiet.
+// 5. Notify learn that new content is available for building and wait for the build.
+// The caller is responsible for removing the workspace.
+func previewContent(target string, opts previewOptions) (*previewResult, error) {
+	logf := func(format string, a ...interface{}) {
+		if !opts.quiet {
+			fmt.Printf(format, a...)
 		}
+	}
 
-		if fileContainsSQLPaths {
-			alternateTarget, err = createNewTarget(target, dataPaths)
+	// Get os.FileInfo from call to os.Stat so we can see if it is a single file or directory
+	fileInfo, err := os.Stat(target)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get stats on file. Err: %v", err)
+	}
+	isDirectory := fileInfo.IsDir()
+	includeLinks := !isDirectory && !opts.fileOnly // not a dir, false
+
+	if !isDirectory && (!strings.HasSuffix(target, ".md") && !strings.HasSuffix(target, ".ipynb")) {
+		return nil, errors.New("The preview file that you chose is not able to be rendered as a single file preview in learn")
+	}
+
+	// If it is a single file preview we need to parse the target for any md link tags
+	// linking to local files. If there are any, add them to the target
+	var singleFileLinkPaths []string
+	var dataPaths []string
+	if includeLinks {
+		if filepath.Ext(target) == ".md" {
+			dataPaths, err = collectDataPaths(target)
 			if err != nil {
-				previewCmdError(fmt.Sprintf("Failed build tmp files around single file preview for: (%s). Err: %v", target, err))
-				return
+				return nil, fmt.Errorf("Failed to attach data paths for single file preview for: (%s). Err: %v", target, err)
 			}
 		}
-		if alternateTarget != "" {
-			target = alternateTarget
+		assets, warnings, err := collectPreviewAssets(target, opts.depth)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to attach local images for single file preview for: (%s). Err: %v", target, err)
 		}
-
-		// Detect config file
-		if fileContainsLinks || fileContainsSQLPaths || isDirectory {
-			_, err = doesConfigExistOrCreate(target, UnitsDirectory, isSingleFilePreviewWithLinks)
-			if err != nil {
-				previewCmdError(fmt.Sprintf("Failed to find or create a config file for: (%s). Err: %v", target, err))
-				return
-			}
+		if !opts.quiet && (len(assets) > 0 || len(warnings) > 0) {
+			printPreviewManifest(os.Stdout, target, assets, warnings)
 		}
+		singleFileLinkPaths = assetPaths(assets)
+	}
+	fileContainsLinks := len(singleFileLinkPaths) > 0
+	fileContainsSQLPaths := len(dataPaths) > 0
 
-		// Start a processing spinner that runs until a user's content is compressed
-		fmt.Println("Compressing your content...")
-		s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
-		s.Color("blue")
-		s.Start()
-
-		// Start benchmark for compressDirectory
-		startOfCompression := time.Now()
+	// variable holding whether or not source is a dir OR when it is a single file preview
+	// AND singleFileLinkPaths is > 0 that means it is now a dir again (tmp one we created)
+	isSingleFilePreviewWithLinks := !isDirectory && (fileContainsLinks || fileContainsSQLPaths)
+	isDirectory = isDirectory || (!isDirectory && fileContainsLinks)
 
-		// Compress directory, output -> tmpZipFile
-		err = compressDirectory(target, tmpZipFile)
+	if isSingleFilePreviewWithLinks {
+		staged, err := createNewTarget(target, opts.workspace.stagingDir(), singleFileLinkPaths, dataPaths)
 		if err != nil {
-			previewCmdError(fmt.Sprintf("Failed to compress provided directory (%s). Err: %v", target, err))
-			return
+			return nil, fmt.Errorf("Failed build tmp files around single file preview for: (%s). Err: %v", target, err)
 		}
+		target = staged
+	}
 
-		// Add benchmark in milliseconds for compressDirectory
-		bench := &learn.CLIBenchmark{
-			Compression: time.Since(startOfCompression).Milliseconds(),
-			CmdName:     "preview",
+	// Detect config file
+	if fileContainsLinks || fileContainsSQLPaths || isDirectory {
+		_, err = doesConfigExistOrCreate(target, opts.unitsDir, isSingleFilePreviewWithLinks || opts.quiet)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find or create a config file for: (%s). Err: %v", target, err)
 		}
+	}
 
-		// Stop the processing spinner
+	// Start a processing spinner that runs until a user's content is compressed
+	logf("Compressing your content...\n")
+	s := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
+	s.Color("blue")
+	if !opts.quiet {
+		s.Start()
+	}
+
+	// Start benchmark for compressDirectory
+	startOfCompression := time.Now()
+
+	// Compress directory, output -> the workspace's zip file
+	zipPath := opts.workspace.zipPath()
+	err = compressDirectory(target, zipPath, isSingleFilePreviewWithLinks)
+	if err != nil {
 		s.Stop()
-		printlnGreen("√")
+		return nil, fmt.Errorf("Failed to compress provided directory (%s). Err: %v", target, err)
+	}
 
-		// Removes artifacts on user's machine
-		defer removeArtifacts()
+	// Add benchmark in milliseconds for compressDirectory
+	bench := &learn.CLIBenchmark{
+		Compression: time.Since(startOfCompression).Milliseconds(),
+		CmdName:     "preview",
+	}
 
-		// Open file so we can get a checksum as well as send to s3
-		f, err := os.Open(tmpZipFile)
-		if err != nil {
-			previewCmdError(fmt.Sprintf("Failed opening file (%q). Err: %v", tmpZipFile, err))
-			return
-		}
-		defer f.Close()
+	// Stop the processing spinner
+	if !opts.quiet {
+		s.Stop()
+		printlnGreen("√")
+	}
 
-		// Create checksum of files in directory
-		checksum, err := createChecksumFromZip(f)
-		if err != nil {
-			previewCmdError(fmt.Sprintf("Failed to create a checksum for compressed file. Err: %v", err))
-			return
-		}
+	// Open file so we can get a checksum as well as send to s3
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed opening file (%q). Err: %v", zipPath, err)
+	}
+	defer f.Close()
 
-		// Start benchmark for uploadToS3
-		startOfUploadToS3 := time.Now()
+	// Create checksum of files in directory
+	checksum, err := createChecksumFromZip(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create a checksum for compressed file. Err: %v", err)
+	}
 
-		// Send compressed zip file to s3
-		bucketKey, err := uploadToS3(f, checksum, learn.API.Credentials)
-		if err != nil {
-			previewCmdError(fmt.Sprintf("Failed to upload zip file to s3. Err: %v", err))
-			return
-		}
+	// Start benchmark for uploadToS3
+	startOfUploadToS3 := time.Now()
 
-		// Add benchmark in milliseconds for uploadToS3
-		bench.UploadToS3 = time.Since(startOfUploadToS3).Milliseconds()
+	// Send compressed zip file to s3
+	bucketKey, err := uploadToS3(f, checksum, learn.API.Credentials, !opts.quiet)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to upload zip file to s3. Err: %v", err)
+	}
 
-		fmt.Println("\nBuilding preview...")
+	// Add benchmark in milliseconds for uploadToS3
+	bench.UploadToS3 = time.Since(startOfUploadToS3).Milliseconds()
 
-		// Start a processing spinner that runs until Learn is finsihed building the preview
-		s = spinner.New(spinner.CharSets[32], 100*time.Millisecond)
-		s.Color("blue")
+	logf("\nBuilding preview...\n")
+
+	// Start a processing spinner that runs until Learn is finsihed building the preview
+	s = spinner.New(spinner.CharSets[32], 100*time.Millisecond)
+	s.Color("blue")
+	if !opts.quiet {
 		s.Start()
+	}
+	defer s.Stop()
 
-		// Start benchmark for BuildReleaseFromS3 & PollForBuildResponse (Learn build stage)
-		startBuildAndPollRelease := time.Now()
+	// Start benchmark for BuildReleaseFromS3 & PollForBuildResponse (Learn build stage)
+	startBuildAndPollRelease := time.Now()
 
-		// Let Learn know there is new preview content on s3, where it is, and to build it
-		res, err := learn.API.BuildReleaseFromS3(bucketKey, (isDirectory || fileContainsSQLPaths))
-		if err != nil {
-			previewCmdError(fmt.Sprintf("Failed to build new preview content in learn. Err: %v", err))
-			return
-		}
+	// Let Learn know there is new preview content on s3, where it is, and to build it
+	res, err := learn.API.BuildReleaseFromS3(bucketKey, (isDirectory || fileContainsSQLPaths))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build new preview content in learn. Err: %v", err)
+	}
 
-		// If content is a directory, rewrite the res from polling for build response. Directories
-		// can take much longer to build, however single files build instantly so we do not need to
-		// poll for them because the call to BuildReleaseFromS3 will get a preview_url right away
-		if isDirectory || fileContainsSQLPaths {
-			var attempts uint8 = 30
-			res, err = learn.API.PollForBuildResponse(res.ReleaseID, &attempts)
-			if err != nil {
-				previewCmdError(fmt.Sprintf("Failed to poll Learn for your new preview build. Err: %v", err))
-				return
-			}
+	// If content is a directory, rewrite the res from polling for build response. Directories
+	// can take much longer to build, however single files build instantly so we do not need to
+	// poll for them because the call to BuildReleaseFromS3 will get a preview_url right away
+	if isDirectory || fileContainsSQLPaths {
+		var attempts uint8 = 30
+		res, err = learn.API.PollForBuildResponse(res.ReleaseID, &attempts)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to poll Learn for your new preview build. Err: %v", err)
 		}
+	}
 
-		// Add benchmark in milliseconds for the Learn build stage and total time in preview cmd
-		bench.LearnBuild = time.Since(startBuildAndPollRelease).Milliseconds()
-		bench.TotalCmdTime = time.Since(startOfCmd).Milliseconds()
+	// Add benchmark in milliseconds for the Learn build stage
+	bench.LearnBuild = time.Since(startBuildAndPollRelease).Milliseconds()
 
+	if !opts.quiet {
 		// Set final message for dislpay
 		s.FinalMSG = fmt.Sprintf("Sucessfully uploaded your preview! You can find your content at: %s\n", res.PreviewURL)
 
 		// Stop the processing spinner
 		s.Stop()
 		printlnGreen("√")
+	}
 
-		if OpenPreview {
-			exec.Command("bash", "-c", fmt.Sprintf("open %s", res.PreviewURL)).Output()
-		}
-
-		err = learn.API.SendMetadataToLearn(&learn.CLIBenchmarkPayload{
-			CLIBenchmark: bench,
-		})
-		if err != nil {
-			removeArtifacts()
-			learn.API.NotifySlack(err)
-			os.Exit(1)
-		}
-	},
+	return &previewResult{url: res.PreviewURL, warnings: res.SyncWarnings, bench: bench}, nil
 }
 
-// createNewTarget will set up and create everything needed for single file previews if they are needed.
-// Returns a string representing the source name which if not single file tmp dir is needed, will return the
-// original
-func createNewTarget(target string, singleFileLinkPaths []string) (string, error) {
-	// Tmp dir so we can build out a new dir with the correct links in their correct
-	// paths based on relative link paths supplied in the single markdown file
-	newSrcPath := tmpSingleFileDir
-
-	// Get the name of the single target file
-	srcArray := strings.Split(target, "/")
-	srcMDFile := srcArray[len(srcArray)-1]
-	substringPaths := []string{}
-
-	for _, imgPath := range singleFileLinkPaths {
-		if !strings.HasPrefix(imgPath, "/") {
-			imgPath = fmt.Sprintf("/%s", imgPath)
-		}
-
-		// Ex. images/something-else/my_neat_image.png -> ["images", "something-else", "my_neat_image.png"]
-		pathArray := []string{}
-		var containsPeriodPeriod bool
-		for _, dir := range strings.Split(imgPath, "/") {
-			if dir != ".." { // sanitize any .. so we don't have to worry about nexted things
-				containsPeriodPeriod = true
-				pathArray = append(pathArray, dir)
-			}
-		}
-		// We need to modify the actual markdown file so it no longer has `..` in links, since we're putting
-		// everything in the newSrcPath
-		if containsPeriodPeriod {
-			substringPaths = append(substringPaths, imgPath)
-		}
-
-		imageName := pathArray[len(pathArray)-1] // -> "my_neat_image.png"
+// createNewTarget stages a single file preview in stagingDir with the files it links to and the
+// data files its challenges use. Links are relative to the target's directory, or to the root
+// when they start with /, and keep their place relative to the root so they work without being
+// rewritten. The root is the block holding the target, or the target's own directory when it
+// isn't in a block, and a link leading out of it is an error rather than being staged
+// elsewhere. Data files are staged at their data path. Returns stagingDir, or target when there
+// is nothing to stage
+func createNewTarget(target, stagingDir string, paths, dataPaths []string) (string, error) {
+	if len(paths) == 0 && len(dataPaths) == 0 {
+		return target, nil
+	}
 
-		// create an linkDirs var and depending on how long the image file path is, update it to include
-		// everything up to the image itself
-		var linkDirs string
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	targetDir := filepath.Dir(abs)
+	root, inBlock := lookupBlockRoot(targetDir)
+	scope := "the block at " + root
+	if !inBlock {
+		root, scope = targetDir, "its directory "+targetDir
+	}
 
-		if len(pathArray) == 1 {
-			linkDirs = ""
-		} else if len(pathArray) == 2 {
-			linkDirs = pathArray[0]
-		} else {
-			// Collect everything up until the image name (last item) and join it back together
-			// This gives us the name of the directory(ies) to make to put the image in
-			linkDirs = strings.Join(pathArray[:len(pathArray)-1], "/")
+	stage := func(src, rel string) error {
+		dst := filepath.Join(stagingDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), os.FileMode(0777)); err != nil {
+			return err
 		}
+		return Copy(src, dst)
+	}
 
-		// Create appropriate directory for each link using the linkDirs
-		err := os.MkdirAll(newSrcPath+linkDirs, os.FileMode(0777))
+	for _, p := range paths {
+		src := filepath.Join(targetDir, filepath.FromSlash(p))
+		if strings.HasPrefix(p, "/") {
+			src = filepath.Join(root, filepath.FromSlash(p))
+		}
+		if !withinDir(root, src) {
+			return "", fmt.Errorf("%s links to %s, which is outside of %s", filepath.Base(target), p, scope)
+		}
+		if _, err := os.Stat(src); err != nil {
+			log.Printf("Link not found with path '%s'\n", p)
+			continue
+		}
+		rel, err := filepath.Rel(root, src)
 		if err != nil {
 			return "", err
 		}
+		if err := stage(src, rel); err != nil {
+			return "", err
+		}
+	}
 
-		// Get "oneDirBackFromTarget" because target will be an .md file with relative
-		// links so we need to go one back from "target" so things aren't trying
-		// to be nested in the .md file itself
-		targetArray := strings.Split(target, "/")
-		sourceLinkPath := trimFirstRune(imgPath)
-		if len(targetArray[:len(targetArray)-1]) != 0 && !strings.HasSuffix(sourceLinkPath, ".sql") {
-			oneDirBackFromTarget := strings.Join(targetArray[:len(targetArray)-1], "/")
-			sourceLinkPath = oneDirBackFromTarget + imgPath
-		}
-
-		if _, err := os.Stat(sourceLinkPath); os.IsNotExist(err) {
-			if strings.HasSuffix(sourceLinkPath, ".sql") {
-				useThisPath := ""
-				parent := "../" + sourceLinkPath
-				for i := 1; i <= 5; i++ {
-					_, parentExists := os.Stat(parent)
-					if parentExists == nil {
-						useThisPath = parent
-						break
-					} else {
-						parent = "../" + parent
-					}
-				}
-
-				if useThisPath != "" {
-					err = Copy(useThisPath, newSrcPath+linkDirs+"/"+imageName)
-					if err != nil {
-						return "", err
-					}
-				}
-			} else {
-				log.Printf("Link not found with path '%s'\n", sourceLinkPath)
-			}
-		} else {
-			// Copy the actual image into our new temp directory in it's appropriate spot
-			err = Copy(sourceLinkPath, newSrcPath+linkDirs+"/"+imageName)
-			if err != nil {
-				return "", err
-			}
+	for _, p := range dataPaths {
+		rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(p, "/")))
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%s uses the data path %s, which leads out of %s", filepath.Base(target), p, scope)
+		}
+		src := findDataPath(targetDir, root, inBlock, rel)
+		if src == "" {
+			log.Printf("Link not found with path '%s'\n", p)
+			continue
+		}
+		if err := stage(src, rel); err != nil {
+			return "", err
 		}
 	}
 
-	// Copy original single markdown file into the base of our new tmp dir
-	newTarget := newSrcPath + "/" + srcMDFile
-	err := Copy(target, newTarget)
+	rel, err := filepath.Rel(root, abs)
 	if err != nil {
 		return "", err
 	}
+	if err := stage(abs, rel); err != nil {
+		return "", err
+	}
+	return stagingDir, nil
+}
 
-	if len(singleFileLinkPaths) > 0 {
-		if len(substringPaths) > 0 {
-			// open contents of new target
-			b, err := ioutil.ReadFile(newTarget)
-			if err != nil {
-				return "", fmt.Errorf("Could not read copied target file: %s", err)
-			}
-			contents := string(b)
-			for _, pathToSub := range substringPaths {
-				if strings.HasPrefix(pathToSub, "/") { // undo the add of a slash we did at the beginning
-					pathToSub = strings.TrimPrefix(pathToSub, "/")
-				}
-				imgPathWithoutPeriodPeriod := strings.Replace(pathToSub, "../", "", -1)
-				contents = strings.ReplaceAll(contents, pathToSub, imgPathWithoutPeriodPeriod)
-			}
-			// overwrite target file with the contents
-			err = ioutil.WriteFile(newTarget, []byte(contents), 0777)
-			if err != nil {
-				return "", fmt.Errorf("Could not write copied target file with cleaned up links: %s", err)
-			}
+// findDataPath looks for the data file rel, a path like data/people.sql, in dir and then in each
+// of its parents up to root. When dir isn't in a block only .sql files are looked for above it,
+// up to maxDataPathDepth parents. Returns "" when the file is not found
+func findDataPath(dir, root string, inBlock bool, rel string) string {
+	for depth := 0; ; depth++ {
+		candidate := filepath.Join(dir, rel)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || (inBlock && dir == root) {
+			return ""
 		}
-		return newSrcPath, nil
+		if !inBlock && (depth == maxDataPathDepth || filepath.Ext(rel) != ".sql") {
+			return ""
+		}
+		dir = parent
 	}
-
-	return target, nil
 }
 
-// previewCmdError is a small wrapper for all errors within the preview command. It ensures
-// artifacts are cleaned up with a call to removeArtifacts
+// previewCmdError is a small wrapper for all errors within the preview command
 func previewCmdError(msg string) {
 	fmt.Println(msg)
-	removeArtifacts()
 	learn.API.NotifySlack(errors.New(msg))
 	os.Exit(1)
 }
@@ -397,19 +477,29 @@ func printlnGreen(text string) {
 	fmt.Printf("\033[32m%s\033[0m\n", text)
 }
 
-// collectLinkPaths takes a target, reads it, and passes it's contents (slice of bytes)
-// to our MDLinkParser as a string. All relative/local markdown flavored images are parsed
-// into an array of strings and returned
+// collectLinkPaths takes a target, reads it, and parses it's contents as markdown. The paths of
+// every local image, html image and link are returned once each
 func collectLinkPaths(target string) ([]string, error) {
 	contents, err := ioutil.ReadFile(target)
 	if err != nil {
 		return []string{}, fmt.Errorf("Failure to read file '%s'. Err: %s", string(contents), err)
 	}
 
-	m := mdlinkparser.New(string(contents))
-	m.ParseLinks()
+	paths := []string{}
+	seen := map[string]struct{}{}
+	for _, ref := range mdlinkparser.Parse(contents) {
+		path := ref.Path()
+		if !ref.IsLocal() || path == "" {
+			continue
+		}
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
 
-	return m.Links, nil
+	return paths, nil
 }
 
 // collectDataPaths takes a target, reads it, and scans the file for data paths and collects them
@@ -446,7 +536,7 @@ func collectDataPaths(target string) ([]string, error) {
 }
 
 // uploadToS3 takes a file and it's checksum and uploads it to s3 in the appropriate bucket/key
-func uploadToS3(file *os.File, checksum string, creds *learn.Credentials) (string, error) {
+func uploadToS3(file *os.File, checksum string, creds *learn.Credentials, showProgress bool) (string, error) {
 	// Set up an AWS session with the user's credentials
 	sess, err := session.NewSession(&aws.Config{
 		Region: aws.String("us-west-2"),
@@ -474,13 +564,15 @@ func uploadToS3(file *os.File, checksum string, creds *learn.Credentials) (strin
 	}
 
 	// Create and start a new progress bar with a fixed width
-	bar := pb.Full.Start64(fileStats.Size()).SetWidth(100)
+	bar := pb.Full.New(0).SetTotal(fileStats.Size()).SetWidth(100)
+	if showProgress {
+		bar.Start()
+		fmt.Println("Uploading assets to Learn...")
+	}
 
 	// Create a ProxyReader and attach the file and progress bar
 	pr := proxyReader.New(file, bar)
 
-	fmt.Println("Uploading assets to Learn...")
-
 	// Upload compressed zip file to s3
 	_, err = uploader.Upload(&s3manager.UploadInput{
 		Bucket: aws.String(creds.BucketName),
@@ -491,8 +583,10 @@ func uploadToS3(file *os.File, checksum string, creds *learn.Credentials) (strin
 		return "", fmt.Errorf("Error uploading assets to s3: %v", err)
 	}
 
-	bar.Finish()
-	printlnGreen("√")
+	if showProgress {
+		bar.Finish()
+		printlnGreen("√")
+	}
 
 	return bucketKey, nil
 }
@@ -524,24 +618,6 @@ func createChecksumFromZip(file *os.File) (string, error) {
 	return checksum, nil
 }
 
-// removeArtifacts removes the tmp zipfile and the tmp single file preview directory (if there
-// was one) that were created for uploading to s3 and including local images. We wouldn't
-// want to leave artifacts on user's machines
-func removeArtifacts() {
-	err := os.Remove(tmpZipFile)
-	if err != nil {
-		fmt.Println("Sorry, we had trouble cleaning up the zip file created for curriculum preview")
-	}
-
-	// Remove tmpSingleFileDir if it exists at this point
-	if _, err := os.Stat(tmpSingleFileDir); !os.IsNotExist(err) {
-		err = os.RemoveAll(tmpSingleFileDir)
-		if err != nil {
-			fmt.Println("Sorry, we had trouble cleaning up the tmp single file preview directory")
-		}
-	}
-}
-
 // Copy the src file to target dest. Any existing file will be overwritten and will not copy file attributes.
 func Copy(src, dst string) error {
 	in, err := os.Open(src)
@@ -565,8 +641,9 @@ func Copy(src, dst string) error {
 
 // compressDirectory takes a source file path (where the content you want zipped lives)
 // and a target file path (where to put the zip file) and recursively compresses the source.
-// Source can either be a directory or a single file
-func compressDirectory(source, target string) error {
+// Source can either be a directory or a single file. Only files in fileExtWhitelist are
+// included unless allTypes is set, for directories holding just what a preview links to
+func compressDirectory(source, target string, allTypes bool) error {
 	// Create file with target name and defer its closing
 	zipfile, err := os.Create(target)
 	if err != nil {
@@ -594,6 +671,7 @@ func compressDirectory(source, target string) error {
 	filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
 		ext := filepath.Ext(path)
 		_, ok := fileExtWhitelist[ext]
+		ok = ok || (allTypes && !info.IsDir())
 
 		if ok || (info.IsDir() && (ext != ".git" && path != "node_modules")) {
 			if err != nil {
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"archive/zip"
 	"bytes"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -17,185 +19,208 @@ const testMDContent = `## Test links
 ![alt](../nested-small.png)`
 
 func Test_createNewTarget(t *testing.T) {
-	result, err := createNewTarget("../../fixtures/test-links/nested/test.md", []string{"./mrsmall-invert.png", "../mrsmall.png", "../image/nested-small.png", "deeper/deep-small.png"})
+	dir := writeLinkBlock(t, map[string]string{
+		"config.yaml":                        "Standards: []\n",
+		"units/nested/test.md":               testMDContent,
+		"units/nested/mrsmall-invert.png":    "png",
+		"units/nested/deeper/deep-small.png": "png",
+		"units/mrsmall.png":                  "png",
+		"units/image/nested-small.png":       "png",
+	})
+	target := filepath.Join(dir, "units/nested/test.md")
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
+	result, err := createNewTarget(target, staging, []string{"./mrsmall-invert.png", "../mrsmall.png", "../image/nested-small.png", "deeper/deep-small.png"}, nil)
 	if err != nil {
 		t.Errorf("Attempting to createNewTarget errored: %s\n", err)
 	}
-	if result != "single-file-upload" {
-		t.Errorf("result should be the temp directory with the target markdown, '%s'", result)
+	if result != staging {
+		t.Errorf("result should be the staging directory with the target markdown, '%s'", result)
 	}
 
-	if _, err := os.Stat(fmt.Sprintf("single-file-upload/%s", "test.md")); os.IsNotExist(err) {
-		t.Errorf("test.md should have been created")
-	}
-	if _, err = os.Stat(fmt.Sprintf("single-file-upload/%s", "mrsmall-invert.png")); os.IsNotExist(err) {
-		t.Errorf("mrsmall-invert should have been created, was not")
-	}
-	if _, err = os.Stat(fmt.Sprintf("single-file-upload/deeper/%s", "deep-small.png")); os.IsNotExist(err) {
-		t.Errorf("deeper/deep-small.png should have been created in its directory, was not")
+	// The files are staged where they are in the block, from the block root
+	nested := filepath.Join(staging, "units/nested")
+	for _, path := range []string{"test.md", "mrsmall-invert.png", "deeper/deep-small.png", "../mrsmall.png", "../image/nested-small.png"} {
+		if _, err := os.Stat(filepath.Join(nested, path)); err != nil {
+			t.Errorf("%s should have been staged next to test.md, was not", path)
+		}
 	}
 
-	// test cases for ,./
-	if _, err = os.Stat(fmt.Sprintf("single-file-upload/%s", "mrsmall.png")); os.IsNotExist(err) {
-		t.Errorf("mrsmall.png should have been created and moved to the root of the single file directory, was not")
+	b, err := ioutil.ReadFile(filepath.Join(nested, "test.md"))
+	if err != nil || string(b) != testMDContent {
+		t.Errorf("test.md should be staged without its links rewritten, got:\n%s\n", string(b))
 	}
-	if _, err = os.Stat(fmt.Sprintf("single-file-upload/image/%s", "nested-small.png")); os.IsNotExist(err) {
-		t.Errorf("nested-small.png should have been created and it's image dir moved to the root of the single file directory, was not")
+}
+
+func Test_createNewTargetWithoutBlock(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"secret.txt":              "secret",
+		"lesson/test.md":          "# Test\n\n![invert](image/invert.png)\n[secret](../secret.txt)\n",
+		"lesson/image/invert.png": "png",
+	})
+	if _, inBlock := lookupBlockRoot(dir); inBlock {
+		t.Skipf("%s is inside a block, previews outside of one cannot be tested here", dir)
 	}
+	target := filepath.Join(dir, "lesson/test.md")
 
-	b, err := ioutil.ReadFile("single-file-upload/test.md")
+	assets, warnings, err := collectPreviewAssets(target, 0)
 	if err != nil {
-		t.Errorf("could not Open test.md")
+		t.Fatal(err)
 	}
-	if strings.Contains(string(b), "../mrsmall.png") {
-		t.Errorf("test.md file should not contain '../mrsmall.png' but does:\n%s\n", string(b))
+	var manifest bytes.Buffer
+	printPreviewManifest(&manifest, target, assets, warnings)
+	if strings.Join(assetPaths(assets), ",") != "image/invert.png" || strings.Contains(manifest.String(), "  ../secret.txt") {
+		t.Errorf("only the files in the lesson's directory should be packed, got:\n%s", manifest.String())
 	}
-	if strings.Contains(string(b), "../image/nested-small.png") {
-		t.Errorf("test.md file should not contain '../image/nested-small.png' but does:\n%s\n", string(b))
+	if !strings.Contains(manifest.String(), "../secret.txt, which is outside of the previewed file's directory") {
+		t.Errorf("the link leaving the lesson's directory should be a warning, got:\n%s", manifest.String())
 	}
-	err = os.RemoveAll("single-file-upload")
-	if err != nil {
-		t.Errorf("could not remove single-file-upload directory: %s\n", err)
-	}
-}
 
-func Test_createNewTargetSingleFileSQLWithImage(t *testing.T) {
-	err := createTestMD()
-	err = os.MkdirAll("image", os.FileMode(0777))
-	_, err = os.Create("image/nested-small.png")
-	if err != nil {
-		t.Errorf("Error generating test image: %s\n", err)
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
+	if _, err := createNewTarget(target, staging, []string{"../secret.txt"}, nil); err == nil || !strings.Contains(err.Error(), "outside of its directory") {
+		t.Errorf("createNewTarget should reject a link leaving the lesson's directory, got %v", err)
 	}
-
-	output := captureOutput(func() {
-		_, err := createNewTarget("test.md", []string{"/data/some.sql", "image/nested-small.png"})
-		_, err = os.Stat(fmt.Sprintf("single-file-upload/%s", "data/some.sql"))
-		if err == nil {
-			t.Errorf("data/some.sql should have been copied over and it was not")
-		}
-
-		if _, err = os.Stat(fmt.Sprintf("single-file-upload/%s", "/image/nested-small.png")); os.IsNotExist(err) {
-			t.Errorf("mrsmall-invert should have been created, was not")
-		}
-	})
-
-	if strings.Contains(output, "Link not found with path") {
-		t.Errorf("output should not print 'Link not found with path', output was:\n%s\n", output)
+	if _, err := createNewTarget(target, staging, assetPaths(assets), nil); err != nil {
+		t.Fatalf("Attempting to createNewTarget errored: %s\n", err)
 	}
-
-	if strings.Contains(output, "Failed build tmp files around single file preview for") {
-		t.Errorf("output should not print 'Failed build tmp files around single file preview for', output was:\n%s\n", output)
+	zipPath := filepath.Join(t.TempDir(), tmpZipFile)
+	if err := compressDirectory(staging, zipPath, true); err != nil {
+		t.Fatal(err)
 	}
-
-	err = os.RemoveAll("single-file-upload")
+	r, err := zip.OpenReader(zipPath)
 	if err != nil {
-		t.Errorf("could not remove single-file-upload directory: %s\n", err)
+		t.Fatal(err)
 	}
-
-	err = os.RemoveAll("image")
-	if err != nil {
-		t.Errorf("could not remove image directory: %s\n", err)
+	defer r.Close()
+	names := []string{}
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			names = append(names, strings.TrimPrefix(f.Name, tmpSingleFileDir+"/"))
+		}
 	}
-
-	err = os.Remove("test.md")
-	if err != nil {
-		t.Errorf("could not remove 'test.md' file: %s\n", err)
+	sort.Strings(names)
+	if strings.Join(names, ",") != "image/invert.png,test.md" {
+		t.Errorf("the zip should hold the lesson and its image only, got %v", names)
 	}
 }
 
-func Test_createNewTargetSingleFileThatIsSQL(t *testing.T) {
-	err := createTestMD()
-	if err != nil {
-		t.Errorf("Error creating test.md: %s\n", err)
-	}
+func Test_createNewTargetDataPathInParent(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"config.yaml":                   "Standards: []\n",
+		"units/data/some.sql":           "create table people (name text);\n",
+		"units/unit-1/nested/lesson.md": testMDContent,
+	})
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
+
 	output := captureOutput(func() {
-		_, err := createNewTarget("test.md", []string{"/data/some.sql"})
-		_, err = os.Stat(fmt.Sprintf("single-file-upload/%s", "data/some.sql"))
-		if err == nil {
-			t.Errorf("data/some.sql should have been copied over and it was not")
+		if _, err := createNewTarget(filepath.Join(dir, "units/unit-1/nested/lesson.md"), staging, nil, []string{"/data/some.sql", "/data/missing.sql"}); err != nil {
+			t.Errorf("Attempting to createNewTarget errored: %s\n", err)
 		}
 	})
 
-	if strings.Contains(output, "Link not found with path") {
-		t.Errorf("output should not print 'Link not found with path', output was:\n%s\n", output)
+	if _, err := os.Stat(filepath.Join(staging, "data/some.sql")); err != nil {
+		t.Errorf("data/some.sql should have been found in a parent of the lesson and staged at its data path")
 	}
-
-	err = os.Remove("test.md")
-	if err != nil {
-		t.Errorf("could not remove 'test.md' file: %s\n", err)
+	if !strings.Contains(output, "Link not found with path '/data/missing.sql'") {
+		t.Errorf("output should print 'Link not found with path' for the missing data file, output was:\n%s\n", output)
 	}
 }
 
-func Test_createNewTargetSingleFile(t *testing.T) {
-	err := createTestMD()
-	err = os.MkdirAll("image", os.FileMode(0777))
-	_, err = os.Create("image/nested-small.png")
-	_, err = os.Create("../nested-small.png")
-	if err != nil {
-		t.Errorf("Error generating test fixtures: %s\n", err)
-	}
+func Test_createNewTargetSingleFileSQLWithImage(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"config.yaml":                  "Standards: []\n",
+		"data/some.sql":                "create table people (name text);\n",
+		"units/lesson.md":              testMDContent,
+		"units/image/nested-small.png": "png",
+	})
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
 
 	output := captureOutput(func() {
-		result, err := createNewTarget("test.md", []string{"./image/nested-small.png", "image/nested-small.png", "../nested-small.png"})
+		_, err := createNewTarget(filepath.Join(dir, "units/lesson.md"), staging, []string{"image/nested-small.png"}, []string{"/data/some.sql"})
 		if err != nil {
 			t.Errorf("Attempting to createNewTarget errored: %s\n", err)
 		}
-		if result != "single-file-upload" {
-			t.Errorf("result should be the temp directory with the target markdown, '%s'", result)
-		}
-
-		if _, err := os.Stat(fmt.Sprintf("single-file-upload/%s", "test.md")); os.IsNotExist(err) {
-			t.Errorf("test.md should have been created")
-		}
+	})
 
-		if _, err = os.Stat(fmt.Sprintf("single-file-upload/image/%s", "nested-small.png")); os.IsNotExist(err) {
-			t.Errorf("nested-small.png should have been created and it's image dir moved to the root of the single file directory, was not")
-		}
+	if _, err := os.Stat(filepath.Join(staging, "data/some.sql")); err != nil {
+		t.Errorf("data/some.sql should have been staged from the block root and it was not")
+	}
+	if _, err := os.Stat(filepath.Join(staging, "units/image/nested-small.png")); err != nil {
+		t.Errorf("image/nested-small.png should have been staged next to the lesson, was not")
+	}
+	if strings.Contains(output, "Link not found with path") {
+		t.Errorf("output should not print 'Link not found with path', output was:\n%s\n", output)
+	}
+}
 
-		if _, err = os.Stat(fmt.Sprintf("single-file-upload/%s", "nested-small.png")); os.IsNotExist(err) {
-			t.Errorf("nested-small.png should have been created and it's image dir moved to the root of the single file directory, was not")
-		}
+func Test_createNewTargetMissingLink(t *testing.T) {
+	dir := writeLinkBlock(t, map[string]string{
+		"config.yaml": "Standards: []\n",
+		"lesson.md":   testMDContent,
+	})
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
 
-		err = os.RemoveAll("single-file-upload")
-		if err != nil {
-			t.Errorf("could not remove single-file-upload directory: %s\n", err)
+	output := captureOutput(func() {
+		if _, err := createNewTarget(filepath.Join(dir, "lesson.md"), staging, nil, []string{"/data/some.sql"}); err != nil {
+			t.Errorf("a missing file should not fail createNewTarget, got %s", err)
 		}
 	})
 
-	if strings.Contains(output, "Link not found with path") {
-		t.Errorf("output should not print 'Link not found with path', output was:\n%s\n", output)
+	if !strings.Contains(output, "Link not found with path '/data/some.sql'") {
+		t.Errorf("output should print 'Link not found with path' for the missing file, output was:\n%s\n", output)
 	}
-
-	err = os.RemoveAll("image")
-	if err != nil {
-		t.Errorf("could not remove 'image' directory: %s\n", err)
+	if _, err := os.Stat(filepath.Join(staging, "lesson.md")); err != nil {
+		t.Errorf("lesson.md should have been staged")
 	}
+}
 
-	err = os.Remove("../nested-small.png")
-	if err != nil {
-		t.Errorf("could not remove '../nested-small.png' file: %s\n", err)
+func Test_createNewTargetOutsideBlock(t *testing.T) {
+	outside := t.TempDir()
+	ioutil.WriteFile(filepath.Join(outside, "nested-small.png"), []byte("png"), 0644)
+	dir := filepath.Join(outside, "block")
+	os.MkdirAll(filepath.Join(dir, "image"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte("Standards: []\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "lesson.md"), []byte(testMDContent), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "image/nested-small.png"), []byte("png"), 0644)
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
+
+	_, err := createNewTarget(filepath.Join(dir, "lesson.md"), staging, []string{"./image/nested-small.png", "../nested-small.png"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "lesson.md links to ../nested-small.png, which is outside of the block") {
+		t.Errorf("a link leaving the block should be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(staging, "nested-small.png")); err == nil {
+		t.Errorf("the file outside of the block should not have been staged")
 	}
+}
 
-	err = os.Remove("test.md")
-	if err != nil {
-		t.Errorf("could not remove 'test.md' file: %s\n", err)
+func Test_createNewTargetNoPaths(t *testing.T) {
+	staging := filepath.Join(t.TempDir(), tmpSingleFileDir)
+	result, err := createNewTarget("lesson.md", staging, nil, nil)
+	if err != nil || result != "lesson.md" {
+		t.Errorf("createNewTarget without paths should return the target, got %s, %v", result, err)
+	}
+	if _, err := os.Stat(staging); err == nil {
+		t.Errorf("nothing should be staged without paths")
 	}
 }
 
-func createTestMD() error {
-	f, err := os.OpenFile("test.md", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func Test_previewWorkspace(t *testing.T) {
+	workspace, err := newPreviewWorkspace()
 	if err != nil {
-		return err
+		t.Fatal(err)
 	}
-	if _, err := f.Write([]byte(testMDContent)); err != nil {
-		f.Close()
-		return err
+	if !withinDir(os.TempDir(), workspace.zipPath()) || filepath.Base(workspace.zipPath()) != tmpZipFile {
+		t.Errorf("the zip file should be in a temporary directory, got %s", workspace.zipPath())
 	}
-	if err := f.Close(); err != nil {
-		return err
+	if !isStagedSingleFile(workspace.stagingDir()) {
+		t.Errorf("the staging directory should be recognized as a staged single file, got %s", workspace.stagingDir())
 	}
 
-	return nil
+	os.MkdirAll(workspace.stagingDir(), 0755)
+	ioutil.WriteFile(workspace.zipPath(), []byte("zip"), 0644)
+	workspace.remove()
+	if _, err := os.Stat(workspace.dir); err == nil {
+		t.Errorf("remove should delete the workspace and everything in it")
+	}
 }
 
 func captureOutput(f func()) string {
@@ -205,3 +230,23 @@ func captureOutput(f func()) string {
 	log.SetOutput(os.Stderr)
 	return buf.String()
 }
+
+func Test_collectLinkPaths(t *testing.T) {
+	dir := t.TempDir()
+	target := dir + "/lesson.md"
+	content := testMDContent + "\n\n" +
+		"<img src=\"./image/html.png\">\n\n" +
+		"![logo][logo] and [the next lesson](../02-next.md) and [docs](https://example.com)\n\n" +
+		"```md\n![in a code sample](sample.png)\n```\n\n" +
+		"[logo]: ./image/nested-small.png\n"
+	ioutil.WriteFile(target, []byte(content), 0644)
+
+	paths, err := collectLinkPaths(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"./image/nested-small.png", "image/nested-small.png", "../nested-small.png", "./image/html.png", "../02-next.md"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("collectLinkPaths should return each local asset once, expected %v got %v", want, paths)
+	}
+}
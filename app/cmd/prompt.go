@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// promptReader is where interactive answers are read from, tests replace it with canned input
+var promptReader *bufio.Reader = bufio.NewReader(os.Stdin)
+
+// promptOut is where interactive questions are written
+var promptOut io.Writer = os.Stdout
+
+// prompt prints question and returns the trimmed line the user answers with
+func prompt(question string) (string, error) {
+	fmt.Fprint(promptOut, question)
+
+	answer, err := promptReader.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(answer), nil
+}
+
+// confirm asks a yes/no question, anything other than y or yes is treated as no
+func confirm(question string) bool {
+	answer, err := prompt(question + " [y/N] ")
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
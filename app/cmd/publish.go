@@ -1,155 +1,164 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/gSchool/glearn-cli/api/learn"
+	"github.com/gSchool/glearn-cli/gitutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-const (
-	branchCommand     = `git branch | grep \* | cut -d ' ' -f2`
-	pushRemoteCommand = `git remote get-url --push origin`
-)
+// fallbackDefaultBranch is published when the origin remote does not report its HEAD branch
+const fallbackDefaultBranch = "master"
 
 var publishCmd = &cobra.Command{
 	Use:   "publish",
-	Short: "Publish master for your curriculum repository",
+	Short: "Publish the default branch for your curriculum repository",
 	Long: `
 The Learn system recognizes blocks of content held in GitHub respositories. This
-command pushes the latest commit for the remote origin master (which should be
-GitHub), then attempts the release of a new Learn block version at the HEAD of
-master. If the block doesn't exist, running the publish command will create a
-new block. If the block already exists, it will update the existing block.
+command pushes the latest commit for the remote origin default branch (master or
+main, which should be GitHub), then attempts the release of a new Learn block
+version at the HEAD of that branch. If the block doesn't exist, running the
+publish command will create a new block. If the block already exists, it will
+update the existing block.
+
+Before anything is pushed, publish refuses to continue with uncommitted changes
+or a branch that is behind or has diverged from origin, validates the block
+config, and shows a summary of the commits to push and the cohorts using the
+block. Confirm the summary to continue, or pass --yes to skip the question. Use
+--dry-run to run every check without pushing or releasing.
+
+Publishing from any other branch releases it for a single cohort. Pass the
+cohort id with --cohort, or pick from the cohorts using the block when asked,
+by cohort id or by #number in the list.
+
+Use --all <directory> to publish every block repository found under a directory,
+several at a time. Each repository must be on its default branch.
+
+To republish a known-good version, pass --ref with a commit sha or tag that
+exists on the remote. Nothing is pushed when publishing a ref.
 	`,
 	Args: cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
 		if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
-			fmt.Println(setAPITokenMessage)
+			fmt.Print(setAPITokenMessage)
 			os.Exit(1)
 		}
 
 		setupLearnAPI()
 
 		if len(args) != 0 {
-			fmt.Println("Usage: `learn publish` takes no arguments, merely pushing latest master and releasing a version to Learn. Use the command from inside a block repository.")
+			fmt.Println("Usage: `learn publish` takes no arguments, merely pushing the latest default branch and releasing a version to Learn. Use the command from inside a block repository.")
 			os.Exit(1)
 		}
 
-		// Start benchmarking the total time spent in publish cmd
-		startOfCmd := time.Now()
-
-		remote, err := remoteName()
-		if err != nil {
-			fmt.Printf("Cannot run git remote detection with command: %s\n%s\n", pushRemoteCommand, err)
-			os.Exit(1)
-		}
-		if remote == "" {
-			fmt.Println("no fetch remote detected")
+		if err := publishFlagsError(PublishRef, PublishCohort); err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		block, err := learn.API.GetBlockByRepoName(remote)
-		if err != nil {
-			fmt.Printf("Error fetching block from learn: %s\n", err)
-			os.Exit(1)
-		}
-		if !block.Exists() {
-			block, err = learn.API.CreateBlockByRepoName(remote)
-			if err != nil {
-				fmt.Printf("Error creating block from learn: %s\n", err)
+		if PublishAll != "" {
+			if PublishRef != "" || PublishCohort != 0 {
+				fmt.Println("--all cannot be combined with --ref or --cohort")
 				os.Exit(1)
 			}
+			publishAll(PublishAll)
+			return
 		}
 
-		branch, err := currentBranch()
-		if err != nil {
-			fmt.Println("Cannot run git branch detection with bash:", err)
-			os.Exit(1)
-		}
-
-		if branch != "master" {
-			fmt.Printf("Branch publishing is cohort-specific. To continue publishing from branch '%s', go to https://learn-2.galvanize.com/cohorts/<cohortID>/setup and click the 'recycle' button for this repo.\n", branch)
-			os.Exit(1)
-		}
+		// Start benchmarking the total time spent in publish cmd
+		startOfCmd := time.Now()
 
-		// Detect config file
 		path, _ := os.Getwd()
-		createdConfig, err := doesConfigExistOrCreate(path+"/", UnitsDirectory, false)
+		plan, err := lookupPublishBlock(path)
 		if err != nil {
-			fmt.Printf(fmt.Sprintf("Failed to find or create a config file for repo: (%s). Err: %v", branch, err))
+			fmt.Println(err)
 			os.Exit(1)
 		}
-		fmt.Printf("Publishing block with repo name %s\n", remote)
-
-		if createdConfig {
-			fmt.Println("Committing autoconfig.yaml to", branch)
-			err = addAutoConfigAndCommit()
 
-			if err != nil && !strings.Contains(err.Error(), "Your branch is up to date with 'origin/master'.") {
-				fmt.Printf("Error committing the autoconfig.yaml to origin remote on branch: %s", err)
+		target := releaseTarget{}
+		if PublishRef != "" {
+			sha, err := plan.repo.ResolveRemoteRef("origin", PublishRef)
+			if err != nil {
+				fmt.Printf("Cannot publish ref '%s': %s\n", PublishRef, err)
 				os.Exit(1)
 			}
-		}
-
-		fmt.Println("Pushing work to remote origin", branch)
+			target = releaseTarget{ref: sha}
+
+			printPublishSummary(publishSummary{
+				remote: plan.remote,
+				block:  plan.block,
+				ref:    fmt.Sprintf("%s (%s)", PublishRef, sha),
+			})
+			if !confirmPublish() {
+				return
+			}
+			fmt.Printf("Publishing block with repo name %s at ref %s (%s)\n", plan.remote, PublishRef, sha)
+		} else {
+			if err = plan.check(UnitsDirectory, false); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if PublishDryRun {
+				defer plan.restoreAutoConfig()
+			}
+			fmt.Println()
+
+			// Branch publishing is cohort-specific, pick the cohort when it wasn't given
+			if plan.branch != plan.defaultBranch || PublishCohort != 0 {
+				cohortID := PublishCohort
+				if cohortID == 0 {
+					fmt.Printf("Publishing from branch '%s' releases it for a single cohort.\n", plan.branch)
+					cohortID, err = selectCohort(plan.block)
+					if err != nil {
+						fmt.Println(err)
+						os.Exit(1)
+					}
+				}
+				target = releaseTarget{branch: plan.branch, cohortID: cohortID}
+			}
 
-		// TODO what happens when they do not have work in remote and push fails?
-		err = pushToRemote(branch)
-		if err != nil {
-			fmt.Printf("Error pushing to origin remote on branch: %s\n", err)
-			os.Exit(1)
+			printPublishSummary(publishSummary{
+				remote:        plan.remote,
+				block:         plan.block,
+				branch:        plan.branch,
+				unpushed:      plan.unpushed,
+				configPath:    plan.configPath,
+				commitsConfig: plan.createdConfig,
+				uidChanges:    plan.uidChanges,
+				cohortID:      target.cohortID,
+			})
+			if !confirmPublish() {
+				return
+			}
+			fmt.Printf("Publishing block with repo name %s\n", plan.remote)
 		}
 
 		// Start benchmark for creating master release & building on learn
 		startOfMasterReleaseAndBuild := time.Now()
 
-		// Start a processing spinner that runs until Learn is finsihed building the preview
-		fmt.Println("\nBuilding release...")
-		s := spinner.New(spinner.CharSets[32], 100*time.Millisecond)
-		s.Color("green")
-		s.FinalMSG = fmt.Sprintf("Block %d released!\n", block.ID)
-		s.Start()
-
-		// Create a release on learn, notify user
-		releaseID, err := learn.API.CreateMasterRelease(block.ID)
-		if err != nil || releaseID == 0 {
-			fmt.Printf("error creating master release for releaseID: %d. Error: %s\n", releaseID, err)
-			os.Exit(1)
-		}
-
-		var attempts uint8 = 30
-		p, err := learn.API.PollForBuildResponse(releaseID, &attempts)
+		p, err := executePublish(plan, target, false)
 		if err != nil {
-			s.Stop()
-
-			block, err := learn.API.GetBlockByRepoName(remote)
-			if err != nil {
-				fmt.Printf("Error fetching block from learn: %s\n", err)
-				os.Exit(1)
-			}
-			fmt.Println("Errors on block:")
-			for _, e := range block.SyncErrors {
-				fmt.Println(e)
-			}
+			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		// Add benchmark in milliseconds for compressDirectory
+		// Add benchmark in milliseconds for the release and build
 		bench := &learn.CLIBenchmark{
 			MasterReleaseAndBuild: time.Since(startOfMasterReleaseAndBuild).Milliseconds(),
 			TotalCmdTime:          time.Since(startOfCmd).Milliseconds(),
 			CmdName:               "publish",
 		}
 
-		s.Stop()
-
 		if len(p.SyncWarnings) > 0 {
 			fmt.Println("Warnings on new release:")
 			for _, warning := range p.SyncWarnings {
@@ -167,53 +176,409 @@ new block. If the block already exists, it will update the existing block.
 	},
 }
 
-func currentBranch() (string, error) {
-	return runBashCommand(branchCommand)
+// publishPlan is everything publish checks and looks up before it pushes or creates anything
+type publishPlan struct {
+	dir           string
+	repo          *gitutil.Repo
+	remote        string
+	block         learn.Block
+	branch        string
+	defaultBranch string
+	unpushed      int
+	configPath    string
+	createdConfig bool
+	// previousAutoConfig holds the autoconfig.yaml and autoconfig.lock that existed before check
+	// generated new ones, nil for files that did not exist
+	previousAutoConfig map[string][]byte
+	// uidChanges describes the UIDs this publish changes compared to the default branch on origin
+	uidChanges []string
 }
 
-func remoteName() (string, error) {
-	s, err := runBashCommand(pushRemoteCommand)
+// autoConfigFiles are generated by autoconfig and committed by publish
+var autoConfigFiles = []string{"autoconfig.yaml", uidLockFile}
+
+// publishFlagsError reports publish flags that cannot be used together, a ref is always
+// released for every cohort
+func publishFlagsError(ref string, cohort int) error {
+	if ref != "" && cohort != 0 {
+		return errors.New("--ref cannot be combined with --cohort")
+	}
+	return nil
+}
+
+// lookupPublishBlock finds the origin remote of the repository at dir and the block Learn
+// has for it. The block may not exist yet
+func lookupPublishBlock(dir string) (*publishPlan, error) {
+	plan := &publishPlan{dir: dir, repo: gitutil.New(dir)}
+
+	remote, err := remoteName(plan.repo)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("Cannot detect the origin remote of this repository:\n%s", err)
 	}
-	parts := strings.Split(s, ".git") // isolate url from .git
-	if len(parts) < 1 {
-		return "", fmt.Errorf("Error parsing remote name from %s", s)
+	if remote == "" {
+		return nil, errors.New("no fetch remote detected")
 	}
-	parts = strings.Split(parts[0], "/") // select final part of the url
-	if len(parts) < 1 {
-		return "", fmt.Errorf("Error parsing remote name from %s", s)
+	plan.remote = remote
+
+	plan.block, err = learn.API.GetBlockByRepoName(remote)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching block from learn: %s", err)
 	}
-	return parts[len(parts)-1], nil
+
+	return plan, nil
 }
 
-func pushToRemote(branch string) error {
-	_, err := exec.Command("bash", "-c", fmt.Sprintf("git push origin %s", branch)).CombinedOutput()
+// check runs the pre-publish safety checks for the current branch: the working tree must be
+// clean and not behind origin, and the block config (generated if missing) must be valid
+func (plan *publishPlan) check(unitsDir string, quiet bool) error {
+	var err error
+
+	plan.branch, err = plan.repo.CurrentBranch()
+	if err != nil {
+		return fmt.Errorf("Cannot detect the current git branch: %s", err)
+	}
+
+	plan.defaultBranch, err = remoteDefaultBranch(plan.repo)
+	if err != nil {
+		return fmt.Errorf("Cannot detect the default branch of origin:\n%s", err)
+	}
+
+	plan.unpushed, err = checkWorkingTree(plan.repo, plan.branch)
 	if err != nil {
 		return err
 	}
 
+	// Detect config file, remembering any autoconfig files so a dry run can put them back
+	plan.previousAutoConfig = map[string][]byte{}
+	for _, name := range autoConfigFiles {
+		plan.previousAutoConfig[name], _ = ioutil.ReadFile(filepath.Join(plan.dir, name))
+	}
+	plan.createdConfig, err = doesConfigExistOrCreate(plan.dir+"/", unitsDir, quiet)
+	if err != nil {
+		return fmt.Errorf("Failed to find or create a config file for repo: (%s). Err: %v", plan.branch, err)
+	}
+	plan.configPath = findConfigPath(plan.dir)
+
+	problems, err := validateBlockConfig(plan.dir)
+	if err != nil {
+		return fmt.Errorf("Failed to validate the config for repo: %s", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Cannot publish, the block config has problems:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	plan.uidChanges = publishedUIDChanges(plan.repo, "origin/"+plan.defaultBranch, plan.configPath)
+
 	return nil
 }
 
-func addAutoConfigAndCommit() error {
-	out, err := exec.Command("bash", "-c", "git add autoconfig.yaml").CombinedOutput()
+// restoreAutoConfig puts back whatever autoconfig files existed before check generated them
+func (plan *publishPlan) restoreAutoConfig() {
+	if !plan.createdConfig {
+		return
+	}
+
+	for _, name := range autoConfigFiles {
+		path := filepath.Join(plan.dir, name)
+		if plan.previousAutoConfig[name] == nil {
+			os.Remove(path)
+		} else {
+			ioutil.WriteFile(path, plan.previousAutoConfig[name], 0644)
+		}
+	}
+}
+
+// executePublish creates the block if it doesn't exist, commits a generated autoconfig.yaml,
+// pushes the branch and releases target, waiting for Learn to build it. Publishing a ref
+// skips the commit and push. Spinners and git output are only shown when not quiet
+func executePublish(plan *publishPlan, target releaseTarget, quiet bool) (*learn.PreviewResponse, error) {
+	var err error
+
+	if !plan.block.Exists() {
+		plan.block, err = learn.API.CreateBlockByRepoName(plan.remote)
+		if err != nil {
+			return nil, fmt.Errorf("Error creating block from learn: %s", err)
+		}
+	}
+
+	if target.ref == "" {
+		if plan.createdConfig {
+			if !quiet {
+				fmt.Println("Committing autoconfig.yaml and autoconfig.lock to", plan.branch)
+			}
+			if err = addAutoConfigAndCommit(plan.repo); err != nil {
+				return nil, fmt.Errorf("Error committing the autoconfig.yaml to origin remote on branch: %s", err)
+			}
+		}
+
+		if !quiet {
+			fmt.Println("Pushing work to remote origin", plan.branch)
+		}
+		out, err := plan.repo.Push("origin", plan.branch)
+		if err != nil {
+			return nil, fmt.Errorf("Error pushing to origin remote on branch: %s", err)
+		}
+		if out != "" && !quiet {
+			fmt.Println(out)
+		}
+	}
+
+	// Start a processing spinner that runs until Learn is finsihed building the release
+	s := spinner.New(spinner.CharSets[32], 100*time.Millisecond)
+	s.Color("green")
+	if !quiet {
+		fmt.Println("\nBuilding release...")
+		s.Start()
+	}
+	defer s.Stop()
+
+	// Create a release on learn
+	releaseID, err := target.create(plan.block.ID)
+	if err != nil || releaseID == 0 {
+		return nil, fmt.Errorf("error creating release for releaseID: %d. Error: %s", releaseID, err)
+	}
+
+	var attempts uint8 = 30
+	p, err := learn.API.PollForBuildResponse(releaseID, &attempts)
+	if err != nil {
+		s.Stop()
+
+		block, blockErr := learn.API.GetBlockByRepoName(plan.remote)
+		if blockErr != nil {
+			return nil, fmt.Errorf("Error fetching block from learn: %s", blockErr)
+		}
+		if len(block.SyncErrors) == 0 {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Errors on block:\n%s", strings.Join(block.SyncErrors, "\n"))
+	}
+
+	if !quiet {
+		s.FinalMSG = target.releasedMessage(plan.block.ID)
+	}
+
+	return p, nil
+}
+
+// publishSummary holds everything a user should see before a publish goes ahead
+type publishSummary struct {
+	remote        string
+	block         learn.Block
+	branch        string
+	ref           string
+	unpushed      int
+	configPath    string
+	commitsConfig bool
+	uidChanges    []string
+	cohortID      int
+}
+
+// printPublishSummary shows what a publish is about to do and who it affects
+func printPublishSummary(summary publishSummary) {
+	fmt.Println("Publish summary")
+	fmt.Println("===============")
+	if summary.block.Exists() {
+		fmt.Printf("Block:    %s (id %d)\n", summary.remote, summary.block.ID)
+	} else {
+		fmt.Printf("Block:    %s (new, will be created on Learn)\n", summary.remote)
+	}
+	if summary.ref != "" {
+		fmt.Printf("Ref:      %s\n", summary.ref)
+	} else {
+		fmt.Printf("Branch:   %s\n", summary.branch)
+		fmt.Printf("Commits:  %d to push to origin/%s\n", summary.unpushed, summary.branch)
+	}
+	if summary.configPath != "" {
+		fmt.Printf("Config:   %s\n", filepath.Base(summary.configPath))
+	}
+	if summary.commitsConfig {
+		fmt.Printf("          autoconfig.yaml and autoconfig.lock will be committed to %s\n", summary.branch)
+	}
+
+	if summary.cohortID != 0 {
+		fmt.Printf("Cohort:   %d only, other cohorts keep their current release\n", summary.cohortID)
+	} else if len(summary.block.CohortsUsing) == 0 {
+		fmt.Println("Cohorts:  none are using this block")
+	} else {
+		fmt.Printf("Cohorts:  %d affected (%s)\n", len(summary.block.CohortsUsing), joinCohorts(summary.block.CohortsUsing))
+	}
+
+	if len(summary.uidChanges) > 0 {
+		fmt.Println()
+		fmt.Println("WARNING: this publish changes UIDs, student progress attached to the old UIDs will be lost:")
+		for _, change := range summary.uidChanges {
+			fmt.Println("  -", change)
+		}
+	}
+	fmt.Println()
+}
+
+// confirmPublish decides whether to continue after the summary. Dry runs always stop here,
+// --yes skips the question
+func confirmPublish() bool {
+	if PublishDryRun {
+		fmt.Println("Dry run complete, nothing was pushed or released.")
+		return false
+	}
+	if PublishYes {
+		return true
+	}
+	if !confirm("Publish this release?") {
+		fmt.Println("Publish cancelled.")
+		return false
+	}
+	return true
+}
+
+// selectCohort lists the cohorts using the block and asks the user to pick one by cohort id,
+// or by its place in the list written like #2. The two forms are kept apart so a small cohort
+// id is never mistaken for a place in the list
+func selectCohort(block learn.Block) (int, error) {
+	if len(block.CohortsUsing) == 0 {
+		return 0, errors.New("No cohorts are using this block yet. Pass the cohort id with --cohort to publish this branch for it.")
+	}
+
+	fmt.Fprintln(promptOut, "Cohorts using this block:")
+	for i, id := range block.CohortsUsing {
+		fmt.Fprintf(promptOut, "  #%d) cohort %d\n", i+1, id)
+	}
+
+	answer, err := prompt("Select a cohort by id, or by #number in the list: ")
 	if err != nil {
-		return fmt.Errorf("%s", out)
+		return 0, fmt.Errorf("No cohort selected: %s", err)
 	}
-	out, err = exec.Command("bash", "-c", "git commit -m \"learn cli tool publish command: adding autoconfig.yaml\"").CombinedOutput()
+
+	if position := strings.TrimPrefix(answer, "#"); position != answer {
+		n, err := strconv.Atoi(position)
+		if err != nil || n < 1 || n > len(block.CohortsUsing) {
+			return 0, fmt.Errorf("'%s' is not a number in the list of cohorts", answer)
+		}
+		return block.CohortsUsing[n-1], nil
+	}
+
+	id, err := strconv.Atoi(answer)
 	if err != nil {
-		return fmt.Errorf("%s", out)
+		return 0, fmt.Errorf("'%s' is not one of the cohorts listed", answer)
+	}
+	for _, listed := range block.CohortsUsing {
+		if listed == id {
+			return id, nil
+		}
 	}
 
-	return nil
+	if id >= 1 && id <= len(block.CohortsUsing) {
+		return 0, fmt.Errorf("'%s' is not one of the cohorts listed, use #%s to pick by number in the list", answer, answer)
+	}
+	return 0, fmt.Errorf("'%s' is not one of the cohorts listed", answer)
 }
 
-func runBashCommand(command string) (string, error) {
-	out, err := exec.Command("bash", "-c", command).CombinedOutput()
+// checkWorkingTree refuses to publish a tree with uncommitted changes or a branch that is
+// behind or has diverged from origin. It returns the number of local commits that a push would publish
+func checkWorkingTree(repo *gitutil.Repo, branch string) (int, error) {
+	changed, err := repo.ChangedPaths()
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range changed {
+		// autoconfig files are regenerated by preview and committed by publish itself
+		if path != "autoconfig.yaml" && path != uidLockFile {
+			return 0, errors.New("You have uncommitted changes. Commit or stash them before publishing so Learn releases what you see locally.")
+		}
+	}
+
+	if err = repo.Fetch("origin"); err != nil {
+		return 0, fmt.Errorf("Could not fetch from origin:\n%s", err)
+	}
+
+	ahead, behind, err := repo.AheadBehind(branch, "origin/"+branch)
 	if err != nil {
-		return "", fmt.Errorf("%s- %s", out, err)
+		// The branch has never been pushed, every commit on it is unpushed
+		count, countErr := repo.CommitCount(branch)
+		if countErr != nil {
+			return 0, err
+		}
+		return count, nil
+	}
+	if ahead > 0 && behind > 0 {
+		return 0, fmt.Errorf("Your branch and 'origin/%s' have diverged (%d local and %d remote commits). Pull and resolve before publishing.", branch, ahead, behind)
+	}
+	if behind > 0 {
+		return 0, fmt.Errorf("Your branch is %d commits behind 'origin/%s'. Pull before publishing so Learn releases what you see locally.", behind, branch)
+	}
+
+	return ahead, nil
+}
+
+// releaseTarget describes what a release is built from: the HEAD of the default branch when
+// empty, a pinned commit when ref is set, or a branch for a single cohort when cohortID is set
+type releaseTarget struct {
+	ref      string
+	branch   string
+	cohortID int
+}
+
+// create asks Learn to build the release, returning its id
+func (t releaseTarget) create(blockID int) (int, error) {
+	if t.cohortID != 0 {
+		return learn.API.CreateCohortBranchRelease(blockID, t.cohortID, t.branch)
+	}
+	if t.ref != "" {
+		return learn.API.CreateReleaseAtRef(blockID, t.ref)
+	}
+	return learn.API.CreateMasterRelease(blockID)
+}
+
+// releasedMessage is shown when Learn finishes building the release
+func (t releaseTarget) releasedMessage(blockID int) string {
+	if t.cohortID != 0 {
+		return fmt.Sprintf("Block %d released from branch %s for cohort %d!\n", blockID, t.branch, t.cohortID)
+	}
+	if t.ref != "" {
+		return fmt.Sprintf("Block %d released at %s!\n", blockID, t.ref)
+	}
+	return fmt.Sprintf("Block %d released!\n", blockID)
+}
+
+// remoteName returns the repository name of the origin push url, which Learn uses as the
+// block's repo name
+func remoteName(repo *gitutil.Repo) (string, error) {
+	u, err := repo.RemoteURL("origin")
+	if err != nil {
+		return "", err
+	}
+
+	remote, err := gitutil.ParseRemoteURL(u)
+	if err != nil {
+		return "", err
+	}
+
+	return remote.Name, nil
+}
+
+// remoteDefaultBranch asks the origin remote which branch its HEAD points to, so repos
+// using main (or anything else) as a default branch publish correctly
+func remoteDefaultBranch(repo *gitutil.Repo) (string, error) {
+	branch, err := repo.DefaultBranch("origin")
+	if err != nil {
+		var gitErr *gitutil.Error
+		if errors.As(err, &gitErr) {
+			return "", err
+		}
+		return fallbackDefaultBranch, nil
+	}
+
+	return branch, nil
+}
+
+func addAutoConfigAndCommit(repo *gitutil.Repo) error {
+	if err := repo.Add(autoConfigFiles...); err != nil {
+		return err
+	}
+
+	staged, err := repo.HasStagedChanges()
+	if err != nil || !staged {
+		return err
 	}
 
-	return strings.TrimSpace(string(out)), nil
+	return repo.Commit("learn cli tool publish command: adding autoconfig.yaml and autoconfig.lock")
 }
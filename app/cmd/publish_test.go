@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+	"github.com/gSchool/glearn-cli/gitutil"
+)
+
+func withPromptInput(input string, f func()) {
+	reader, out := promptReader, promptOut
+	promptReader = bufio.NewReader(strings.NewReader(input))
+	promptOut = ioutil.Discard
+	defer func() { promptReader, promptOut = reader, out }()
+	f()
+}
+
+// gitIn runs a git command in dir, failing the test on error
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func Test_selectCohort(t *testing.T) {
+	block := learn.Block{ID: 1, CohortsUsing: []int{7, 9, 2}}
+
+	tableTest := map[string]int{
+		"#2\n": 9, // by list number
+		"9\n":  9, // by cohort id
+		"2\n":  2, // a cohort id that is also a list number
+		"#1":   7, // no trailing newline
+	}
+	for input, expected := range tableTest {
+		withPromptInput(input, func() {
+			id, err := selectCohort(block)
+			if err != nil || id != expected {
+				t.Errorf("selectCohort with input %q expected %d, got %d %v", input, expected, id, err)
+			}
+		})
+	}
+
+	for _, input := range []string{"42\n", "1\n", "#4\n", "#x\n"} {
+		withPromptInput(input, func() {
+			if _, err := selectCohort(block); err == nil {
+				t.Errorf("selectCohort with input %q should reject a cohort that is not listed", input)
+			}
+		})
+	}
+
+	if _, err := selectCohort(learn.Block{ID: 1}); err == nil {
+		t.Errorf("selectCohort should error when no cohorts use the block")
+	}
+}
+
+func Test_publishFlagsError(t *testing.T) {
+	if err := publishFlagsError("v1.2", 12); err == nil || err.Error() != "--ref cannot be combined with --cohort" {
+		t.Errorf("--ref with --cohort should be rejected, got %v", err)
+	}
+	for _, cohort := range []int{0, 12} {
+		if err := publishFlagsError("", cohort); err != nil {
+			t.Errorf("--cohort %d without --ref should be allowed, got %s", cohort, err)
+		}
+	}
+	if err := publishFlagsError("v1.2", 0); err != nil {
+		t.Errorf("--ref without --cohort should be allowed, got %s", err)
+	}
+}
+
+func Test_checkWorkingTree(t *testing.T) {
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	local := filepath.Join(root, "local")
+	other := filepath.Join(root, "other")
+	gitIn(t, root, "init", "--quiet", "--bare", "--initial-branch=main", origin)
+	gitIn(t, root, "clone", "--quiet", origin, local)
+	gitIn(t, local, "checkout", "--quiet", "-b", "main")
+	ioutil.WriteFile(filepath.Join(local, "autoconfig.yaml"), []byte("Standards: []\n"), 0644)
+	gitIn(t, local, "add", ".")
+	gitIn(t, local, "commit", "--quiet", "-m", "first")
+	gitIn(t, local, "push", "--quiet", "origin", "main")
+	gitIn(t, root, "clone", "--quiet", origin, other)
+	repo := gitutil.New(local)
+
+	// A regenerated autoconfig is committed by publish, even when it is the first change listed
+	ioutil.WriteFile(filepath.Join(local, "autoconfig.yaml"), []byte("Standards:\n  -\n"), 0644)
+	if ahead, err := checkWorkingTree(repo, "main"); err != nil || ahead != 0 {
+		t.Errorf("a modified autoconfig.yaml should not block publishing, got %d %v", ahead, err)
+	}
+	gitIn(t, local, "checkout", "--quiet", "--", "autoconfig.yaml")
+
+	ioutil.WriteFile(filepath.Join(other, "remote.md"), []byte("remote"), 0644)
+	gitIn(t, other, "add", ".")
+	gitIn(t, other, "commit", "--quiet", "-m", "remote")
+	gitIn(t, other, "push", "--quiet", "origin", "main")
+
+	_, err := checkWorkingTree(repo, "main")
+	if err == nil || !strings.Contains(err.Error(), "1 commits behind") || strings.Contains(err.Error(), "diverged") {
+		t.Errorf("a branch only behind origin should be told to pull, got %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(local, "local.md"), []byte("local"), 0644)
+	gitIn(t, local, "add", ".")
+	gitIn(t, local, "commit", "--quiet", "-m", "local")
+	_, err = checkWorkingTree(repo, "main")
+	if err == nil || !strings.Contains(err.Error(), "have diverged (1 local and 1 remote commits)") {
+		t.Errorf("a branch ahead of and behind origin has diverged, got %v", err)
+	}
+}
@@ -0,0 +1,423 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// QTI 2.1 namespaces, schemas and identifiers used in packages
+const (
+	qtiNamespace          = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiManifestNamespace  = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	qtiMatchCorrect       = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	qtiResourceType       = "imsqti_item_xmlv2p1"
+	qtiResponseIdentifier = "RESPONSE"
+	qtiManifestFile       = "imsmanifest.xml"
+)
+
+// qtiIdentifierChars are the characters not allowed in a QTI identifier
+var qtiIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// qtiInteractions are the interactions that map to a challenge type
+var qtiInteractions = map[string]struct{}{
+	"choiceInteraction":       {},
+	"textEntryInteraction":    {},
+	"extendedTextInteraction": {},
+}
+
+// qtiItem is an assessmentItem, one question in a QTI package
+type qtiItem struct {
+	XMLName        xml.Name           `xml:"assessmentItem"`
+	Xmlns          string             `xml:"xmlns,attr,omitempty"`
+	Identifier     string             `xml:"identifier,attr"`
+	Label          string             `xml:"label,attr,omitempty"`
+	Title          string             `xml:"title,attr"`
+	Adaptive       string             `xml:"adaptive,attr"`
+	TimeDependent  string             `xml:"timeDependent,attr"`
+	Responses      []qtiResponse      `xml:"responseDeclaration"`
+	Outcomes       []qtiOutcome       `xml:"outcomeDeclaration"`
+	Body           qtiBody            `xml:"itemBody"`
+	Processing     *qtiProcessing     `xml:"responseProcessing"`
+	ModalFeedbacks []qtiModalFeedback `xml:"modalFeedback"`
+}
+
+type qtiResponse struct {
+	Identifier  string   `xml:"identifier,attr"`
+	Cardinality string   `xml:"cardinality,attr"`
+	BaseType    string   `xml:"baseType,attr"`
+	Correct     []string `xml:"correctResponse>value"`
+}
+
+type qtiOutcome struct {
+	Identifier  string `xml:"identifier,attr"`
+	Cardinality string `xml:"cardinality,attr"`
+	BaseType    string `xml:"baseType,attr"`
+}
+
+// qtiBody is written from its fields, but read by walking its XML since other tools lay out
+// the question text in many ways
+type qtiBody struct {
+	Question     string           `xml:"div,omitempty"`
+	Choice       *qtiChoice       `xml:"choiceInteraction"`
+	TextEntry    *qtiTextEntry    `xml:"p>textEntryInteraction"`
+	ExtendedText *qtiExtendedText `xml:"extendedTextInteraction"`
+	Inner        []byte           `xml:",innerxml"`
+}
+
+type qtiChoice struct {
+	ResponseIdentifier string            `xml:"responseIdentifier,attr"`
+	Shuffle            string            `xml:"shuffle,attr"`
+	MaxChoices         int               `xml:"maxChoices,attr"`
+	Choices            []qtiSimpleChoice `xml:"simpleChoice"`
+}
+
+type qtiSimpleChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	Text       string `xml:",chardata"`
+}
+
+type qtiTextEntry struct {
+	ResponseIdentifier string `xml:"responseIdentifier,attr"`
+	PlaceholderText    string `xml:"placeholderText,attr,omitempty"`
+}
+
+type qtiExtendedText struct {
+	ResponseIdentifier string `xml:"responseIdentifier,attr"`
+	PlaceholderText    string `xml:"placeholderText,attr,omitempty"`
+}
+
+type qtiProcessing struct {
+	Template string `xml:"template,attr"`
+}
+
+type qtiModalFeedback struct {
+	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
+	ShowHide          string `xml:"showHide,attr"`
+	Identifier        string `xml:"identifier,attr"`
+	Text              string `xml:",chardata"`
+}
+
+// qtiManifest is the imsmanifest.xml listing the items of a package
+type qtiManifest struct {
+	XMLName    xml.Name      `xml:"manifest"`
+	Xmlns      string        `xml:"xmlns,attr,omitempty"`
+	Identifier string        `xml:"identifier,attr"`
+	Schema     string        `xml:"metadata>schema"`
+	Version    string        `xml:"metadata>schemaversion"`
+	Resources  []qtiResource `xml:"resources>resource"`
+}
+
+type qtiResource struct {
+	Identifier string `xml:"identifier,attr"`
+	Type       string `xml:"type,attr"`
+	Href       string `xml:"href,attr"`
+	File       struct {
+		Href string `xml:"href,attr"`
+	} `xml:"file"`
+}
+
+// qtiIdentifier makes id safe to use as a QTI identifier and file name
+func qtiIdentifier(id string) string {
+	id = qtiIdentifierChars.ReplaceAllString(id, "_")
+	if id == "" || !strings.ContainsAny(id[:1], "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_") {
+		id = "_" + id
+	}
+	return id
+}
+
+// challengeToQTI builds the assessmentItem for a challenge that unconvertible allows
+func challengeToQTI(c challenge) qtiItem {
+	item := qtiItem{
+		Xmlns:         qtiNamespace,
+		Identifier:    qtiIdentifier(c.ID),
+		Label:         c.ID,
+		Title:         c.Title,
+		Adaptive:      "false",
+		TimeDependent: "false",
+		Outcomes:      []qtiOutcome{{Identifier: "SCORE", Cardinality: "single", BaseType: "float"}},
+		Body:          qtiBody{Question: c.Question},
+	}
+	response := qtiResponse{Identifier: qtiResponseIdentifier, Cardinality: "single"}
+
+	switch c.Type {
+	case "multiple-choice", "checkbox":
+		choice := &qtiChoice{ResponseIdentifier: qtiResponseIdentifier, Shuffle: "false", MaxChoices: 1}
+		if c.Type == "checkbox" {
+			choice.MaxChoices = 0
+			response.Cardinality = "multiple"
+		}
+		response.BaseType = "identifier"
+
+		correct := map[string]struct{}{}
+		for _, a := range c.Answers {
+			correct[a] = struct{}{}
+		}
+		for i, o := range c.Options {
+			id := fmt.Sprintf("choice_%d", i+1)
+			choice.Choices = append(choice.Choices, qtiSimpleChoice{Identifier: id, Text: o})
+			if _, ok := correct[o]; ok {
+				response.Correct = append(response.Correct, id)
+			}
+		}
+		item.Body.Choice = choice
+	case "short-answer", "number":
+		response.BaseType = "string"
+		if c.Type == "number" {
+			response.BaseType = "float"
+		}
+		response.Correct = []string{strings.Join(c.Answers, "\n")}
+		item.Body.TextEntry = &qtiTextEntry{ResponseIdentifier: qtiResponseIdentifier, PlaceholderText: c.Placeholder}
+	case "paragraph":
+		response.BaseType = "string"
+		item.Body.ExtendedText = &qtiExtendedText{ResponseIdentifier: qtiResponseIdentifier, PlaceholderText: c.Placeholder}
+	}
+
+	item.Responses = []qtiResponse{response}
+	if c.Type != "paragraph" {
+		item.Processing = &qtiProcessing{Template: qtiMatchCorrect}
+	}
+	if c.Explanation != "" {
+		item.Outcomes = append(item.Outcomes, qtiOutcome{Identifier: "FEEDBACK", Cardinality: "single", BaseType: "identifier"})
+		item.ModalFeedbacks = []qtiModalFeedback{{OutcomeIdentifier: "FEEDBACK", ShowHide: "show", Identifier: "explanation", Text: c.Explanation}}
+	}
+
+	return item
+}
+
+// writeChallengesQTI writes challenges as a zipped QTI 2.1 package with one item per challenge,
+// returning notes about the challenges that were skipped or lost parts on the way
+func writeChallengesQTI(w io.Writer, challenges []challenge) ([]string, error) {
+	notes := []string{}
+	archive := zip.NewWriter(w)
+	manifest := qtiManifest{Xmlns: qtiManifestNamespace, Identifier: "learn-challenges", Schema: "QTIv2.1 Package", Version: "1.0.0"}
+
+	used := map[string]struct{}{}
+	for _, c := range challenges {
+		if reason := unconvertible(c); reason != "" {
+			notes = append(notes, challengeNote(c, "skipped, "+reason))
+			continue
+		}
+		if without := convertedWithout(c, true); without != "" {
+			notes = append(notes, challengeNote(c, without))
+		}
+
+		item := challengeToQTI(c)
+		if _, ok := used[item.Identifier]; ok {
+			notes = append(notes, challengeNote(c, "skipped, another challenge has the same id"))
+			continue
+		}
+		used[item.Identifier] = struct{}{}
+
+		href := "items/" + item.Identifier + ".xml"
+		if err := writeZippedXML(archive, href, item); err != nil {
+			return notes, err
+		}
+		resource := qtiResource{Identifier: item.Identifier, Type: qtiResourceType, Href: href}
+		resource.File.Href = href
+		manifest.Resources = append(manifest.Resources, resource)
+	}
+
+	if err := writeZippedXML(archive, qtiManifestFile, manifest); err != nil {
+		return notes, err
+	}
+	return notes, archive.Close()
+}
+
+// writeZippedXML adds v to the archive as an indented XML file called name
+func writeZippedXML(archive *zip.Writer, name string, v interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append([]byte(xml.Header), append(b, '\n')...))
+	return err
+}
+
+// readChallengesQTI reads the items of a zipped QTI package, or a single item .xml, as
+// challenges, returning notes about the items that have no matching challenge type
+func readChallengesQTI(file string) ([]challenge, []string, error) {
+	files := map[string][]byte{}
+	names := []string{}
+
+	if strings.ToLower(filepath.Ext(file)) == ".xml" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[filepath.Base(file)] = b
+		names = append(names, filepath.Base(file))
+	} else {
+		archive, err := zip.OpenReader(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s is not a QTI package: %s", file, err)
+		}
+		defer archive.Close()
+
+		for _, f := range archive.File {
+			if path.Ext(f.Name) != ".xml" || path.Base(f.Name) == qtiManifestFile {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return nil, nil, err
+			}
+			b, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			files[f.Name] = b
+			names = append(names, f.Name)
+		}
+	}
+
+	challenges := []challenge{}
+	notes := []string{}
+	for _, name := range names {
+		if !bytes.Contains(files[name], []byte("assessmentItem")) {
+			continue
+		}
+		c, err := qtiToChallenge(files[name])
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: skipped, %s", name, err))
+			continue
+		}
+		challenges = append(challenges, c)
+	}
+
+	return challenges, notes, nil
+}
+
+// qtiToChallenge reads an assessmentItem as a challenge
+func qtiToChallenge(b []byte) (challenge, error) {
+	item := qtiItem{}
+	if err := xml.Unmarshal(b, &item); err != nil {
+		return challenge{}, fmt.Errorf("it is not a QTI item: %s", err)
+	}
+
+	question, interactions, err := qtiBodyText(item.Body.Inner)
+	if err != nil {
+		return challenge{}, err
+	}
+	for _, name := range interactions {
+		if _, ok := qtiInteractions[name]; !ok {
+			return challenge{}, fmt.Errorf("%s questions have no equivalent", name)
+		}
+	}
+	if len(interactions) != 1 {
+		return challenge{}, fmt.Errorf("it has %d interactions, challenges have exactly one", len(interactions))
+	}
+
+	// identifiers can't hold every id, so the original is kept in the label
+	c := challenge{ID: item.Identifier, Title: item.Title, Question: question}
+	if item.Label != "" {
+		c.ID = item.Label
+	}
+	for _, f := range item.ModalFeedbacks {
+		if text := strings.TrimSpace(f.Text); text != "" {
+			c.Explanation = text
+		}
+	}
+	correct := []string{}
+	for _, r := range item.Responses {
+		if r.Identifier == qtiResponseIdentifier || len(item.Responses) == 1 {
+			correct = r.Correct
+		}
+	}
+
+	switch {
+	case item.Body.Choice != nil:
+		c.Type = "multiple-choice"
+		if item.Body.Choice.MaxChoices != 1 {
+			c.Type = "checkbox"
+		}
+		isCorrect := map[string]struct{}{}
+		for _, id := range correct {
+			isCorrect[id] = struct{}{}
+		}
+		for _, choice := range item.Body.Choice.Choices {
+			text := strings.TrimSpace(choice.Text)
+			c.Options = append(c.Options, text)
+			if _, ok := isCorrect[choice.Identifier]; ok {
+				c.Answers = append(c.Answers, text)
+			}
+		}
+	case item.Body.TextEntry != nil:
+		c.Type = "short-answer"
+		for _, r := range item.Responses {
+			if r.BaseType == "float" || r.BaseType == "integer" {
+				c.Type = "number"
+			}
+		}
+		c.Placeholder = item.Body.TextEntry.PlaceholderText
+		if len(correct) > 0 {
+			c.Answers = []string{correct[0]}
+		}
+	case item.Body.ExtendedText != nil:
+		c.Type = "paragraph"
+		c.Placeholder = item.Body.ExtendedText.PlaceholderText
+	default:
+		return c, fmt.Errorf("its interaction is not where challenges can be read from")
+	}
+
+	return c, nil
+}
+
+// qtiBodyText collects the text of an itemBody outside of its interactions, which it names.
+// Blocks of text are separated by blank lines
+func qtiBodyText(inner []byte) (string, []string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(inner))
+	blocks := []string{}
+	interactions := []string{}
+	var current strings.Builder
+
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		current.Reset()
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", nil, fmt.Errorf("its itemBody is not valid XML: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if strings.HasSuffix(t.Name.Local, "Interaction") {
+				interactions = append(interactions, t.Name.Local)
+				if err = decoder.Skip(); err != nil {
+					return "", nil, err
+				}
+			} else if t.Name.Local == "br" {
+				current.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "div", "li", "pre", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote":
+				flush()
+			}
+		case xml.CharData:
+			current.Write(t)
+		}
+	}
+	flush()
+
+	return strings.Join(blocks, "\n\n"), interactions, nil
+}
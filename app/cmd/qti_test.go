@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_QTIRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-qti")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	challenges := append(append([]challenge{}, convertibleChallenges...),
+		challenge{ID: "8f7e2a4c-uuid", Type: "short-answer", Title: "Placeholder", Question: "Name?", Placeholder: "your name", Answers: []string{"Ada"}},
+		challenge{ID: "tp", Type: "testable-project", Question: "Fork it"},
+	)
+
+	packagePath := filepath.Join(dir, "quiz.zip")
+	f, err := os.Create(packagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, err := writeChallengesQTI(f, challenges)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(notes, "\n") != "tp: skipped, testable-project challenges have no equivalent" {
+		t.Errorf("unexpected notes %v", notes)
+	}
+
+	archive, err := zip.OpenReader(packagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	archive.Close()
+	if names[len(names)-1] != qtiManifestFile || !strings.Contains(strings.Join(names, ","), "items/_8f7e2a4c-uuid.xml") {
+		t.Errorf("the package should hold an item per challenge and a manifest, got %v", names)
+	}
+
+	read, notes, err := readChallengesQTI(packagePath)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("reading the package errored: %v %v", err, notes)
+	}
+	expected := challenges[:len(challenges)-1]
+	if len(read) != len(expected) {
+		t.Fatalf("expected %d items, got %d", len(expected), len(read))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(read[i], expected[i]) {
+			t.Errorf("item %d expected %+v\nbut got %+v", i, expected[i], read[i])
+		}
+	}
+}
+
+func Test_qtiToChallengeFromOtherTools(t *testing.T) {
+	item := `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Capital">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>B</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <p>What is the capital</p>
+    <p>of <b>France</b>?</p>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="1">
+      <prompt>Choose one</prompt>
+      <simpleChoice identifier="A">Lyon</simpleChoice>
+      <simpleChoice identifier="B">Paris</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+
+	c, err := qtiToChallenge([]byte(item))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != "q1" || c.Type != "multiple-choice" || c.Question != "What is the capital\n\nof France?" || strings.Join(c.Answers, ",") != "Paris" {
+		t.Errorf("unexpected challenge %+v", c)
+	}
+
+	matching := strings.Replace(item, "choiceInteraction", "matchInteraction", -1)
+	if _, err = qtiToChallenge([]byte(matching)); err == nil || !strings.Contains(err.Error(), "matchInteraction questions have no equivalent") {
+		t.Errorf("interactions without a challenge type should be reported, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+	"github.com/gSchool/glearn-cli/gitutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var releasesCmd = &cobra.Command{
+	Use:   "releases [--repo name]",
+	Short: "List the releases of a block",
+	Long: `
+Lists every release Learn has built for a block, newest first, with the commit
+it was built from, who created it, its build status and any sync warnings. The
+current release is marked with a *. Run it from inside a block repository or
+pass the block's repo name with --repo.
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
+			fmt.Print(setAPITokenMessage)
+			os.Exit(1)
+		}
+
+		setupLearnAPI()
+
+		block := lookupBlock(RepoName)
+
+		releases, err := learn.API.GetReleases(block.ID)
+		if err != nil {
+			fmt.Printf("Error fetching releases from learn: %s\n", err)
+			os.Exit(1)
+		}
+		if len(releases) == 0 {
+			fmt.Printf("Block %s has no releases yet. Run `learn publish` to create one.\n", block.RepoName)
+			return
+		}
+
+		printReleases(os.Stdout, releases)
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <release-id> [--repo name]",
+	Short: "Make a previous release of a block current",
+	Long: `
+Makes a previous release of a block the current release on Learn, for when a
+publish introduced sync errors. Find release ids with 'learn releases'. Run it
+from inside a block repository or pass the block's repo name with --repo.
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.Get("api_token") == "" || viper.Get("api_token") == nil {
+			fmt.Print(setAPITokenMessage)
+			os.Exit(1)
+		}
+
+		setupLearnAPI()
+
+		releaseID, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Printf("'%s' is not a release id, find ids with `learn releases`\n", args[0])
+			os.Exit(1)
+		}
+
+		block := lookupBlock(RepoName)
+
+		releases, err := learn.API.GetReleases(block.ID)
+		if err != nil {
+			fmt.Printf("Error fetching releases from learn: %s\n", err)
+			os.Exit(1)
+		}
+
+		var target *learn.Release
+		for i := range releases {
+			if releases[i].ID == releaseID {
+				target = &releases[i]
+			}
+		}
+		if target == nil {
+			fmt.Printf("Block %s has no release with id %d, find ids with `learn releases`\n", block.RepoName, releaseID)
+			os.Exit(1)
+		}
+		if target.Current {
+			fmt.Printf("Release %d is already the current release of %s\n", releaseID, block.RepoName)
+			return
+		}
+
+		printReleases(os.Stdout, []learn.Release{*target})
+		fmt.Println()
+		if !RollbackYes && !confirm(fmt.Sprintf("Make release %d current for %s and the %d cohort(s) using it?", releaseID, block.RepoName, len(block.CohortsUsing))) {
+			fmt.Println("Rollback cancelled.")
+			return
+		}
+
+		if _, err = learn.API.RollbackRelease(block.ID, releaseID); err != nil {
+			fmt.Printf("Error rolling back to release %d: %s\n", releaseID, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Release %d (%s) is now the current release of %s\n", releaseID, shortSHA(target.Commit), block.RepoName)
+	},
+}
+
+// lookupBlock fetches the block for repoName, or for the origin remote of the current
+// directory when repoName is empty. Exits when no block exists
+func lookupBlock(repoName string) learn.Block {
+	if repoName == "" {
+		path, _ := os.Getwd()
+		name, err := remoteName(gitutil.New(path))
+		if err != nil {
+			fmt.Printf("Cannot detect the origin remote of this repository, pass --repo instead:\n%s\n", err)
+			os.Exit(1)
+		}
+		repoName = name
+	}
+
+	block, err := learn.API.GetBlockByRepoName(repoName)
+	if err != nil {
+		fmt.Printf("Error fetching block from learn: %s\n", err)
+		os.Exit(1)
+	}
+	if !block.Exists() {
+		fmt.Printf("No block exists on Learn for repo %s\n", repoName)
+		os.Exit(1)
+	}
+
+	return block
+}
+
+// printReleases writes releases as a table followed by the warnings of each release
+func printReleases(out io.Writer, releases []learn.Release) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tID\tCOMMIT\tCREATED AT\tAUTHOR\tSTATUS\tWARNINGS")
+	for _, r := range releases {
+		current := ""
+		if r.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%d\n", current, r.ID, shortSHA(r.Commit), r.CreatedAt, r.Author, r.Status, len(r.SyncWarnings))
+	}
+	w.Flush()
+
+	for _, r := range releases {
+		if len(r.SyncWarnings) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\nWarnings on release %d:\n", r.ID)
+		for _, warning := range r.SyncWarnings {
+			fmt.Fprintln(out, "  -", warning)
+		}
+	}
+}
+
+// shortSHA abbreviates a commit sha for display
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
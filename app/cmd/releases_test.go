@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gSchool/glearn-cli/api/learn"
+)
+
+func Test_printReleases(t *testing.T) {
+	var out bytes.Buffer
+	printReleases(&out, []learn.Release{
+		{ID: 12, Commit: "0123456789abcdef", CreatedAt: "2020-07-23T10:00:00Z", Author: "a@example.com", Status: "success", SyncWarnings: []string{"missing image"}, Current: true},
+		{ID: 11, Commit: "fedcba9876543210", CreatedAt: "2020-07-22T10:00:00Z", Author: "b@example.com", Status: "failed"},
+	})
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[1], "*  12  0123456  2020-07-23T10:00:00Z  a@example.com  success  1") {
+		t.Errorf("current release should be marked and abbreviated, got '%s'", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "   11  fedcba9  2020-07-22T10:00:00Z  b@example.com  failed   0") {
+		t.Errorf("second release row was '%s'", lines[2])
+	}
+	if !strings.Contains(out.String(), "Warnings on release 12:\n  - missing image") {
+		t.Errorf("warnings should be listed under the table, got:\n%s", out.String())
+	}
+}
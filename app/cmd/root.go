@@ -33,7 +33,7 @@ Edit existing curriculum:
   1. Clone and edit curriculum
   2. Preview your changes. Run:
       learn preview -o <directory|file>
-  3. Git add / commit / push changes to the master branch
+  3. Git add / commit / push changes to the default (master or main) branch
   4. Publish changes for any cohort in Learn. Run:
       learn publish
 
@@ -83,9 +83,87 @@ var UnitsDirectory string
 // and only upload the markdown file
 var FileOnly bool
 
+// PreviewDepth is how many links away from a single file preview linked files are included
+var PreviewDepth int
+
 // OpenPreview is the flag boolean which will open the preview in browser
 var OpenPreview bool
 
+// PublishRef is a commit sha or tag on the remote to publish instead of the default branch HEAD
+var PublishRef string
+
+// PublishYes skips the confirmation prompt after the publish summary
+var PublishYes bool
+
+// PublishCohort is the cohort a branch is published for
+var PublishCohort int
+
+// PublishDryRun runs every publish check and lookup without pushing or releasing
+var PublishDryRun bool
+
+// PublishAll is a directory whose block repositories are all published
+var PublishAll string
+
+// PreviewAll is a directory whose block repositories are all previewed
+var PreviewAll string
+
+// BatchConcurrency is how many blocks --all works on at once
+var BatchConcurrency int
+
+// ConfigForce lets config generate replace an existing config.yaml
+var ConfigForce bool
+
+// InteractiveTemplate builds a challenge for learn md by asking for each part of it
+var InteractiveTemplate bool
+
+// InsertAfterHeading adds a learn md template after this heading instead of appending it
+var InsertAfterHeading string
+
+// InsertLine adds a learn md template at this line instead of appending it
+var InsertLine int
+
+// ReplaceChallenge replaces the challenge with this id with a learn md template
+var ReplaceChallenge string
+
+// ChallengesFormat is the format challenges are exported in, json or csv
+var ChallengesFormat string
+
+// ChallengesOut is the file challenges are exported to, instead of stdout
+var ChallengesOut string
+
+// ChallengesInto is the markdown file imported challenges are added to
+var ChallengesInto string
+
+// DockerChallengeID is the id of the custom-snippet challenge run with Docker
+var DockerChallengeID string
+
+// DockerSubmission is a file to run as the submission instead of the challenge's solution
+var DockerSubmission string
+
+// LinksExternal is a flag for links check to request http and https links too
+var LinksExternal bool
+
+// DockerEngine is the container CLI used to build and run custom-snippet challenges
+var DockerEngine string
+
+// WalkthroughFrom is a git URL to copy the walkthrough curriculum from instead of the built-in one
+var WalkthroughFrom string
+
+// WalkthroughDir is where the walkthrough curriculum is added
+var WalkthroughDir string
+
+// RepoName is a flag for commands that act on a block other than the current repository
+var RepoName string
+
+// RollbackYes skips the confirmation before a rollback
+var RollbackYes bool
+
+// CourseYes skips the confirmation before releasing every repo in a course
+var CourseYes bool
+
+// BlockJSON prints block command output as JSON instead of a table
+var BlockJSON bool
+
 func init() {
 	u, err := user.Current()
 	if err != nil {
@@ -122,6 +200,31 @@ func init() {
 	rootCmd.AddCommand(markdownCmd)
 	rootCmd.AddCommand(previewCmd)
 	rootCmd.AddCommand(publishCmd)
+	rootCmd.AddCommand(releasesCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(blockCmd)
+	blockCmd.AddCommand(blockShowCmd)
+	blockCmd.AddCommand(blockCreateCmd)
+	blockCmd.AddCommand(blockListCmd)
+	rootCmd.AddCommand(courseCmd)
+	courseCmd.AddCommand(courseValidateCmd)
+	courseCmd.AddCommand(coursePublishCmd)
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGenerateCmd)
+	configCmd.AddCommand(configSyncCmd)
+	rootCmd.AddCommand(newCmd)
+	newCmd.AddCommand(newBlockCmd)
+	newCmd.AddCommand(newUnitCmd)
+	newCmd.AddCommand(newLessonCmd)
+	rootCmd.AddCommand(challengesCmd)
+	challengesCmd.AddCommand(challengesExportCmd)
+	challengesCmd.AddCommand(challengesImportCmd)
+	challengesCmd.AddCommand(challengesTestCmd)
+	challengesCmd.AddCommand(challengesRunDockerCmd)
+	rootCmd.AddCommand(linksCmd)
+	linksCmd.AddCommand(linksCheckCmd)
+	rootCmd.AddCommand(nbCmd)
+	nbCmd.AddCommand(nbStripCmd)
 	rootCmd.AddCommand(guideCmd)
 	rootCmd.AddCommand(setCmd)
 	rootCmd.AddCommand(versionCmd)
@@ -131,8 +234,43 @@ func init() {
 	previewCmd.Flags().StringVarP(&UnitsDirectory, "units", "u", "", "The directory where your units exist")
 	previewCmd.Flags().BoolVarP(&OpenPreview, "open", "o", false, "Open the preview in the browser")
 	previewCmd.Flags().BoolVarP(&FileOnly, "fileonly", "x", false, "E(x)cludes images when previewing a single file, defaults false")
+	previewCmd.Flags().IntVarP(&PreviewDepth, "depth", "", 0, "How many links away from a single file linked files are included, 0 follows every link")
+	previewCmd.Flags().StringVarP(&PreviewAll, "all", "", "", "Preview every block repository under this directory")
+	previewCmd.Flags().IntVarP(&BatchConcurrency, "concurrency", "", defaultBatchConcurrency, "How many blocks to work on at once with --all")
 	publishCmd.Flags().StringVarP(&UnitsDirectory, "units", "u", "", "The directory where your units exist")
+	publishCmd.Flags().StringVarP(&PublishRef, "ref", "r", "", "A commit sha or tag on the remote to publish instead of the default branch HEAD")
+	publishCmd.Flags().IntVarP(&PublishCohort, "cohort", "c", 0, "Publish the current branch for this cohort only")
+	publishCmd.Flags().BoolVarP(&PublishYes, "yes", "y", false, "Skip the confirmation after the publish summary")
+	publishCmd.Flags().BoolVarP(&PublishDryRun, "dry-run", "", false, "Run every check and lookup without pushing or releasing")
+	publishCmd.Flags().StringVarP(&PublishAll, "all", "", "", "Publish every block repository under this directory")
+	publishCmd.Flags().IntVarP(&BatchConcurrency, "concurrency", "", defaultBatchConcurrency, "How many blocks to work on at once with --all")
+	releasesCmd.Flags().StringVarP(&RepoName, "repo", "", "", "The repo name of the block, defaults to the current repository")
+	rollbackCmd.Flags().StringVarP(&RepoName, "repo", "", "", "The repo name of the block, defaults to the current repository")
+	rollbackCmd.Flags().BoolVarP(&RollbackYes, "yes", "y", false, "Skip the confirmation before rolling back")
+	blockShowCmd.Flags().StringVarP(&RepoName, "repo", "", "", "The repo name of the block, defaults to the current repository")
+	blockCreateCmd.Flags().StringVarP(&RepoName, "repo", "", "", "The repo name of the block, defaults to the current repository")
+	blockCmd.PersistentFlags().BoolVarP(&BlockJSON, "json", "", false, "Print output as JSON")
+	coursePublishCmd.Flags().BoolVarP(&CourseYes, "yes", "y", false, "Skip the confirmation before releasing every repo")
+	configGenerateCmd.Flags().StringVarP(&UnitsDirectory, "units", "u", "", "The directory where your units exist")
+	configGenerateCmd.Flags().BoolVarP(&ConfigForce, "force", "f", false, "Replace an existing config.yaml")
+	configSyncCmd.Flags().StringVarP(&UnitsDirectory, "units", "u", "", "The directory where your units exist")
+	newCmd.PersistentFlags().StringVarP(&UnitsDirectory, "units", "u", "", "The directory where your units exist, defaults to units")
+	challengesExportCmd.Flags().StringVarP(&ChallengesFormat, "format", "f", "json", "The format to export, json, csv, gift or qti")
+	challengesExportCmd.Flags().StringVarP(&ChallengesOut, "out", "o", "", "A file to write the export to, instead of printing it")
+	challengesImportCmd.Flags().StringVarP(&ChallengesInto, "into", "", "", "A markdown file to add the challenges to, instead of printing them")
+	challengesRunDockerCmd.Flags().StringVarP(&DockerChallengeID, "id", "", "", "The id of the custom-snippet challenge to run")
+	challengesRunDockerCmd.MarkFlagRequired("id")
+	challengesRunDockerCmd.Flags().StringVarP(&DockerSubmission, "submission", "s", "", "A file to run as the submission, defaults to the !solution or !placeholder")
+	challengesRunDockerCmd.Flags().StringVarP(&DockerEngine, "engine", "", "", "docker, podman or the path to a compatible CLI, defaults to whichever is installed")
+	linksCheckCmd.Flags().BoolVarP(&LinksExternal, "external", "", false, "Request http and https links and report the ones that fail")
+	guideCmd.Flags().StringVarP(&WalkthroughFrom, "from", "", "", "A git URL to copy the curriculum from instead of the built-in example")
+	guideCmd.Flags().StringVarP(&WalkthroughDir, "dir", "d", "", "The directory to add the curriculum to, defaults to the current directory")
+	markdownCmd.SetHelpFunc(markdownHelp)
 	markdownCmd.Flags().BoolVarP(&PrintTemplate, "out", "o", false, "Prints the template to stdout")
+	markdownCmd.Flags().StringVarP(&InsertAfterHeading, "after-heading", "", "", "Add the template after this heading instead of appending it")
+	markdownCmd.Flags().IntVarP(&InsertLine, "line", "", 0, "Add the template at this line instead of appending it")
+	markdownCmd.Flags().StringVarP(&ReplaceChallenge, "replace", "", "", "Replace the challenge with this id with the template")
+	markdownCmd.Flags().BoolVarP(&InteractiveTemplate, "interactive", "i", false, "Build a complete challenge by answering questions")
 }
 
 // Execute runs the learn CLI according to the user's command/subcommand/flags
@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// snippetTimeout is how long the tests of one challenge can run
+const snippetTimeout = 60 * time.Second
+
+// junitClasspathEnv names the classpath holding JUnit 4 used to run Java tests
+const junitClasspathEnv = "LEARN_JUNIT_CLASSPATH"
+
+var challengesTestCmd = &cobra.Command{
+	Use:   "test <file.md>",
+	Short: "Run the tests of code-snippet challenges against a reference solution",
+	Long: `
+Runs the !tests of every code-snippet challenge in a markdown file with the
+toolchains installed on this computer, and reports whether each one passes.
+
+The code tested is the challenge's !solution section, which students never see:
+
+  ##### !solution
+
+  ~~~py
+  def doSomething():
+    return 1
+  ~~~
+
+  ##### !end-solution
+
+Challenges without a !solution are tested with their !placeholder. Each
+language needs its toolchain on the PATH:
+
+  python     python3, running the unittest tests against main.py
+  javascript node, running the mocha style tests (with chai when it is installed)
+  java       javac and java, with JUnit 4 on the ` + junitClasspathEnv + ` classpath
+  sql        sqlite3, loading the .sql files in data_path and comparing results
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := testSnippetFile(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if failed := printSnippetResults(os.Stdout, results); failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// snippet is the code of a code-snippet challenge ready to run
+type snippet struct {
+	id       string
+	language string
+	setup    string
+	code     string
+	tests    string
+	dataPath string
+
+	// fromPlaceholder is true when there was no !solution to test
+	fromPlaceholder bool
+}
+
+// snippetResult is the outcome of testing one challenge
+type snippetResult struct {
+	id       string
+	language string
+	status   string
+	detail   string
+	output   string
+}
+
+// snippetRunner runs the tests of a snippet in dir, returning their output. An error means
+// the tests failed, tools are the programs it needs on the PATH
+type snippetRunner struct {
+	tools []string
+	run   func(ctx context.Context, dir string, s snippet) (string, error)
+}
+
+// snippetRunnerFor picks the runner for a challenge language
+func snippetRunnerFor(language string) (snippetRunner, bool) {
+	switch {
+	case strings.HasPrefix(language, "python"):
+		return snippetRunner{[]string{"python3"}, runPythonSnippet}, true
+	case language == "javascript" || language == "js" || language == "node":
+		return snippetRunner{[]string{"node"}, runJavaScriptSnippet}, true
+	case language == "java":
+		return snippetRunner{[]string{"javac", "java"}, runJavaSnippet}, true
+	case language == "sql":
+		return snippetRunner{[]string{"sqlite3"}, runSQLSnippet}, true
+	}
+	return snippetRunner{}, false
+}
+
+// sectionCode is the first fenced code block in a section, or all of it when there isn't one.
+// The text around the block tells authors what the code is for
+func sectionCode(content string) string {
+	lines := strings.Split(content, "\n")
+	start := -1
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if start == -1 {
+			if match := fenceLine.FindStringSubmatch(line); match != nil {
+				start, fence = i, match[1]
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			return strings.Join(lines[start+1:i], "\n")
+		}
+	}
+	if start != -1 {
+		return strings.Join(lines[start+1:], "\n")
+	}
+	return content
+}
+
+// snippetFromChallenge collects the code of a code-snippet challenge
+func snippetFromChallenge(c challenge, blockRoot string) snippet {
+	s := snippet{id: c.ID, language: c.attribute("language")}
+	solution := ""
+	for _, section := range c.Sections {
+		switch section.Name {
+		case "setup":
+			s.setup = sectionCode(section.Content)
+		case "tests":
+			s.tests = sectionCode(section.Content)
+		case "solution":
+			solution = sectionCode(section.Content)
+		}
+	}
+
+	s.code = solution
+	if solution == "" {
+		s.code = sectionCode(c.Placeholder)
+		s.fromPlaceholder = true
+	}
+	if dataPath := c.attribute("data_path"); dataPath != "" {
+		s.dataPath = filepath.Join(blockRoot, filepath.FromSlash(strings.TrimPrefix(dataPath, "/")))
+	}
+
+	return s
+}
+
+// testSnippetFile runs the tests of every code-snippet challenge in the markdown file at path
+func testSnippetFile(path string) ([]snippetResult, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	challenges, err := parseChallenges(path, string(b))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the challenges in %s, %s", path, err)
+	}
+
+	blockRoot := findBlockRoot(filepath.Dir(path))
+	results := []snippetResult{}
+	for _, c := range challenges {
+		if c.Type != "code-snippet" {
+			continue
+		}
+		results = append(results, testSnippet(snippetFromChallenge(c, blockRoot)))
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%s has no code-snippet challenges to test", path)
+	}
+	return results, nil
+}
+
+// testSnippet runs one snippet's tests in a temporary directory
+func testSnippet(s snippet) snippetResult {
+	result := snippetResult{id: s.id, language: s.language}
+
+	runner, ok := snippetRunnerFor(s.language)
+	if !ok {
+		result.status, result.detail = "SKIP", fmt.Sprintf("no local runner for language '%s'", s.language)
+		return result
+	}
+	for _, tool := range runner.tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			result.status, result.detail = "SKIP", tool+" is not installed"
+			return result
+		}
+	}
+	if strings.TrimSpace(s.tests) == "" {
+		result.status, result.detail = "SKIP", "the challenge has no !tests"
+		return result
+	}
+
+	dir, err := ioutil.TempDir("", "learn-snippet")
+	if err != nil {
+		result.status, result.detail = "FAIL", err.Error()
+		return result
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), snippetTimeout)
+	defer cancel()
+
+	result.output, err = runner.run(ctx, dir, s)
+	switch {
+	case ctx.Err() != nil:
+		result.status, result.detail = "FAIL", fmt.Sprintf("timed out after %s", snippetTimeout)
+	case err != nil:
+		result.status, result.detail = "FAIL", err.Error()
+	default:
+		result.status = "PASS"
+	}
+	if s.fromPlaceholder {
+		result.detail = strings.TrimPrefix(result.detail+", tested the placeholder as there is no !solution", ", ")
+	}
+	if errSkip, ok := err.(snippetSkip); ok {
+		result.status, result.detail = "SKIP", string(errSkip)
+	}
+
+	return result
+}
+
+// snippetSkip is returned by runners that find they can't run the tests here
+type snippetSkip string
+
+func (s snippetSkip) Error() string { return string(s) }
+
+// runSnippetCommand runs a command in dir, returning its combined output
+func runSnippetCommand(ctx context.Context, dir string, stdin io.Reader, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// runPythonSnippet writes the code to main.py, which the tests import, and runs them with unittest
+func runPythonSnippet(ctx context.Context, dir string, s snippet) (string, error) {
+	code := strings.TrimLeft(s.setup+"\n"+s.code, "\n") + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.py"), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "test_main.py"), []byte(s.tests+"\n"), 0644); err != nil {
+		return "", err
+	}
+
+	out, err := runSnippetCommand(ctx, dir, nil, "python3", "-m", "unittest", "-v", "test_main")
+	if err != nil {
+		return out, fmt.Errorf("unittest failed")
+	}
+	return out, nil
+}
+
+// runJavaScriptSnippet runs the code and the tests in one file with a small mocha style harness
+func runJavaScriptSnippet(ctx context.Context, dir string, s snippet) (string, error) {
+	code := strings.Join([]string{s.setup, s.code, s.tests}, "\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "snippet.js"), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "harness.js"), []byte(mochaHarness), 0644); err != nil {
+		return "", err
+	}
+
+	out, err := runSnippetCommand(ctx, dir, nil, "node", "harness.js")
+	if err != nil {
+		return out, fmt.Errorf("mocha tests failed")
+	}
+	return out, nil
+}
+
+// runJavaSnippet compiles the setup, code and tests as SnippetTest.java and runs it with JUnit
+func runJavaSnippet(ctx context.Context, dir string, s snippet) (string, error) {
+	classpath := os.Getenv(junitClasspathEnv)
+	if classpath == "" {
+		for _, entry := range filepath.SplitList(os.Getenv("CLASSPATH")) {
+			if strings.Contains(strings.ToLower(filepath.Base(entry)), "junit") {
+				classpath = os.Getenv("CLASSPATH")
+				break
+			}
+		}
+	}
+	if classpath == "" {
+		return "", snippetSkip(fmt.Sprintf("JUnit 4 was not found, set %s to its jars", junitClasspathEnv))
+	}
+
+	code := strings.Join([]string{"import org.junit.Test;", "import static org.junit.Assert.*;", s.setup, s.code, s.tests}, "\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "SnippetTest.java"), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	classpath = "." + string(filepath.ListSeparator) + classpath
+
+	if out, err := runSnippetCommand(ctx, dir, nil, "javac", "-cp", classpath, "SnippetTest.java"); err != nil {
+		return out, fmt.Errorf("SnippetTest.java did not compile")
+	}
+	out, err := runSnippetCommand(ctx, dir, nil, "java", "-cp", classpath, "org.junit.runner.JUnitCore", "SnippetTest")
+	if err != nil {
+		return out, fmt.Errorf("JUnit tests failed")
+	}
+	return out, nil
+}
+
+// runSQLSnippet loads the .sql files in the data path into a new database and checks the code
+// returns the same rows as the query in the tests
+func runSQLSnippet(ctx context.Context, dir string, s snippet) (string, error) {
+	if s.dataPath == "" {
+		return "", fmt.Errorf("the challenge has no data_path")
+	}
+	files := []string{s.dataPath}
+	if info, err := os.Stat(s.dataPath); err != nil {
+		return "", fmt.Errorf("data_path %s cannot be read: %s", s.dataPath, err)
+	} else if info.IsDir() {
+		files, _ = filepath.Glob(filepath.Join(s.dataPath, "*.sql"))
+		sort.Strings(files)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("there are no .sql files in data_path %s", s.dataPath)
+	}
+
+	db := filepath.Join(dir, "snippet.db")
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		if out, err := runSnippetCommand(ctx, dir, bytes.NewReader(data), "sqlite3", "-bail", db); err != nil {
+			return out, fmt.Errorf("%s could not be loaded", filepath.Base(file))
+		}
+	}
+
+	query := func(sql string) (string, error) {
+		return runSnippetCommand(ctx, dir, strings.NewReader(sql+"\n"), "sqlite3", "-bail", "-header", "-csv", db)
+	}
+	expected, err := query(s.tests)
+	if err != nil {
+		return expected, fmt.Errorf("the query in !tests failed")
+	}
+	actual, err := query(s.code)
+	if err != nil {
+		return actual, fmt.Errorf("the query failed")
+	}
+	if actual != expected {
+		return fmt.Sprintf("expected:\n%s\ngot:\n%s", expected, actual), fmt.Errorf("the query returned different rows than the query in !tests")
+	}
+
+	return actual, nil
+}
+
+// printSnippetResults writes a line per challenge, with the output of failures, and a summary.
+// It returns the number of failures
+func printSnippetResults(out io.Writer, results []snippetResult) int {
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		counts[r.status]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.status, r.id, r.language, r.detail)
+	}
+	w.Flush()
+
+	for _, r := range results {
+		if r.status == "FAIL" && strings.TrimSpace(r.output) != "" {
+			fmt.Fprintf(out, "\n--- %s output:\n%s\n", r.id, strings.TrimRight(r.output, "\n"))
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d passed, %d failed, %d skipped\n", counts["PASS"], counts["FAIL"], counts["SKIP"])
+	return counts["FAIL"]
+}
+
+// mochaHarness runs snippet.js, which holds the code and its mocha style tests. chai's expect
+// is used when it can be required, otherwise a small expect covering the common assertions
+const mochaHarness = `'use strict';
+const assert = require('assert');
+
+let chai = null;
+try { chai = require('chai'); } catch (e) {}
+
+function miniExpect(actual, message) {
+  const flags = { not: false, deep: false };
+  const check = (ok, description) => {
+    if (ok === flags.not) {
+      throw new assert.AssertionError({ message: message || 'expected ' + JSON.stringify(actual) + (flags.not ? ' not ' : ' ') + description });
+    }
+    return chain;
+  };
+  const equal = (expected) => {
+    let ok = true;
+    try {
+      flags.deep ? assert.deepStrictEqual(actual, expected) : assert.strictEqual(actual, expected);
+    } catch (e) { ok = false; }
+    return check(ok, 'to equal ' + JSON.stringify(expected));
+  };
+  const chain = {
+    equal, equals: equal, eq: equal,
+    eql: (expected) => { flags.deep = true; return equal(expected); },
+    a: (type) => check(Array.isArray(actual) ? type === 'array' : typeof actual === type, 'to be a ' + type),
+    include: (item) => check(actual != null && actual.includes(item), 'to include ' + JSON.stringify(item)),
+    above: (n) => check(actual > n, 'to be above ' + n),
+    below: (n) => check(actual < n, 'to be below ' + n),
+    least: (n) => check(actual >= n, 'to be at least ' + n),
+    most: (n) => check(actual <= n, 'to be at most ' + n),
+    lengthOf: (n) => check(actual != null && actual.length === n, 'to have length ' + n),
+    throw: () => { let threw = false; try { actual(); } catch (e) { threw = true; } return check(threw, 'to throw'); },
+  };
+  chain.an = chain.a;
+  chain.contain = chain.include;
+  chain.contains = chain.include;
+  chain.length = chain.lengthOf;
+  chain.equals = equal;
+  for (const word of ['to', 'be', 'been', 'is', 'that', 'which', 'and', 'has', 'have', 'with', 'at', 'of', 'same']) {
+    Object.defineProperty(chain, word, { get: () => chain });
+  }
+  Object.defineProperty(chain, 'not', { get: () => { flags.not = !flags.not; return chain; } });
+  Object.defineProperty(chain, 'deep', { get: () => { flags.deep = true; return chain; } });
+  const values = { true: true, false: false, null: null, undefined: undefined };
+  for (const name of Object.keys(values)) {
+    Object.defineProperty(chain, name, { get: () => check(actual === values[name], 'to be ' + name) });
+  }
+  Object.defineProperty(chain, 'ok', { get: () => check(!!actual, 'to be ok') });
+  Object.defineProperty(chain, 'empty', { get: () => check(actual != null && Object.keys(actual).length === 0, 'to be empty') });
+  return chain;
+}
+
+global.expect = chai ? chai.expect : miniExpect;
+global.assert = chai ? chai.assert : assert;
+
+const tests = [];
+const suites = [{ name: '', beforeEach: [], afterEach: [] }];
+global.describe = global.context = (name, fn) => {
+  suites.push({ name, beforeEach: [], afterEach: [] });
+  fn();
+  suites.pop();
+};
+global.it = global.specify = (name, fn) => {
+  const scope = suites.slice();
+  tests.push({
+    name: scope.map((s) => s.name).concat(name).filter(Boolean).join(' '),
+    fn,
+    beforeEach: [].concat(...scope.map((s) => s.beforeEach)),
+    afterEach: [].concat(...scope.map((s) => s.afterEach)),
+  });
+};
+global.beforeEach = (fn) => suites[suites.length - 1].beforeEach.push(fn);
+global.afterEach = (fn) => suites[suites.length - 1].afterEach.push(fn);
+global.before = (fn) => fn();
+global.after = () => {};
+
+function call(fn) {
+  if (fn.length > 0) {
+    return new Promise((resolve, reject) => fn((err) => (err ? reject(err) : resolve())));
+  }
+  return Promise.resolve().then(() => fn());
+}
+
+(async () => {
+  require('./snippet.js');
+  let failures = 0;
+  for (const test of tests) {
+    try {
+      for (const hook of test.beforeEach) await call(hook);
+      await Promise.race([
+        call(test.fn),
+        new Promise((_, reject) => setTimeout(() => reject(new Error('timed out after 2000ms')), 2000).unref()),
+      ]);
+      for (const hook of test.afterEach) await call(hook);
+      console.log('  ok   ' + test.name);
+    } catch (e) {
+      failures++;
+      console.log('  FAIL ' + test.name + ': ' + (e && e.message ? e.message : e));
+    }
+  }
+  console.log('\n' + (tests.length - failures) + ' passing, ' + failures + ' failing');
+  process.exit(failures > 0 || tests.length === 0 ? 1 : 0);
+})().catch((e) => {
+  console.log(e && e.stack ? e.stack : e);
+  process.exit(1);
+});
+`
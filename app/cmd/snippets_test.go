@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const snippetLesson = "# Snippets\n\n" +
+	"### !challenge\n\n* type: code-snippet\n* language: python3.6\n* id: py-pass\n* title: Python\n\n" +
+	"##### !question\n\nReturn one\n\n##### !end-question\n\n" +
+	"##### !placeholder\n\n```py\ndef one():\n  pass\n```\n\n##### !end-placeholder\n\n" +
+	"##### !tests\n\n```py\nimport unittest\nfrom main import *\n\nclass TestOne(unittest.TestCase):\n  def test_one(self):\n    self.assertEqual(one(), 1)\n```\n\n##### !end-tests\n\n" +
+	"##### !solution\n\n```py\ndef one():\n  return 1\n```\n\n##### !end-solution\n\n" +
+	"### !end-challenge\n\n" +
+	"### !challenge\n\n* type: code-snippet\n* language: python3.6\n* id: py-placeholder\n* title: Python placeholder\n\n" +
+	"##### !question\n\nReturn one\n\n##### !end-question\n\n" +
+	"##### !placeholder\n\n```py\ndef one():\n  pass\n```\n\n##### !end-placeholder\n\n" +
+	"##### !tests\n\n```py\nimport unittest\nfrom main import *\n\nclass TestOne(unittest.TestCase):\n  def test_one(self):\n    self.assertEqual(one(), 1)\n```\n\n##### !end-tests\n\n" +
+	"### !end-challenge\n\n" +
+	"### !challenge\n\n* type: code-snippet\n* language: javascript\n* id: js-pass\n* title: JavaScript\n\n" +
+	"##### !question\n\nAdd\n\n##### !end-question\n\n" +
+	"##### !tests\n\n```js\ndescribe('add', () => {\n  it('adds', () => {\n    expect(add(1, 2)).to.equal(3);\n    expect([add(1, 1)]).to.deep.equal([2]);\n  });\n});\n```\n\n##### !end-tests\n\n" +
+	"##### !solution\n\n```js\nfunction add(a, b) {\n  return a + b;\n}\n```\n\n##### !end-solution\n\n" +
+	"### !end-challenge\n\n" +
+	"### !challenge\n\n* type: code-snippet\n* language: sql\n* id: sql-pass\n* title: SQL\n* data_path: /data/people.sql\n\n" +
+	"##### !question\n\nSelect the adults\n\n##### !end-question\n\n" +
+	"##### !tests\n\n```sql\nSELECT name FROM people WHERE age >= 18 ORDER BY name;\n```\n\n##### !end-tests\n\n" +
+	"##### !solution\n\n```sql\nSELECT name FROM people WHERE age > 17 ORDER BY name;\n```\n\n##### !end-solution\n\n" +
+	"### !end-challenge\n\n" +
+	"### !challenge\n\n* type: code-snippet\n* language: ruby\n* id: rb-skip\n* title: Ruby\n\n" +
+	"##### !question\n\nAnything\n\n##### !end-question\n\n" +
+	"##### !tests\n\n```rb\nputs 1\n```\n\n##### !end-tests\n\n" +
+	"### !end-challenge\n\n" +
+	"### !challenge\n\n* type: short-answer\n* id: not-a-snippet\n* title: Short\n\n" +
+	"##### !question\n\nWhat?\n\n##### !end-question\n\n" +
+	"##### !answer\n\nthat\n\n##### !end-answer\n\n" +
+	"### !end-challenge\n"
+
+func writeSnippetBlock(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "snippets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"config.yaml":         "Standards: []\n",
+		"data/people.sql":     "CREATE TABLE people (name TEXT, age INTEGER);\nINSERT INTO people VALUES ('Ann', 30), ('Bo', 12), ('Cy', 18);\n",
+		"01-unit/snippets.md": snippetLesson,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func Test_sectionCode(t *testing.T) {
+	got := sectionCode("Some words\n\n```py\ndef one():\n  return 1\n```\n\nMore words")
+	if got != "def one():\n  return 1" {
+		t.Errorf("sectionCode should return the code block, got %q", got)
+	}
+
+	got = sectionCode("def one():\n  return 1")
+	if got != "def one():\n  return 1" {
+		t.Errorf("sectionCode should return content without a code block as it is, got %q", got)
+	}
+}
+
+func Test_findBlockRoot(t *testing.T) {
+	dir := writeSnippetBlock(t)
+	got := findBlockRoot(filepath.Join(dir, "01-unit"))
+	want, _ := filepath.Abs(dir)
+	if got != want {
+		t.Errorf("findBlockRoot should find the directory with config.yaml, want %s got %s", want, got)
+	}
+}
+
+func Test_snippetFromChallenge(t *testing.T) {
+	challenges, err := parseChallenges("snippets.md", snippetLesson)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := snippetFromChallenge(challenges[0], "/block")
+	if s.code != "def one():\n  return 1" || s.fromPlaceholder {
+		t.Errorf("the solution should be the code tested, got %q", s.code)
+	}
+	if !strings.HasPrefix(s.tests, "import unittest") {
+		t.Errorf("the tests should be taken from their code block, got %q", s.tests)
+	}
+
+	s = snippetFromChallenge(challenges[1], "/block")
+	if s.code != "def one():\n  pass" || !s.fromPlaceholder {
+		t.Errorf("the placeholder should be tested without a solution, got %q", s.code)
+	}
+
+	s = snippetFromChallenge(challenges[3], "/block")
+	if s.dataPath != filepath.Join("/block", "data", "people.sql") {
+		t.Errorf("data_path should be relative to the block root, got %s", s.dataPath)
+	}
+}
+
+func Test_testSnippetFile(t *testing.T) {
+	dir := writeSnippetBlock(t)
+	results, err := testSnippetFile(filepath.Join(dir, "01-unit", "snippets.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("every code-snippet challenge should be tested, got %d results", len(results))
+	}
+
+	want := map[string]struct {
+		tool   string
+		status string
+	}{
+		"py-pass":        {"python3", "PASS"},
+		"py-placeholder": {"python3", "FAIL"},
+		"js-pass":        {"node", "PASS"},
+		"sql-pass":       {"sqlite3", "PASS"},
+		"rb-skip":        {"", "SKIP"},
+	}
+	for _, r := range results {
+		w := want[r.id]
+		if w.tool != "" {
+			if _, err := exec.LookPath(w.tool); err != nil {
+				if r.status != "SKIP" {
+					t.Errorf("%s should be skipped without %s, got %s", r.id, w.tool, r.status)
+				}
+				continue
+			}
+		}
+		if r.status != w.status {
+			t.Errorf("%s should be %s, got %s: %s\n%s", r.id, w.status, r.status, r.detail, r.output)
+		}
+	}
+}
+
+func Test_testSnippetFileWithoutSnippets(t *testing.T) {
+	dir := writeSnippetBlock(t)
+	path := filepath.Join(dir, "empty.md")
+	ioutil.WriteFile(path, []byte("# Nothing to test\n"), 0644)
+
+	if _, err := testSnippetFile(path); err == nil {
+		t.Errorf("a file without code-snippet challenges should be an error")
+	}
+}
+
+func Test_printSnippetResults(t *testing.T) {
+	out := &bytes.Buffer{}
+	failed := printSnippetResults(out, []snippetResult{
+		{id: "a", language: "python3.6", status: "PASS"},
+		{id: "b", language: "python3.6", status: "FAIL", detail: "unittest failed", output: "AssertionError: None != 1"},
+		{id: "c", language: "ruby", status: "SKIP", detail: "no local runner for language 'ruby'"},
+	})
+
+	if failed != 1 {
+		t.Errorf("printSnippetResults should count one failure, got %d", failed)
+	}
+	if !strings.Contains(out.String(), "AssertionError: None != 1") {
+		t.Errorf("the output of failures should be printed, got %s", out.String())
+	}
+	if !strings.Contains(out.String(), "1 passed, 1 failed, 1 skipped") {
+		t.Errorf("a summary should be printed, got %s", out.String())
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultIDPlaceholder is replaced with a generated id in templates that don't set id_placeholder
+const defaultIDPlaceholder = "{{id}}"
+
+// customTemplate is a markdown template loaded from a templates directory. Its front matter
+// gives the name and aliases it is used with and a description for `learn md --help`
+type customTemplate struct {
+	Name          string   `yaml:"name"`
+	Aliases       []string `yaml:"aliases"`
+	Description   string   `yaml:"description"`
+	IDPlaceholder string   `yaml:"id_placeholder"`
+
+	source string
+	body   string
+}
+
+// customTemplates are the templates loaded by loadCustomTemplates, in the order they were added
+var customTemplates []customTemplate
+
+// loadTemplatesOnce keeps the templates directories from being read more than once per command
+var loadTemplatesOnce sync.Once
+
+// userTemplatesDir is where a user keeps their own templates, ~/.config/learn/templates
+func userTemplatesDir() string {
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		config = filepath.Join(home, ".config")
+	}
+	return filepath.Join(config, "learn", "templates")
+}
+
+// repoTemplatesDir finds the .learn/templates directory shared by a repo in dir or one of its
+// parents, or returns an empty string when there isn't one
+func repoTemplatesDir(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		candidate := filepath.Join(dir, ".learn", "templates")
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadCustomTemplates adds the user's templates and then the current repo's templates to the
+// templates learn md can use, so repo templates replace user templates which replace built-ins.
+// Only learn md uses them, so they are loaded when it runs or shows its help. A template that
+// can't be read is skipped with a warning on stderr rather than breaking the command
+func loadCustomTemplates() {
+	loadTemplatesOnce.Do(func() {
+		sources := []struct{ dir, name string }{
+			{userTemplatesDir(), "user"},
+			{repoTemplatesDir("."), "repo"},
+		}
+
+		for _, source := range sources {
+			if source.dir == "" {
+				continue
+			}
+			loaded, errs := readTemplateDir(source.dir, source.name)
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+			}
+			addCustomTemplates(templates, loaded)
+		}
+	})
+}
+
+// readTemplateDir reads every .md template in dir. Templates that can't be read are returned
+// as errors alongside the ones that could
+func readTemplateDir(dir, source string) ([]customTemplate, []error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, []error{err}
+	}
+
+	loaded := []customTemplate{}
+	errs := []error{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		t, err := readTemplateFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.source = source
+		loaded = append(loaded, t)
+	}
+
+	return loaded, errs
+}
+
+// readTemplateFile reads a template and its front matter. The name defaults to the file name
+func readTemplateFile(path string) (customTemplate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return customTemplate{}, err
+	}
+
+	t := customTemplate{}
+	raw, body := splitFrontMatter(b)
+	if raw != nil {
+		if err = yaml.Unmarshal(raw, &t); err != nil {
+			return customTemplate{}, fmt.Errorf("template %s has invalid front matter: %s", path, err)
+		}
+	}
+
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), ".md")
+	}
+	for _, name := range append([]string{t.Name}, t.Aliases...) {
+		if name == "" || strings.ContainsAny(name, " \t/") {
+			return customTemplate{}, fmt.Errorf("template %s cannot be used with the name '%s'", path, name)
+		}
+	}
+	if t.IDPlaceholder == "" {
+		t.IDPlaceholder = defaultIDPlaceholder
+	}
+	t.body = strings.TrimRight(string(body), "\n")
+
+	return t, nil
+}
+
+// temp converts a custom template to the form the built-in templates use, where the id is
+// written with fmt
+func (c customTemplate) temp() temp {
+	t := temp{Name: c.Name + " markdown", Template: c.body}
+	if strings.Contains(c.body, c.IDPlaceholder) {
+		escaped := strings.ReplaceAll(c.body, "%", "%%")
+		t.Template = strings.ReplaceAll(escaped, strings.ReplaceAll(c.IDPlaceholder, "%", "%%"), "%s")
+		t.RequireId = true
+	}
+	return t
+}
+
+// addCustomTemplates adds each template under its name and aliases, replacing any template
+// already using them
+func addCustomTemplates(all map[string]temp, custom []customTemplate) {
+	for _, c := range custom {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			all[name] = c.temp()
+		}
+		customTemplates = append(customTemplates, c)
+	}
+}
+
+// customTemplateList describes the custom templates for the help text, noting where each
+// comes from and which built-ins they replace
+func customTemplateList(custom []customTemplate) string {
+	if len(custom) == 0 {
+		return ""
+	}
+
+	// a later template replaces an earlier one with the same name
+	byName := map[string]customTemplate{}
+	for _, c := range custom {
+		byName[c.Name] = c
+	}
+	names := []string{}
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var list strings.Builder
+	list.WriteString("\nCustom templates:")
+	for _, name := range names {
+		c := byName[name]
+		list.WriteString("\n  " + c.Name)
+		if len(c.Aliases) > 0 {
+			list.WriteString(" (" + strings.Join(c.Aliases, ", ") + ")")
+		}
+		if c.Description != "" {
+			list.WriteString(" -- " + c.Description)
+		}
+		list.WriteString(" [" + c.source + "]")
+	}
+
+	return list.String()
+}
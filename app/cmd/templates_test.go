@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_customTemplates(t *testing.T) {
+	userDir, err := ioutil.TempDir("", "learn-templates-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(userDir)
+	repo, err := ioutil.TempDir("", "learn-templates-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+	repoDir := filepath.Join(repo, ".learn", "templates")
+	os.MkdirAll(repoDir, 0755)
+	os.MkdirAll(filepath.Join(repo, "units", "01-intro"), 0755)
+
+	ioutil.WriteFile(filepath.Join(userDir, "gosnippet.md"), []byte("---\naliases: [gs]\ndescription: A Go snippet\n---\n* id: {{id}}\n* points: 100%\n"), 0644)
+	ioutil.WriteFile(filepath.Join(userDir, "rubric.md"), []byte("---\nname: rubric\ndescription: Standard rubric\n---\n### Rubric\n"), 0644)
+	ioutil.WriteFile(filepath.Join(repoDir, "team-rubric.md"), []byte("---\nname: rubric\naliases: [co]\nid_placeholder: ID_HERE\n---\nid ID_HERE\n"), 0644)
+	ioutil.WriteFile(filepath.Join(repoDir, "broken.md"), []byte("---\nname: has space\n---\n"), 0644)
+
+	if found := repoTemplatesDir(filepath.Join(repo, "units", "01-intro")); found != repoDir {
+		t.Errorf("repoTemplatesDir should find %s from a subdirectory, got %s", repoDir, found)
+	}
+
+	all := map[string]temp{"co": templates["co"], "mc": templates["mc"]}
+	defer func() { customTemplates = nil }()
+
+	user, errs := readTemplateDir(userDir, "user")
+	if len(errs) != 0 || len(user) != 2 {
+		t.Fatalf("expected two user templates, got %v %v", user, errs)
+	}
+	addCustomTemplates(all, user)
+	repoTemplates, errs := readTemplateDir(repoDir, "repo")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "has space") {
+		t.Errorf("a template with an unusable name should be reported, got %v", errs)
+	}
+	addCustomTemplates(all, repoTemplates)
+
+	gs := all["gs"]
+	if !gs.RequireId || gs.Template != "* id: %s\n* points: 100%%" {
+		t.Errorf("the id placeholder should be written with fmt, got %+v", gs)
+	}
+	if all["rubric"].Template != "id %s" || all["co"].Template != "id %s" {
+		t.Errorf("repo templates should replace user templates and built-ins, got %+v %+v", all["rubric"], all["co"])
+	}
+	if all["mc"].Name != "Multiple Choice markdown" {
+		t.Errorf("built-ins without a custom template should be kept")
+	}
+
+	list := customTemplateList(customTemplates)
+	expected := "\nCustom templates:\n  gosnippet (gs) -- A Go snippet [user]\n  rubric (co) [repo]"
+	if list != expected {
+		t.Errorf("customTemplateList expected:\n%s\nbut got:\n%s", expected, list)
+	}
+}
+
+func Test_markdownHelp(t *testing.T) {
+	// the templates directories aren't read, only customTemplates is listed
+	loadTemplatesOnce.Do(func() {})
+	customTemplates = []customTemplate{{Name: "rubric", Description: "Standard rubric", source: "user"}}
+	defer func() { customTemplates = nil }()
+
+	var out bytes.Buffer
+	markdownCmd.SetOutput(&out)
+	defer markdownCmd.SetOutput(nil)
+
+	markdownHelp(markdownCmd, nil)
+	markdownHelp(markdownCmd, nil)
+	if strings.Count(out.String(), "Custom templates:") != 2 || strings.Count(out.String(), "rubric -- Standard rubric") != 2 {
+		t.Errorf("each help should list the custom templates once, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gSchool/glearn-cli/gitutil"
+	"gopkg.in/yaml.v3"
+)
+
+// uidLockFile records the UIDs autoconfig generation has handed out. It is committed with the
+// block so renaming a file or unit directory keeps the UID, and the student progress on it
+const uidLockFile = "autoconfig.lock"
+
+// uidLock maps block relative paths, written like config Paths, to their UIDs. Units are keyed
+// by their directory, content files by their file
+type uidLock struct {
+	Units        map[string]string `yaml:"Units"`
+	ContentFiles map[string]string `yaml:"ContentFiles"`
+
+	// used tracks the paths handed a UID since the lock was loaded, only those are written back
+	used map[string]struct{}
+}
+
+// loadUIDLock reads the autoconfig.lock at the root of a block, an empty lock when there isn't one
+func loadUIDLock(blockRoot string) (*uidLock, error) {
+	lock := &uidLock{Units: map[string]string{}, ContentFiles: map[string]string{}, used: map[string]struct{}{}}
+
+	b, err := ioutil.ReadFile(filepath.Join(blockRoot, uidLockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("Could not parse '%s': %s", filepath.Join(blockRoot, uidLockFile), err)
+	}
+	if lock.Units == nil {
+		lock.Units = map[string]string{}
+	}
+	if lock.ContentFiles == nil {
+		lock.ContentFiles = map[string]string{}
+	}
+
+	return lock, nil
+}
+
+// write saves the lock to the root of a block, keeping only the units and content files that
+// were handed a UID
+func (l *uidLock) write(blockRoot string) error {
+	keep := &uidLock{Units: map[string]string{}, ContentFiles: map[string]string{}}
+	for path, uid := range l.Units {
+		if _, ok := l.used["unit:"+path]; ok {
+			keep.Units[path] = uid
+		}
+	}
+	for path, uid := range l.ContentFiles {
+		if _, ok := l.used[path]; ok {
+			keep.ContentFiles[path] = uid
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("# Generated alongside autoconfig.yaml, commit it with your content.\n")
+	b.WriteString("# It keeps the UID of every unit and lesson when files are renamed so student progress is not lost.\n")
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(keep); err != nil {
+		return err
+	}
+	enc.Close()
+
+	return ioutil.WriteFile(filepath.Join(blockRoot, uidLockFile), b.Bytes(), 0644)
+}
+
+// unitUID returns the locked UID of the unit directory at path, locking generated when it has none yet
+func (l *uidLock) unitUID(path, generated string) string {
+	l.used["unit:"+path] = struct{}{}
+	if uid, ok := l.Units[path]; ok && uid != "" {
+		return uid
+	}
+	l.Units[path] = generated
+	return generated
+}
+
+// contentFileUID returns the locked UID of the content file at path, locking generated when it has none yet
+func (l *uidLock) contentFileUID(path, generated string) string {
+	l.used[path] = struct{}{}
+	if uid, ok := l.ContentFiles[path]; ok && uid != "" {
+		return uid
+	}
+	l.ContentFiles[path] = generated
+	return generated
+}
+
+// carryRenames moves the locked UIDs of units and content files that no longer exist to the
+// paths git says they were renamed to. Renames git tracks are found by diffing against the
+// commit the lock was last committed in, plain moves of untracked files by matching content
+func (l *uidLock) carryRenames(blockRoot string, units, contentFiles []string) {
+	missingFiles := missingPaths(l.ContentFiles, contentFiles)
+	unlockedFiles := unlockedPaths(l.ContentFiles, contentFiles)
+	if len(missingFiles) == 0 || len(unlockedFiles) == 0 {
+		return
+	}
+
+	repo := gitutil.New(blockRoot)
+	base, err := repo.LastCommit(uidLockFile)
+	if err != nil {
+		// not a git repository, renames can't be detected
+		return
+	}
+	if base == "" {
+		base = "HEAD"
+	}
+
+	// moved maps old content file paths to their new ones
+	moved := map[string]string{}
+	if renames, err := repo.Renames(base); err == nil {
+		for oldPath, newPath := range renames {
+			oldPath, newPath = "/"+oldPath, "/"+newPath
+			if _, ok := missingFiles[oldPath]; !ok {
+				continue
+			}
+			if _, ok := unlockedFiles[newPath]; !ok {
+				continue
+			}
+			moved[oldPath] = newPath
+			delete(unlockedFiles, newPath)
+		}
+	}
+
+	if len(moved) < len(missingFiles) && len(unlockedFiles) > 0 {
+		newBlobs := map[string]string{}
+		for newPath := range unlockedFiles {
+			if id, err := repo.HashObject(filepath.Join(blockRoot, newPath)); err == nil {
+				newBlobs[id] = newPath
+			}
+		}
+		for oldPath := range missingFiles {
+			if _, ok := moved[oldPath]; ok {
+				continue
+			}
+			id, err := repo.BlobID(base, strings.TrimPrefix(oldPath, "/"))
+			if err != nil || id == "" {
+				continue
+			}
+			if newPath, ok := newBlobs[id]; ok {
+				moved[oldPath] = newPath
+				delete(newBlobs, id)
+			}
+		}
+	}
+
+	missingUnits := missingPaths(l.Units, units)
+	unlockedUnits := unlockedPaths(l.Units, units)
+	for oldPath, newPath := range moved {
+		l.ContentFiles[newPath] = l.ContentFiles[oldPath]
+		delete(l.ContentFiles, oldPath)
+
+		oldUnit := longestPrefix(missingUnits, oldPath)
+		newUnit := longestPrefix(unlockedUnits, newPath)
+		if oldUnit != "" && newUnit != "" {
+			l.Units[newUnit] = l.Units[oldUnit]
+			delete(l.Units, oldUnit)
+			delete(missingUnits, oldUnit)
+			delete(unlockedUnits, newUnit)
+		}
+	}
+}
+
+// missingPaths returns the locked paths that are not in current
+func missingPaths(locked map[string]string, current []string) map[string]struct{} {
+	missing := map[string]struct{}{}
+	for path := range locked {
+		missing[path] = struct{}{}
+	}
+	for _, path := range current {
+		delete(missing, path)
+	}
+	return missing
+}
+
+// unlockedPaths returns the paths in current that have no locked UID
+func unlockedPaths(locked map[string]string, current []string) map[string]struct{} {
+	unlocked := map[string]struct{}{}
+	for _, path := range current {
+		if _, ok := locked[path]; !ok {
+			unlocked[path] = struct{}{}
+		}
+	}
+	return unlocked
+}
+
+// longestPrefix returns the longest directory in dirs holding path
+func longestPrefix(dirs map[string]struct{}, path string) string {
+	longest := ""
+	for dir := range dirs {
+		if strings.HasPrefix(path, dir+"/") && len(dir) > len(longest) {
+			longest = dir
+		}
+	}
+	return longest
+}
+
+// uidChanges compares the config a block was last published with to the one about to be
+// published and describes every unit or content file UID that would change or disappear.
+// Moving a file while keeping its UID is not a change
+func uidChanges(published, next blockConfig) []string {
+	nextUIDs := map[string]struct{}{}
+	nextFiles := map[string]string{}
+	nextUnits := map[string]string{}
+	for _, unit := range next.Standards {
+		nextUIDs[unit.UID] = struct{}{}
+		nextUnits[unit.Title] = unit.UID
+		for _, cf := range unit.ContentFiles {
+			nextUIDs[cf.UID] = struct{}{}
+			nextFiles[cf.Path] = cf.UID
+		}
+	}
+
+	changes := []string{}
+	for _, unit := range published.Standards {
+		if _, ok := nextUIDs[unit.UID]; !ok {
+			if uid, ok := nextUnits[unit.Title]; ok {
+				changes = append(changes, fmt.Sprintf("unit '%s' changes UID from %s to %s", unit.Title, unit.UID, uid))
+			} else {
+				changes = append(changes, fmt.Sprintf("unit '%s' (UID %s) is no longer in the config", unit.Title, unit.UID))
+			}
+		}
+
+		for _, cf := range unit.ContentFiles {
+			if _, ok := nextUIDs[cf.UID]; ok {
+				continue
+			}
+			if uid, ok := nextFiles[cf.Path]; ok {
+				changes = append(changes, fmt.Sprintf("%s changes UID from %s to %s", cf.Path, cf.UID, uid))
+			} else {
+				changes = append(changes, fmt.Sprintf("%s (UID %s) is no longer in the config", cf.Path, cf.UID))
+			}
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+// publishedUIDChanges compares the config on rev, the last published state of a block, with
+// the config at configPath. Nothing is reported when rev has no config
+func publishedUIDChanges(repo *gitutil.Repo, rev, configPath string) []string {
+	var published blockConfig
+	found := false
+	for _, name := range []string{"config.yaml", "config.yml", "autoconfig.yaml"} {
+		content, err := repo.ShowFile(rev, name)
+		if err != nil {
+			continue
+		}
+		if err = yaml.Unmarshal([]byte(content), &published); err != nil {
+			return nil
+		}
+		found = true
+		break
+	}
+	if !found {
+		return nil
+	}
+
+	next, err := readBlockConfig(configPath)
+	if err != nil {
+		return nil
+	}
+
+	return uidChanges(published, next)
+}
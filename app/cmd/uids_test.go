@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// uidsByPath generates the autoconfig of the block at dir and returns the UID of every unit,
+// keyed by title, and content file, keyed by path
+func uidsByPath(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	if err := createAutoConfig(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	config, err := readBlockConfig(filepath.Join(dir, "autoconfig.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uids := map[string]string{}
+	for _, unit := range config.Standards {
+		uids[unit.Title] = unit.UID
+		for _, cf := range unit.ContentFiles {
+			uids[cf.Path] = cf.UID
+		}
+	}
+	return uids
+}
+
+func Test_AutoConfigKeepsUIDsAcrossRenames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "learn-uids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "units", "01-intro"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "units", "01-intro", "welcome.md"), []byte("# Welcome\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "units", "01-intro", "setup.md"), []byte("# Setup\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "units", "01-intro", "tools.md"), []byte("# Tools\n"), 0644)
+
+	gitIn(t, dir, "init")
+	before := uidsByPath(t, dir)
+	gitIn(t, dir, "add", ".")
+	gitIn(t, dir, "commit", "-m", "content")
+
+	// a tracked rename of the unit, a plain move of a file and a new file
+	gitIn(t, dir, "mv", "units/01-intro", "units/01-getting-started")
+	os.Rename(filepath.Join(dir, "units", "01-getting-started", "tools.md"), filepath.Join(dir, "units", "01-getting-started", "editors.md"))
+	ioutil.WriteFile(filepath.Join(dir, "units", "01-getting-started", "next.md"), []byte("# Next\n"), 0644)
+
+	after := uidsByPath(t, dir)
+
+	if after["Getting Started"] != before["Intro"] {
+		t.Errorf("renamed unit should keep UID %s, got %s", before["Intro"], after["Getting Started"])
+	}
+	for oldPath, newPath := range map[string]string{
+		"/units/01-intro/welcome.md": "/units/01-getting-started/welcome.md",
+		"/units/01-intro/setup.md":   "/units/01-getting-started/setup.md",
+		"/units/01-intro/tools.md":   "/units/01-getting-started/editors.md",
+	} {
+		if after[newPath] != before[oldPath] {
+			t.Errorf("%s should keep the UID of %s, %s, got %s", newPath, oldPath, before[oldPath], after[newPath])
+		}
+	}
+	if after["/units/01-getting-started/next.md"] == "" {
+		t.Errorf("a new file should get a UID")
+	}
+
+	lock, err := loadUIDLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.ContentFiles["/units/01-intro/welcome.md"]; ok || len(lock.ContentFiles) != 4 || len(lock.Units) != 1 {
+		t.Errorf("the lock should only hold current paths, got %+v", lock)
+	}
+}
+
+func Test_uidChanges(t *testing.T) {
+	published := blockConfig{Standards: []standard{
+		{Title: "Intro", UID: "unit-1", ContentFiles: []contentFile{
+			{Path: "/units/intro/a.md", UID: "a"},
+			{Path: "/units/intro/b.md", UID: "b"},
+			{Path: "/units/intro/c.md", UID: "c"},
+		}},
+		{Title: "Loops", UID: "unit-2"},
+	}}
+	next := blockConfig{Standards: []standard{
+		{Title: "Intro", UID: "unit-1", ContentFiles: []contentFile{
+			{Path: "/units/intro/renamed.md", UID: "a"},
+			{Path: "/units/intro/b.md", UID: "b2"},
+		}},
+		{Title: "Loops", UID: "unit-3"},
+	}}
+
+	expected := []string{
+		"/units/intro/b.md changes UID from b to b2",
+		"/units/intro/c.md (UID c) is no longer in the config",
+		"unit 'Loops' changes UID from unit-2 to unit-3",
+	}
+	if changes := uidChanges(published, next); strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("uidChanges expected:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), strings.Join(changes, "\n"))
+	}
+}
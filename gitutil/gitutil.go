@@ -0,0 +1,339 @@
+package gitutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrDetachedHead is returned by CurrentBranch when HEAD does not point at a branch
+var ErrDetachedHead = errors.New("HEAD is detached, check out a branch first")
+
+// shaPattern matches abbreviated or full commit shas
+var shaPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// Repo runs git commands against the working tree at Dir. Every command is executed with
+// an argument array, never through a shell.
+type Repo struct {
+	Dir string
+}
+
+// New creates a Repo for the working tree at dir
+func New(dir string) *Repo {
+	return &Repo{Dir: dir}
+}
+
+// Error is returned when a git command exits unsuccessfully. It keeps the captured stderr
+// so callers can show users what git actually said.
+type Error struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+// Error formats the failed command with git's own output
+func (e *Error) Error() string {
+	return fmt.Sprintf("git %s: %s\n%s", strings.Join(e.Args, " "), e.Err, e.Stderr)
+}
+
+// Unwrap exposes the underlying exec error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// run executes git with args in the repo directory and returns trimmed stdout
+func (r *Repo) run(args ...string) (string, error) {
+	out, err := r.output(args...)
+	return strings.TrimSpace(out), err
+}
+
+// output executes git with args in the repo directory and returns stdout as git wrote it, for
+// output where leading whitespace is significant
+func (r *Repo) output(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", &Error{Args: args, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	}
+
+	return stdout.String(), nil
+}
+
+// CurrentBranch returns the short name of the checked out branch, or ErrDetachedHead
+func (r *Repo) CurrentBranch() (string, error) {
+	branch, err := r.run("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		var gitErr *Error
+		if errors.As(err, &gitErr) && gitErr.Stderr == "" {
+			return "", ErrDetachedHead
+		}
+		return "", err
+	}
+
+	return branch, nil
+}
+
+// RemoteURL returns the push url configured for the named remote
+func (r *Repo) RemoteURL(remote string) (string, error) {
+	return r.run("remote", "get-url", "--push", remote)
+}
+
+// IsDirty reports whether the working tree has staged, unstaged or untracked changes
+func (r *Repo) IsDirty() (bool, error) {
+	out, err := r.run("status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+
+	return out != "", nil
+}
+
+// ChangedPaths lists every staged, unstaged or untracked path in the working tree. Renamed
+// paths are reported by their new name
+func (r *Repo) ChangedPaths() ([]string, error) {
+	out, err := r.output("status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseStatus(out), nil
+}
+
+// ParseStatus reads `git status --porcelain -z` output into the paths it lists. Each entry is
+// a two letter status, a space and the path, and renames and copies are followed by an entry
+// holding the original path, which is skipped
+func ParseStatus(out string) []string {
+	paths := []string{}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths = append(paths, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+
+	return paths
+}
+
+// AheadBehind counts the commits on local that are not on upstream (ahead) and the commits on
+// upstream that are not on local (behind), ex. AheadBehind("main", "origin/main")
+func (r *Repo) AheadBehind(local, upstream string) (int, int, error) {
+	out, err := r.run("rev-list", "--left-right", "--count", local+"..."+upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output '%s'", out)
+	}
+	ahead, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	behind, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return ahead, behind, nil
+}
+
+// CommitCount returns the number of commits reachable from rev
+func (r *Repo) CommitCount(rev string) (int, error) {
+	out, err := r.run("rev-list", "--count", rev)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(out)
+}
+
+// Fetch updates the remote tracking branches of remote
+func (r *Repo) Fetch(remote string) error {
+	_, err := r.run("fetch", remote)
+	return err
+}
+
+// Push pushes branch to remote. Git reports progress on stderr, so the combined output is
+// returned for display on success and kept in the *Error on failure.
+func (r *Repo) Push(remote, branch string) (string, error) {
+	cmd := exec.Command("git", "push", remote, branch)
+	cmd.Dir = r.Dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", &Error{Args: []string{"push", remote, branch}, Stderr: strings.TrimSpace(string(out)), Err: err}
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Add stages paths
+func (r *Repo) Add(paths ...string) error {
+	_, err := r.run(append([]string{"add", "--"}, paths...)...)
+	return err
+}
+
+// Commit records the staged changes with message
+func (r *Repo) Commit(message string) error {
+	_, err := r.run("commit", "-m", message)
+	return err
+}
+
+// HasStagedChanges reports whether anything is staged for commit
+func (r *Repo) HasStagedChanges() (bool, error) {
+	_, err := r.run("diff", "--cached", "--quiet")
+	if err == nil {
+		return false, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// DefaultBranch asks remote which branch its HEAD points to, ex. master or main
+func (r *Repo) DefaultBranch(remote string) (string, error) {
+	out, err := r.run("ls-remote", "--symref", remote, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	// ex. "ref: refs/heads/main\tHEAD"
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "ref: ") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "ref: "))
+		if len(fields) > 0 {
+			return strings.TrimPrefix(fields[0], "refs/heads/"), nil
+		}
+	}
+
+	return "", fmt.Errorf("remote '%s' did not report a HEAD branch", remote)
+}
+
+// ResolveRemoteRef verifies that a tag, branch or commit sha exists on remote and returns the
+// full commit sha it points to
+func (r *Repo) ResolveRemoteRef(remote, ref string) (string, error) {
+	// ls-remote only lists the peeled line of an annotated tag when asked for it by name
+	out, err := r.run("ls-remote", remote, ref, ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+	if sha := ParseLsRemote(out, ref); sha != "" {
+		return sha, nil
+	}
+
+	if !shaPattern.MatchString(ref) {
+		return "", fmt.Errorf("no tag or branch named '%s' exists on %s", ref, remote)
+	}
+
+	// Commits are not listed by ls-remote, make sure some remote branch contains it
+	if err = r.Fetch(remote); err != nil {
+		return "", err
+	}
+	sha, err := r.run("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil || sha == "" {
+		return "", fmt.Errorf("no commit '%s' exists on %s", ref, remote)
+	}
+	branches, err := r.run("branch", "--remotes", "--contains", sha)
+	if err != nil || branches == "" {
+		return "", fmt.Errorf("commit '%s' has not been pushed to %s", ref, remote)
+	}
+
+	return sha, nil
+}
+
+// LastCommit returns the sha of the last commit that changed path, or "" when it was never committed
+func (r *Repo) LastCommit(path string) (string, error) {
+	return r.run("log", "-1", "--format=%H", "--", path)
+}
+
+// Renames returns the files renamed between rev and the working tree, old path to new path.
+// Paths are relative to the repo's directory. Only tracked files are compared
+func (r *Repo) Renames(rev string) (map[string]string, error) {
+	out, err := r.run("diff", "--relative", "--name-status", "-M", "--diff-filter=R", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRenames(out), nil
+}
+
+// ShowFile returns the content of path, relative to the repo's directory, at rev
+func (r *Repo) ShowFile(rev, path string) (string, error) {
+	return r.run("show", rev+":./"+path)
+}
+
+// BlobID returns the object id of path, relative to the repo's directory, at rev
+func (r *Repo) BlobID(rev, path string) (string, error) {
+	return r.run("rev-parse", "--verify", "--quiet", rev+":./"+path)
+}
+
+// HashObject returns the object id git would give the content of the file at path
+func (r *Repo) HashObject(path string) (string, error) {
+	return r.run("hash-object", "--", path)
+}
+
+// ParseRenames reads `git diff --name-status` output into a map of old path to new path
+func ParseRenames(out string) map[string]string {
+	renames := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], "R") {
+			continue
+		}
+		renames[fields[1]] = fields[2]
+	}
+
+	return renames
+}
+
+// ParseLsRemote picks the commit sha for ref out of `git ls-remote` output. Annotated tags
+// are listed twice, the peeled "^{}" line holds the commit the tag points to
+func ParseLsRemote(out, ref string) string {
+	sha := ""
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := fields[1]
+		if strings.HasSuffix(name, "^{}") {
+			name = strings.TrimSuffix(name, "^{}")
+			if name == ref || name == "refs/tags/"+ref {
+				return fields[0]
+			}
+			continue
+		}
+		if name == ref || name == "refs/tags/"+ref || name == "refs/heads/"+ref {
+			sha = fields[0]
+		}
+	}
+
+	return sha
+}
+
+// Clone clones url into dir, dir is created by git and must not already exist
+func Clone(url, dir string) error {
+	_, err := New("").run("clone", url, dir)
+	return err
+}
@@ -0,0 +1,319 @@
+package gitutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testGit runs a git command in dir, failing the test on error
+func testGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes name with contents into dir and commits it
+func commitFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, dir, "add", name)
+	testGit(t, dir, "commit", "-m", "add "+name)
+}
+
+// newTestRepos creates a bare origin with a main branch and a clone of it, returning the clone
+// directory and a cleanup func
+func newTestRepos(t *testing.T) (string, func()) {
+	t.Helper()
+	root, err := ioutil.TempDir("", "gitutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origin := filepath.Join(root, "origin.git")
+	seed := filepath.Join(root, "seed")
+	clone := filepath.Join(root, "clone")
+
+	testGit(t, root, "init", "--bare", "--initial-branch=main", origin)
+	testGit(t, root, "init", "--initial-branch=main", seed)
+	commitFile(t, seed, "README.md", "# seed\n")
+	testGit(t, seed, "remote", "add", "origin", origin)
+	testGit(t, seed, "push", "origin", "main")
+	testGit(t, root, "clone", origin, clone)
+	testGit(t, clone, "config", "user.name", "test")
+	testGit(t, clone, "config", "user.email", "test@example.com")
+
+	return clone, func() { os.RemoveAll(root) }
+}
+
+func Test_CurrentBranch(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		t.Errorf("CurrentBranch errored: %s", err)
+	}
+	if branch != "main" {
+		t.Errorf("CurrentBranch should be 'main', was '%s'", branch)
+	}
+
+	testGit(t, dir, "checkout", "-b", "feature/with-slash")
+	branch, _ = repo.CurrentBranch()
+	if branch != "feature/with-slash" {
+		t.Errorf("CurrentBranch should be 'feature/with-slash', was '%s'", branch)
+	}
+
+	testGit(t, dir, "checkout", "--detach")
+	_, err = repo.CurrentBranch()
+	if err != ErrDetachedHead {
+		t.Errorf("CurrentBranch should return ErrDetachedHead on a detached HEAD, got %v", err)
+	}
+}
+
+func Test_IsDirty(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	dirty, err := repo.IsDirty()
+	if err != nil || dirty {
+		t.Errorf("a fresh clone should not be dirty, got %v %v", dirty, err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "untracked.md"), []byte("new"), 0644)
+	dirty, err = repo.IsDirty()
+	if err != nil || !dirty {
+		t.Errorf("an untracked file should make the tree dirty, got %v %v", dirty, err)
+	}
+
+	testGit(t, dir, "mv", "README.md", "MOVED.md")
+	paths, err := repo.ChangedPaths()
+	if err != nil {
+		t.Errorf("ChangedPaths errored: %s", err)
+	}
+	if strings.Join(paths, ",") != "MOVED.md,untracked.md" {
+		t.Errorf("ChangedPaths should list the renamed and untracked files, got %v", paths)
+	}
+}
+
+func Test_ChangedPathsUnstagedFirst(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	commitFile(t, dir, "autoconfig.yaml", "Standards: []\n")
+	ioutil.WriteFile(filepath.Join(dir, "autoconfig.yaml"), []byte("Standards:\n  -\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "my notes.md"), []byte("new"), 0644)
+
+	paths, err := repo.ChangedPaths()
+	if err != nil {
+		t.Errorf("ChangedPaths errored: %s", err)
+	}
+	if strings.Join(paths, ",") != "autoconfig.yaml,my notes.md" {
+		t.Errorf("ChangedPaths should keep the whole path of an unstaged change listed first, got %q", paths)
+	}
+}
+
+func Test_ParseStatus(t *testing.T) {
+	out := " M autoconfig.yaml\x00R  new name.md\x00old name.md\x00?? units/a.md\x00"
+	paths := ParseStatus(out)
+	if strings.Join(paths, ",") != "autoconfig.yaml,new name.md,units/a.md" {
+		t.Errorf("ParseStatus should list modified, renamed and untracked paths, got %q", paths)
+	}
+}
+
+func Test_AheadBehindAndPush(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	commitFile(t, dir, "one.md", "1")
+	commitFile(t, dir, "two.md", "2")
+
+	ahead, behind, err := repo.AheadBehind("main", "origin/main")
+	if err != nil {
+		t.Errorf("AheadBehind errored: %s", err)
+	}
+	if ahead != 2 || behind != 0 {
+		t.Errorf("expected 2 ahead and 0 behind, got %d ahead and %d behind", ahead, behind)
+	}
+
+	if _, err = repo.Push("origin", "main"); err != nil {
+		t.Errorf("Push errored: %s", err)
+	}
+	ahead, behind, _ = repo.AheadBehind("main", "origin/main")
+	if ahead != 0 || behind != 0 {
+		t.Errorf("expected 0 ahead and 0 behind after pushing, got %d ahead and %d behind", ahead, behind)
+	}
+
+	branch, err := repo.DefaultBranch("origin")
+	if err != nil || branch != "main" {
+		t.Errorf("DefaultBranch should be 'main', was '%s' %v", branch, err)
+	}
+}
+
+func Test_PushCapturesStderr(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	_, err := repo.Push("origin", "no-such-branch")
+	var gitErr *Error
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("Push of a missing branch should return a *gitutil.Error, got %v", err)
+	}
+	if !strings.Contains(gitErr.Stderr, "no-such-branch") {
+		t.Errorf("Push error should contain git's output, got '%s'", gitErr.Stderr)
+	}
+}
+
+func Test_AddCommitAndResolveRemoteRef(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "autoconfig.yaml"), []byte("Standards:\n"), 0644)
+	if err := repo.Add("autoconfig.yaml"); err != nil {
+		t.Fatalf("Add errored: %s", err)
+	}
+	staged, _ := repo.HasStagedChanges()
+	if !staged {
+		t.Errorf("autoconfig.yaml should be staged")
+	}
+	if err := repo.Commit("adding autoconfig.yaml"); err != nil {
+		t.Fatalf("Commit errored: %s", err)
+	}
+	testGit(t, dir, "tag", "-a", "v1", "-m", "v1")
+	testGit(t, dir, "push", "origin", "main", "--tags")
+	head := testGit(t, dir, "rev-parse", "HEAD")
+
+	sha, err := repo.ResolveRemoteRef("origin", "v1")
+	if err != nil || sha != head {
+		t.Errorf("ResolveRemoteRef of an annotated tag should be '%s', was '%s' %v", head, sha, err)
+	}
+	sha, err = repo.ResolveRemoteRef("origin", head[:10])
+	if err != nil || sha != head {
+		t.Errorf("ResolveRemoteRef of a pushed sha should be '%s', was '%s' %v", head, sha, err)
+	}
+
+	commitFile(t, dir, "local.md", "not pushed")
+	local := testGit(t, dir, "rev-parse", "HEAD")
+	if _, err = repo.ResolveRemoteRef("origin", local); err == nil {
+		t.Errorf("ResolveRemoteRef should fail for a commit that was never pushed")
+	}
+	if _, err = repo.ResolveRemoteRef("origin", "no-such-tag"); err == nil {
+		t.Errorf("ResolveRemoteRef should fail for a missing tag")
+	}
+}
+
+const lsRemoteOutput = `1111111111111111111111111111111111111111	refs/heads/main
+2222222222222222222222222222222222222222	refs/tags/v1.0
+3333333333333333333333333333333333333333	refs/tags/v1.1
+4444444444444444444444444444444444444444	refs/tags/v1.1^{}`
+
+func Test_ParseLsRemote(t *testing.T) {
+	tableTest := map[string]string{
+		"main":      "1111111111111111111111111111111111111111",
+		"v1.0":      "2222222222222222222222222222222222222222",
+		"v1.1":      "4444444444444444444444444444444444444444",
+		"not-there": "",
+	}
+
+	for ref, expected := range tableTest {
+		if sha := ParseLsRemote(lsRemoteOutput, ref); sha != expected {
+			t.Errorf("ParseLsRemote for '%s' expected '%s' but got '%s'", ref, expected, sha)
+		}
+	}
+}
+
+func Test_ParseRemoteURL(t *testing.T) {
+	tableTest := map[string]Remote{
+		"git@github.com:gSchool/glearn-cli.git":                 {"github.com", "gSchool", "glearn-cli"},
+		"https://github.com/gSchool/glearn-cli.git":             {"github.com", "gSchool", "glearn-cli"},
+		"https://github.com/gSchool/glearn-cli":                 {"github.com", "gSchool", "glearn-cli"},
+		"https://user@github.com/gSchool/glearn-cli/":           {"github.com", "gSchool", "glearn-cli"},
+		"ssh://git@github.example.com:2222/org/team/repo.git":   {"github.example.com", "org/team", "repo"},
+		"git@git.enterprise.example.com:curriculum/block-1.git": {"git.enterprise.example.com", "curriculum", "block-1"},
+	}
+
+	for raw, expected := range tableTest {
+		remote, err := ParseRemoteURL(raw)
+		if err != nil {
+			t.Errorf("ParseRemoteURL for '%s' errored: %s", raw, err)
+		}
+		if remote != expected {
+			t.Errorf("ParseRemoteURL for '%s' expected %+v but got %+v", raw, expected, remote)
+		}
+	}
+
+	for _, raw := range []string{"", "not-a-remote", "https://github.com/"} {
+		if _, err := ParseRemoteURL(raw); err == nil {
+			t.Errorf("ParseRemoteURL for '%s' should have errored", raw)
+		}
+	}
+}
+
+func Test_RenamesAndShowFile(t *testing.T) {
+	dir, cleanup := newTestRepos(t)
+	defer cleanup()
+	repo := New(dir)
+
+	os.Mkdir(filepath.Join(dir, "units"), 0755)
+	commitFile(t, dir, "units/intro.md", "# Intro\n")
+	commitFile(t, dir, "units/moved.md", "# Moved\n")
+	lockCommit := testGit(t, dir, "rev-parse", "HEAD")
+	commitFile(t, dir, "later.md", "# Later\n")
+
+	last, err := repo.LastCommit("units/moved.md")
+	if err != nil || last != lockCommit {
+		t.Errorf("LastCommit should be '%s', was '%s' %v", lockCommit, last, err)
+	}
+
+	testGit(t, dir, "mv", "units/intro.md", "units/welcome.md")
+	renames, err := New(filepath.Join(dir, "units")).Renames(lockCommit)
+	if err != nil {
+		t.Fatalf("Renames errored: %s", err)
+	}
+	if len(renames) != 1 || renames["intro.md"] != "welcome.md" {
+		t.Errorf("Renames should be relative to the repo dir, got %v", renames)
+	}
+
+	content, err := repo.ShowFile(lockCommit, "units/intro.md")
+	if err != nil || content != "# Intro" {
+		t.Errorf("ShowFile should return the old content, got '%s' %v", content, err)
+	}
+
+	old, err := repo.BlobID(lockCommit, "units/moved.md")
+	if err != nil {
+		t.Fatalf("BlobID errored: %s", err)
+	}
+	os.Rename(filepath.Join(dir, "units/moved.md"), filepath.Join(dir, "untracked.md"))
+	current, err := repo.HashObject(filepath.Join(dir, "untracked.md"))
+	if err != nil || current != old {
+		t.Errorf("HashObject of an unchanged file should be '%s', was '%s' %v", old, current, err)
+	}
+}
+
+func Test_ParseRenames(t *testing.T) {
+	renames := ParseRenames("R100\tunits/a.md\tunits/b.md\nR087\tc.md\td/c.md\nM\te.md")
+	if len(renames) != 2 || renames["units/a.md"] != "units/b.md" || renames["c.md"] != "d/c.md" {
+		t.Errorf("ParseRenames should only return renames, got %v", renames)
+	}
+}
@@ -0,0 +1,58 @@
+package gitutil
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Remote describes the parts of a git remote url Learn cares about. Owner holds everything
+// between the host and the repository, so nested groups are kept, ex. "org/team"
+type Remote struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+// ParseRemoteURL understands scp-like ssh remotes (git@github.com:org/repo.git), url remotes
+// with a scheme (https://, ssh://, git://) and enterprise hosts with custom ports or users
+func ParseRemoteURL(raw string) (Remote, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Remote{}, fmt.Errorf("empty remote url")
+	}
+
+	var host, path string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return Remote{}, fmt.Errorf("Error parsing remote url '%s': %s", raw, err)
+		}
+		host = u.Hostname()
+		path = u.Path
+	} else {
+		// scp-like syntax, [user@]host:path
+		colon := strings.Index(raw, ":")
+		if colon < 0 {
+			return Remote{}, fmt.Errorf("Error parsing remote url '%s'", raw)
+		}
+		host = raw[:colon]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path = raw[colon+1:]
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	parts := strings.Split(path, "/")
+	if host == "" || len(parts) < 2 || parts[len(parts)-1] == "" {
+		return Remote{}, fmt.Errorf("Error parsing remote url '%s'", raw)
+	}
+
+	return Remote{
+		Host:  host,
+		Owner: strings.Join(parts[:len(parts)-1], "/"),
+		Name:  parts[len(parts)-1],
+	}, nil
+}
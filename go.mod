@@ -8,6 +8,8 @@ require (
 	github.com/google/uuid v1.1.1
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.5.0
+	github.com/yuin/goldmark v1.4.12
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-go 1.13
+go 1.16
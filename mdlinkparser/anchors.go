@@ -0,0 +1,77 @@
+package mdlinkparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// htmlAnchor matches an id or name attribute in html, which can be linked to like a heading
+var htmlAnchor = regexp.MustCompile(`(?i)<[a-z][a-z0-9]*\b[^>]*?\s(?:id|name)\s*=\s*["']([^"']+)["']`)
+
+// Anchors returns every fragment a link to the markdown in source can point at, the slugs of
+// its headings and the ids of its html elements
+func Anchors(source []byte) map[string]struct{} {
+	doc := goldmark.New().Parser().Parse(text.NewReader(source))
+	anchors := map[string]struct{}{}
+	counts := map[string]int{}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.CodeBlock, *ast.FencedCodeBlock, *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			slug := Slug(string(node.Text(source)))
+			if counts[slug] > 0 {
+				anchors[fmt.Sprintf("%s-%d", slug, counts[slug])] = struct{}{}
+			} else {
+				anchors[slug] = struct{}{}
+			}
+			counts[slug]++
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			for i := 0; i < node.Segments.Len(); i++ {
+				segment := node.Segments.At(i)
+				addHTMLAnchors(anchors, segment.Value(source))
+			}
+		case *ast.HTMLBlock:
+			for i := 0; i < node.Lines().Len(); i++ {
+				line := node.Lines().At(i)
+				addHTMLAnchors(anchors, line.Value(source))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return anchors
+}
+
+// addHTMLAnchors adds the ids and names of the elements in html to anchors
+func addHTMLAnchors(anchors map[string]struct{}, html []byte) {
+	for _, match := range htmlAnchor.FindAllSubmatch(html, -1) {
+		anchors[string(match[1])] = struct{}{}
+	}
+}
+
+// Slug is the anchor of a heading, its text in lower case with spaces as hyphens and without
+// punctuation, the way GitHub renders them
+func Slug(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
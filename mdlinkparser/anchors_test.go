@@ -0,0 +1,37 @@
+package mdlinkparser
+
+import (
+	"testing"
+)
+
+func Test_Slug(t *testing.T) {
+	tableTest := map[string]string{
+		"Hello World":                "hello-world",
+		"  What's `new`? ":           "whats-new",
+		"Step 1 - Setup":             "step-1---setup",
+		"snake_case and ÜNICODE":     "snake_case-and-ünicode",
+		"Punctuation!, (everywhere)": "punctuation-everywhere",
+	}
+
+	for heading, want := range tableTest {
+		if got := Slug(heading); got != want {
+			t.Errorf("Slug %q expected %s but got %s", heading, want, got)
+		}
+	}
+}
+
+func Test_Anchors(t *testing.T) {
+	source := "# Intro\n\n## Setup\n\nSetext heading\n---\n\n## Setup\n\n" +
+		"```md\n# In a code block\n```\n\n" +
+		"<a name=\"custom-anchor\"></a>\n\nSome <span id='inline'>text</span>\n"
+
+	anchors := Anchors([]byte(source))
+	for _, want := range []string{"intro", "setup", "setup-1", "setext-heading", "custom-anchor", "inline"} {
+		if _, ok := anchors[want]; !ok {
+			t.Errorf("Anchors should include %s, got %v", want, anchors)
+		}
+	}
+	if _, ok := anchors["in-a-code-block"]; ok {
+		t.Errorf("Anchors should not include headings in code blocks")
+	}
+}
@@ -1,130 +1,284 @@
 package mdlinkparser
 
 import (
-	"errors"
-	"io"
+	"bytes"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// MDLinkParser performs our lexical analysis/scanning/parsing. Not a true lexer/parser
-// because right now we don't need tokens, logic, ast, just to collect MD paths
-type MDLinkParser struct {
-	input        []rune
-	char         rune     // current char under examination
-	position     int      // current position in input (points to current char)
-	readPosition int      // current reading position in input (after current char)
-	Links        []string // collection of links paths
-}
+// Kind is the markdown syntax a Reference was written with
+type Kind int
 
-// New creates and returns a pointer to the MDLinkParser with it's input attached
-func New(input string) *MDLinkParser {
-	p := &MDLinkParser{input: []rune(input)}
-	p.readChar()
-	return p
-}
+const (
+	// Image is an inline or reference style image, ![alt](path "title")
+	Image Kind = iota
+	// Link is an inline, reference style or autolink, [text](path "title")
+	Link
+	// HTMLImage is the src of an html <img> tag
+	HTMLImage
+	// Definition is a link reference definition, [label]: path "title"
+	Definition
+)
 
-// ParseLinks takes the input contents and parses it for our MD image links
-func (p *MDLinkParser) ParseLinks() {
-	for p.readPosition < len(p.input) {
-		p.next()
+// String names the kind of a reference
+func (k Kind) String() string {
+	switch k {
+	case Image:
+		return "image"
+	case Link:
+		return "link"
+	case HTMLImage:
+		return "html image"
+	case Definition:
+		return "definition"
 	}
+	return "unknown"
 }
 
-// readChar checks if were at EOF and if we are not, it sets the parser's char to
-// the char at our readPosition and increments position and read position by one
-func (p *MDLinkParser) readChar() {
-	if p.readPosition >= len(p.input) {
-		// End of input (haven't read anything yet or EOF)
-		// 0 is ASCII code for "NUL" character
-		p.char = 0
-	} else {
-		p.char = p.input[p.readPosition]
-	}
+// Reference is a destination found in markdown, with where it was written. Line and Column
+// start at 1 and point at the start of the link, image, tag or definition
+type Reference struct {
+	Kind        Kind
+	Destination string
+	Title       string
+	Label       string
+	Line        int
+	Column      int
+}
 
-	p.position = p.readPosition
-	p.readPosition++
+// urlScheme matches the scheme at the start of an absolute URL, like https: or mailto:
+var urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// htmlImageSrc matches the src attribute of an <img> tag, capturing its quoted or bare value
+var htmlImageSrc = regexp.MustCompile(`(?is)<img\b[^>]*?\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// linkDefinitionLine matches the start of a link reference definition, capturing its label
+var linkDefinitionLine = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:`)
+
+// IsLocal is true when the reference points at a file in the repository rather than a URL
+// or an anchor in the same document
+func (r Reference) IsLocal() bool {
+	d := r.Destination
+	return d != "" && !strings.HasPrefix(d, "#") && !strings.HasPrefix(d, "//") && !urlScheme.MatchString(d)
 }
 
-// skipWhitespace gets called on every iteration of "next" because we do
-// not care about whitespace
-func (p *MDLinkParser) skipWhitespace() {
-	for p.char == ' ' || p.char == '\t' || p.char == '\n' || p.char == '\r' {
-		p.readChar()
+// Path is the file the reference points at, without any query or fragment and with percent
+// encoding decoded
+func (r Reference) Path() string {
+	path := r.Destination
+	if i := strings.IndexAny(path, "?#"); i != -1 {
+		path = path[:i]
 	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	return path
 }
 
-// peek checks to see what the next char is by reading the input at our readPosition
-// This does not consume any characters or increment our position
-func (p *MDLinkParser) peek() rune {
-	if p.readPosition >= len(p.input) {
-		return 0
+// Fragment is the part of the destination after #, an anchor in the document it points at
+func (r Reference) Fragment() string {
+	if i := strings.Index(r.Destination, "#"); i != -1 {
+		return r.Destination[i+1:]
 	}
-	return p.input[p.readPosition]
+	return ""
 }
 
-func (p *MDLinkParser) extractLink() (string, error) {
-	if p.readPosition >= len(p.input) {
-		return "", io.EOF
-	}
+// Parse reads source as CommonMark and returns every reference in it, in the order they
+// appear. Examples inside code blocks and code spans are not references and are left out
+func Parse(source []byte) []Reference {
+	pc := parser.NewContext()
+	doc := goldmark.New().Parser().Parse(text.NewReader(source), parser.WithContext(pc))
+	lines := newLineIndex(source)
+	refs := []Reference{}
 
-	for p.char != ']' && p.char != 0 {
-		p.readChar()
-	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
 
-	if p.peek() != '(' {
-		return "", errors.New("Not a valid MD image link")
-	}
+		switch node := n.(type) {
+		case *ast.CodeBlock, *ast.FencedCodeBlock, *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Image:
+			line, col := lines.position(inlineStart(source, node))
+			refs = append(refs, Reference{Kind: Image, Destination: unescape(node.Destination), Title: unescape(node.Title), Line: line, Column: col})
+		case *ast.Link:
+			line, col := lines.position(inlineStart(source, node))
+			refs = append(refs, Reference{Kind: Link, Destination: unescape(node.Destination), Title: unescape(node.Title), Line: line, Column: col})
+		case *ast.AutoLink:
+			start := blockStart(node)
+			if i := bytes.Index(source[start:], append([]byte("<"), node.Label(source)...)); i != -1 {
+				start += i
+			}
+			line, col := lines.position(start)
+			refs = append(refs, Reference{Kind: Link, Destination: string(node.URL(source)), Line: line, Column: col})
+		case *ast.RawHTML:
+			if node.Segments.Len() > 0 {
+				start := node.Segments.At(0).Start
+				refs = append(refs, htmlImages(source[start:node.Segments.At(node.Segments.Len()-1).Stop], start, lines)...)
+			}
+		case *ast.HTMLBlock:
+			if node.Lines().Len() > 0 {
+				start, stop := node.Lines().At(0).Start, node.Lines().At(node.Lines().Len()-1).Stop
+				if node.HasClosure() {
+					stop = node.ClosureLine.Stop
+				}
+				refs = append(refs, htmlImages(source[start:stop], start, lines)...)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
 
-	// Consume ) and [ to get to start of the link
-	p.readChar()
-	p.readChar()
+	refs = append(refs, definitions(source, pc.References(), lines)...)
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Line != refs[j].Line {
+			return refs[i].Line < refs[j].Line
+		}
+		return refs[i].Column < refs[j].Column
+	})
 
-	linkPath, err := p.readImagePath()
-	if err != nil {
-		return "", errors.New("Please check your markdown syntax for your image links")
-	}
+	return refs
+}
 
-	return linkPath, nil
+// unescape resolves the backslash escapes and entities in a destination or title
+func unescape(b []byte) string {
+	return string(util.ResolveNumericReferences(util.ResolveEntityNames(util.UnescapePunctuations(b))))
 }
 
-func (p *MDLinkParser) readImagePath() (string, error) {
-	var path []rune
+// inlineStart is the offset of the [ or ![ opening a link or image. Inline nodes don't keep
+// their position, so it is found from the first text inside them, or the block holding them
+func inlineStart(source []byte, n ast.Node) int {
+	start := -1
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := child.(*ast.Text); ok && entering {
+			start = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if start == -1 {
+		return blockStart(n)
+	}
 
-	for p.char != ')' && p.char != 0 {
-		path = append(path, p.char)
-		p.readChar()
+	opener := bytes.LastIndexByte(source[:start], '[')
+	if opener == -1 {
+		return start
+	}
+	if _, ok := n.(*ast.Image); ok && opener > 0 && source[opener-1] == '!' {
+		opener--
 	}
+	return opener
+}
 
-	if p.char == 0 {
-		return "", errors.New("Please check your markdown syntax for your image links")
+// blockStart is the offset of the first line of the block holding n
+func blockStart(n ast.Node) int {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			return p.Lines().At(0).Start
+		}
 	}
+	return 0
+}
 
-	return string(path), nil
+// htmlImages finds the src of every <img> tag in html, which starts at offset in the source
+func htmlImages(html []byte, offset int, lines lineIndex) []Reference {
+	refs := []Reference{}
+	for _, match := range htmlImageSrc.FindAllSubmatchIndex(html, -1) {
+		src := ""
+		for group := 1; group <= 3; group++ {
+			if match[group*2] != -1 {
+				src = string(html[match[group*2]:match[group*2+1]])
+			}
+		}
+		line, col := lines.position(offset + match[0])
+		refs = append(refs, Reference{Kind: HTMLImage, Destination: string(util.ResolveEntityNames([]byte(src))), Line: line, Column: col})
+	}
+	return refs
 }
 
-// next switches through the lexer's current char and creates a new token.
-// It then it calls readChar() to advance the lexer and it returns the token
-func (p *MDLinkParser) next() {
-	p.skipWhitespace()
+// definitions finds where each link reference definition goldmark parsed was written. The
+// parser doesn't keep their position, so the source is scanned for their labels outside of
+// code blocks
+func definitions(source []byte, defined []parser.Reference, lines lineIndex) []Reference {
+	byLabel := map[string]parser.Reference{}
+	for _, ref := range defined {
+		byLabel[normalizeLabel(string(ref.Label()))] = ref
+	}
+
+	refs := []Reference{}
+	fence := ""
+	for i, start := range lines {
+		end := len(source)
+		if i+1 < len(lines) {
+			end = lines[i+1]
+		}
+		line := strings.TrimRight(string(source[start:end]), "\r\n")
+
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
 
-	switch p.char {
-	case '[':
-		linkPath, err := p.extractLink()
-		if err != nil {
-			return
+		match := linkDefinitionLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		label := normalizeLabel(match[1])
+		ref, ok := byLabel[label]
+		if !ok {
+			continue
 		}
-		// We do not need to worry about hosted links, check for full url (http/https)
-		if strings.HasPrefix(linkPath, "http") || strings.HasPrefix(linkPath, "https") || strings.HasSuffix(linkPath, ".md") {
-			return
+		delete(byLabel, label)
+		refs = append(refs, Reference{
+			Kind:        Definition,
+			Destination: unescape(ref.Destination()),
+			Title:       unescape(ref.Title()),
+			Label:       match[1],
+			Line:        i + 1,
+			Column:      strings.Index(line, "[") + 1,
+		})
+	}
+	return refs
+}
+
+// normalizeLabel matches labels case insensitively and ignoring runs of whitespace
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// lineIndex holds the offset each line of a source starts at
+type lineIndex []int
+
+func newLineIndex(source []byte) lineIndex {
+	index := lineIndex{0}
+	for i, b := range source {
+		if b == '\n' && i+1 < len(source) {
+			index = append(index, i+1)
 		}
-		p.Links = append(p.Links, linkPath)
-	case 0:
-		p.readChar()
-		return
-	default:
-		p.readChar()
-		return
 	}
+	return index
+}
 
-	p.readChar()
+// position converts an offset in the source to a line and column starting at 1
+func (l lineIndex) position(offset int) (int, int) {
+	line := sort.Search(len(l), func(i int) bool { return l[i] > offset })
+	if line == 0 {
+		return 1, offset + 1
+	}
+	return line, offset - l[line-1] + 1
 }
@@ -1,36 +1,88 @@
 package mdlinkparser
 
 import (
-	"strings"
+	"reflect"
 	"testing"
 )
 
-func Test_ParseLink(t *testing.T) {
-	tableTest := map[string][]string{
-		"[example](linkresult)":   []string{"linkresult"},
-		"[example]()":             []string{""},
-		"[example](ends-in.md)":   []string{""},
-		"[](has-no-link-text)":    []string{"has-no-link-text"},
-		"[more](than)[one](link)": []string{"than", "link"},
-		`[more](than)
-[one](line)
-[with](links)
-		`: []string{"than", "line", "links"},
-		"[example](linkresult[contains](valid-link))": []string{"linkresult[contains](valid-link"}, // not actually supported, checks terminating link character
-		"[)":                  []string{""},
-		"[here](./../result)": []string{"./../result"},
-		`var myarr = [];
-myarr[0] = (val != otherval);`: []string{""},
-		`var myarr = [(arg) => { console.log(arg) }];
-myarr[0]("code-test-case");`: []string{"\"code-test-case\""},
+func Test_Parse(t *testing.T) {
+	tableTest := map[string][]Reference{
+		"[example](linkresult)": {{Kind: Link, Destination: "linkresult", Line: 1, Column: 1}},
+		"![alt](image.png)":     {{Kind: Image, Destination: "image.png", Line: 1, Column: 1}},
+		"[example]()":           {{Kind: Link, Line: 1, Column: 1}},
+		"[](has-no-link-text)":  {{Kind: Link, Destination: "has-no-link-text", Line: 1, Column: 1}},
+		"[example](ends-in.md)": {{Kind: Link, Destination: "ends-in.md", Line: 1, Column: 1}},
+		"[more](than)[one](link)": {
+			{Kind: Link, Destination: "than", Line: 1, Column: 1},
+			{Kind: Link, Destination: "link", Line: 1, Column: 13},
+		},
+		"[more](than)\n[one](line)\n  [with](links)\n": {
+			{Kind: Link, Destination: "than", Line: 1, Column: 1},
+			{Kind: Link, Destination: "line", Line: 2, Column: 1},
+			{Kind: Link, Destination: "links", Line: 3, Column: 3},
+		},
+		`Some ![a *b*](b.png "title") here`: {{Kind: Image, Destination: "b.png", Title: "title", Line: 1, Column: 6}},
+		"[spaces](<my image.png>)":          {{Kind: Link, Destination: "my image.png", Line: 1, Column: 1}},
+		`\[not](a-link) and [escaped](a\_b.png)`: {
+			{Kind: Link, Destination: "a_b.png", Line: 1, Column: 20},
+		},
+		"[here](./../result)":   {{Kind: Link, Destination: "./../result", Line: 1, Column: 1}},
+		"<https://example.com>": {{Kind: Link, Destination: "https://example.com", Line: 1, Column: 1}},
+		"[)":                    {},
+		"var myarr = [];\nmyarr[0] = (val != otherval);":                                          {},
+		"```js\nvar myarr = [(arg) => { console.log(arg) }];\nmyarr[0](\"code-test-case\");\n```": {},
+		"Use `[text](not-a-link.png)` to link":                                                    {},
+		"    ![indented](code.png)":                                                               {},
+		"A <img alt=\"x\" src=\"html.png\"> tag":                                                  {{Kind: HTMLImage, Destination: "html.png", Line: 1, Column: 3}},
+		"<div>\n<img src='block.png'>\n<IMG SRC=bare.png>\n</div>": {
+			{Kind: HTMLImage, Destination: "block.png", Line: 2, Column: 1},
+			{Kind: HTMLImage, Destination: "bare.png", Line: 3, Column: 1},
+		},
+		"![logo][Logo Ref]\n\n[logo ref]: images/logo.png \"The logo\"\n": {
+			{Kind: Image, Destination: "images/logo.png", Title: "The logo", Line: 1, Column: 1},
+			{Kind: Definition, Destination: "images/logo.png", Title: "The logo", Label: "logo ref", Line: 3, Column: 1},
+		},
+		"```\n[unused]: in-code.png\n```\n": {},
 	}
 
-	for k, v := range tableTest {
-		parser := New(k)
-		parser.ParseLinks()
-		result := parser.Links
-		if strings.Join(result, "") != strings.Join(v, "") {
-			t.Errorf("ParseLinks %s expected %v but got %v", k, v, result)
+	for k, want := range tableTest {
+		got := Parse([]byte(k))
+		if len(want) == 0 && len(got) == 0 {
+			continue
 		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse %q expected %+v but got %+v", k, want, got)
+		}
+	}
+}
+
+func Test_ReferenceIsLocal(t *testing.T) {
+	tableTest := map[string]bool{
+		"images/a.png":           true,
+		"../unit-2/lesson.md":    true,
+		"/data/people.sql":       true,
+		"lesson.md#a-heading":    true,
+		"#a-heading":             false,
+		"":                       false,
+		"https://example.com":    false,
+		"http://example.com":     false,
+		"//cdn.example.com/a.js": false,
+		"mailto:a@example.com":   false,
+	}
+
+	for destination, want := range tableTest {
+		if got := (Reference{Destination: destination}).IsLocal(); got != want {
+			t.Errorf("IsLocal for %s expected %v but got %v", destination, want, got)
+		}
+	}
+}
+
+func Test_ReferencePathAndFragment(t *testing.T) {
+	r := Reference{Destination: "images/my%20image.png?raw=true#top"}
+	if r.Path() != "images/my image.png" {
+		t.Errorf("Path should drop the query and fragment and decode the path, got %s", r.Path())
+	}
+	if r.Fragment() != "top" {
+		t.Errorf("Fragment should be the part after #, got %s", r.Fragment())
 	}
 }